@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gwkline/artestian/pkg/agent"
@@ -13,8 +21,15 @@ import (
 	"github.com/gwkline/artestian/pkg/finder"
 	"github.com/gwkline/artestian/pkg/generator"
 	"github.com/gwkline/artestian/pkg/golang"
+	"github.com/gwkline/artestian/pkg/output"
 	"github.com/gwkline/artestian/pkg/prompt_logger"
+	"github.com/gwkline/artestian/pkg/prompts"
+	"github.com/gwkline/artestian/pkg/review"
+	"github.com/gwkline/artestian/pkg/sandbox"
 	"github.com/gwkline/artestian/pkg/typescript"
+	"github.com/gwkline/artestian/pkg/usage"
+	"github.com/gwkline/artestian/pkg/watch"
+	"github.com/gwkline/artestian/pkg/workspace"
 	"github.com/gwkline/artestian/types"
 
 	"github.com/joho/godotenv"
@@ -22,26 +37,78 @@ import (
 
 var (
 	dir        = flag.String("dir", "", "Path to project root")
-	aiProvider = flag.String("ai", "anthropic", "AI provider to use (currently only anthropic is supported)")
+	aiProvider = flag.String("ai", "", "AI provider to use for every operation (overrides ai.provider, currently only anthropic is supported)")
 	logLevel   = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
 	numGens    = flag.Int("generations", 1, "Number of test generations to run (use -1 for infinite)")
+
+	model           = flag.String("model", "", "Model to use for every operation (overrides ai.model and per-operation models)")
+	maxOutputTokens = flag.Int64("max-output-tokens", 0, "Maximum response tokens for every operation (overrides ai.max_tokens)")
+	temperature     = flag.Float64("temperature", -1, "Sampling temperature between 0 and 1 for every operation (overrides ai.temperature)")
+
+	fallbackModel     = flag.String("fallback-model", "", "Model to fall back to when the default model keeps failing, e.g. claude-3-5-haiku-latest")
+	requestsPerMinute = flag.Int("requests-per-minute", 0, "Client-side limit on agent requests per minute (0 for no limit)")
+	tokensPerMinute   = flag.Int("tokens-per-minute", 0, "Client-side limit on estimated tokens per minute (0 for no limit)")
+
+	maxTokens           = flag.Int64("max-tokens", 0, "Stop after this many tokens across the run (overrides budget.max_tokens, 0 for no limit)")
+	maxCost             = flag.Float64("max-cost", 0, "Stop after this estimated cost in USD (overrides budget.max_cost, 0 for no limit)")
+	maxTime             = flag.Duration("max-time", 0, "Stop after this much wall-clock time, e.g. 2h (overrides budget.max_duration, 0 for no limit)")
+	maxCallsPerFunction = flag.Int("max-calls-per-function", 0, "Maximum agent calls per function including fixes (overrides budget.max_calls_per_function, 0 for no limit)")
+
+	dryRun      = flag.Bool("dry-run", false, "Generate and check tests without writing them, and print a patch of the test files instead")
+	outputPatch = flag.String("output-patch", "", "Write a patch of the test files to this file instead of writing them, for git apply")
+	useGit      = flag.Bool("git", false, "Commit the test files on a new branch, then switch back to the current branch")
+	branch      = flag.String("branch", "", "Branch to commit the test files on with -git (default artestian/<date>-<time>)")
+	commitMode  = flag.String("commit-mode", string(output.CommitPerFile), "How -git groups test files into commits: single, per-file or per-function")
+	force       = flag.Bool("force", false, "Run -git on a working tree with uncommitted changes")
+
+	keepFailed = flag.Bool("keep-failed", false, "Keep tests that couldn't be fixed in a temp directory, which is logged at the end of the run")
+	sandboxed  = flag.Bool("sandbox", false, "Type check and run generated tests in a sandbox (overrides settings.sandbox.enabled)")
+	flakyRuns  = flag.Int("flaky-runs", -1, "Times each passing test is re-run to detect flakiness, turning the check on, or 0 to turn it off (overrides settings.flakiness)")
+	mutation   = flag.Bool("mutation", false, "Check generated tests against mutants of the function and strengthen weak ones (overrides settings.mutation.enabled)")
+
+	debounce = flag.Duration("debounce", 500*time.Millisecond, "Quiet time after the last save before changed files are looked at in watch mode")
+	poll     = flag.Bool("poll", false, "Poll for changes in watch mode instead of using inotify")
+
+	reviewing bool // Set by the review subcommand
+	watching  bool // Set by the watch subcommand
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "prompts" {
+		if err := runPrompts(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// review runs the same generation, with every passing test shown for review, and watch
+	// generates tests for functions as their files are saved
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "review" {
+		reviewing = true
+		args = args[1:]
+	} else if len(args) > 0 && args[0] == "watch" {
+		watching = true
+		args = args[1:]
+	}
+
 	slog.Info("starting Artestian - AI-Powered Test Generator")
 
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
-	if err := run(); err != nil {
+	if err := run(args); err != nil {
 		slog.Error("application error", "error", err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
-	flag.Parse()
+func run(args []string) error {
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return err
+	}
 	setupLogger()
 
 	if *dir == "" {
@@ -58,17 +125,86 @@ func run() error {
 		return err
 	}
 
-	lang, err := initializeLanguage(cfg)
+	// Tests are generated in a copy of the project, and only final test files are written
+	// back. The copy starts at the repository or module root, so tools find what they need
+	// above the project.
+	wsRoot, err := workspace.FindRoot(*dir)
+	if err != nil {
+		return err
+	}
+	ws, err := workspace.New(wsRoot)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	sb, err := initializeSandbox(cfg, ws)
+	if err != nil {
+		return err
+	}
+	defer sb.Close()
+
+	lang, err := initializeLanguage(cfg, sb)
+	if err != nil {
+		return err
+	}
+
+	renderer, err := loadPromptRenderer(cfg)
+	if err != nil {
+		return err
+	}
+
+	logger, err := prompt_logger.Init(true)
+	if err != nil {
+		return fmt.Errorf("failed to create prompt logger: %w", err)
+	}
+
+	limits, err := budgetLimits(cfg)
 	if err != nil {
 		return err
 	}
+	tracker := usage.NewTracker(cfg.GetPricing(), limits)
 
-	agent, err := initializeAIProvider(*aiProvider)
+	ai, err := aiSettings(cfg)
 	if err != nil {
 		return err
 	}
 
-	return generateTests(cfg, lang, examples, contextFiles, agent)
+	agent, err := initializeAIProvider(ai, logger, renderer, tracker)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Restore the default handler, so a second interrupt quits immediately
+		stop()
+		slog.Warn("interrupted, finishing up (press Ctrl-C again to force quit)")
+	}()
+
+	sink, err := initializeOutput(lang)
+	if err != nil {
+		return err
+	}
+	testGen, fileFinder, err := newTestGenerator(cfg, ws, sink, lang, examples, contextFiles, agent, tracker)
+	if err != nil {
+		return errors.Join(err, sink.Close())
+	}
+	if watching {
+		err = watchTests(ctx, cfg, testGen, fileFinder, lang, tracker)
+	} else {
+		err = generateTests(ctx, cfg, testGen, tracker)
+	}
+	reportUsage(tracker, logger)
+	if closeErr := sink.Close(); closeErr != nil {
+		return errors.Join(err, closeErr)
+	}
+	if *outputPatch != "" {
+		slog.Info("wrote patch", "path", *outputPatch)
+	}
+	return err
 }
 
 func loadConfiguration(dirPath string) (types.IConfig, error) {
@@ -99,45 +235,228 @@ func loadTestResources(cfg types.IConfig) ([]types.TestExample, []types.ContextF
 	return examples, contextFiles, nil
 }
 
-func initializeLanguage(cfg types.IConfig) (types.ILanguage, error) {
+// initializeOutput returns where final test files go: a patch file with -output-patch,
+// a patch on stdout with -dry-run, commits on a new branch with -git, and otherwise
+// the project
+func initializeOutput(lang types.ILanguage) (types.IOutputSink, error) {
+	if *useGit && (*dryRun || *outputPatch != "") {
+		return nil, fmt.Errorf("-git can't be combined with -dry-run or -output-patch")
+	}
+
+	switch {
+	case *outputPatch != "":
+		return output.NewPatchFileSink(*dir, *outputPatch), nil
+	case *dryRun:
+		return output.NewStdoutSink(*dir), nil
+	case *useGit:
+		return output.NewGitSink(output.GitOptions{
+			Root:   *dir,
+			Branch: *branch,
+			Mode:   output.CommitMode(*commitMode),
+			Force:  *force,
+			Merge:  lang.MergeTests,
+		})
+	default:
+		return output.NewFileSink(), nil
+	}
+}
+
+// initializeSandbox returns the sandbox generated tests are run in, or nil when it's off.
+// Tests are generated in the workspace, which the sandbox reuses rather than copying.
+func initializeSandbox(cfg types.IConfig, ws *workspace.Workspace) (*sandbox.Sandbox, error) {
+	settings := cfg.GetSandbox()
+	if !settings.Enabled && !*sandboxed {
+		return nil, nil
+	}
+
+	cpuTime, err := time.ParseDuration(settings.CPUTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox cpu_time: %w", err)
+	}
+	timeout, err := time.ParseDuration(settings.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox timeout: %w", err)
+	}
+
+	sb, err := sandbox.New(sandbox.Options{
+		Mode:         sandbox.Mode(settings.Mode),
+		Root:         ws.Dir(),
+		Workspace:    ws,
+		Writable:     append(settings.Writable, toolCaches(cfg.GetLanguage())...),
+		CPUTime:      cpuTime,
+		MemoryMB:     settings.MemoryMB,
+		MaxProcesses: settings.MaxProcesses,
+		Timeout:      timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox: %w", err)
+	}
+	slog.Info("running generated tests in a sandbox", "mode", sb.Mode())
+	return sb, nil
+}
+
+// toolCaches returns the directories go and npm write their caches to, which stay
+// writable in the sandbox
+func toolCaches(language string) []string {
+	var dirs []string
+	if cache, err := os.UserCacheDir(); err == nil {
+		dirs = append(dirs, cache)
+	}
+	switch language {
+	case "go":
+		if output, err := exec.Command("go", "env", "GOCACHE", "GOMODCACHE").Output(); err == nil {
+			dirs = append(dirs, strings.Split(strings.TrimSpace(string(output)), "\n")...)
+		}
+	case "typescript":
+		if home, err := os.UserHomeDir(); err == nil {
+			dirs = append(dirs, filepath.Join(home, ".npm"))
+		}
+	}
+	return dirs
+}
+
+func initializeLanguage(cfg types.IConfig, sb *sandbox.Sandbox) (types.ILanguage, error) {
 	slog.Debug("initializing language support", "language", cfg.GetLanguage())
 	switch cfg.GetLanguage() {
 	case "typescript":
-		return typescript.NewTypeScriptSupport(), nil
+		return typescript.NewTypeScriptSupportWithOptions(typescript.Options{Sandbox: sb}), nil
 	case "go":
-		return golang.NewGoSupport(), nil
+		settings := cfg.GetGoSettings()
+		options := golang.Options{
+			Tags:    settings.Tags,
+			Race:    settings.Race,
+			Sandbox: sb,
+		}
+		if settings.Timeout != "" {
+			timeout, err := time.ParseDuration(settings.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid go timeout: %w", err)
+			}
+			options.Timeout = timeout
+		}
+		return golang.NewGoSupportWithOptions(options), nil
 	default:
 		return nil, fmt.Errorf("unsupported language: %s", cfg.GetLanguage())
 	}
 }
 
-func initializeAIProvider(provider string) (types.IAgent, error) {
-	logger, err := prompt_logger.Init(true)
+func loadPromptRenderer(cfg types.IConfig) (*prompts.Renderer, error) {
+	slog.Debug("loading prompt templates")
+	templates, err := cfg.LoadPromptTemplates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+
+	renderer, err := prompts.NewRenderer(templates)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create prompt logger: %w", err)
+		return nil, fmt.Errorf("failed to parse prompt templates: %w", err)
+	}
+	return renderer, nil
+}
+
+// aiSettings applies the model flags on top of the configured model settings
+func aiSettings(cfg types.IConfig) (types.AI, error) {
+	override := types.ModelSettings{
+		Provider:  *aiProvider,
+		Model:     *model,
+		MaxTokens: *maxOutputTokens,
+	}
+	if *temperature >= 0 {
+		override.Temperature = temperature
+	}
+
+	ai := config.OverrideAI(cfg.GetAI(), override)
+	if err := config.ValidateAI(ai); err != nil {
+		return types.AI{}, fmt.Errorf("invalid model flags: %w", err)
+	}
+	return ai, nil
+}
+
+func initializeAIProvider(ai types.AI, logger types.IPromptLogger, renderer *prompts.Renderer, tracker types.IUsageTracker) (types.IAgent, error) {
+	provider := ai.Provider
+	if provider == "" {
+		provider = agent.DefaultModelSettings.Provider
 	}
 
 	slog.Debug("initializing AI provider", "provider", provider)
 	switch provider {
 	case "anthropic":
-		return agent.NewAnthropicProvider(logger)
+		provider, err := agent.NewAnthropicProvider(logger, renderer, tracker, ai)
+		if err != nil {
+			return nil, err
+		}
+
+		var fallbacks []types.IAgent
+		if *fallbackModel != "" {
+			fallbacks = append(fallbacks, provider.WithModel(*fallbackModel))
+		}
+
+		return agent.NewRetryingAgent(provider, agent.RetryOptions{
+			Limiter: agent.NewLimiter(*requestsPerMinute, *tokensPerMinute),
+		}, fallbacks...), nil
 	default:
 		return nil, fmt.Errorf("unknown AI provider: %s", provider)
 	}
 }
 
-func generateTests(cfg types.IConfig, lang types.ILanguage, examples []types.TestExample, contextFiles []types.ContextFile, aiClient types.IAgent) error {
+func newTestGenerator(cfg types.IConfig, ws *workspace.Workspace, sink types.IOutputSink, lang types.ILanguage, examples []types.TestExample, contextFiles []types.ContextFile, aiClient types.IAgent, tracker types.IUsageTracker) (*generator.TestGenerator, *finder.FileFinder, error) {
 	slog.Debug("initializing file finder")
 	fileFinder := finder.NewFileFinder(lang)
 
 	slog.Debug("initializing test generator")
-	testGen := generator.NewTestGenerator(fileFinder, aiClient, lang, examples, contextFiles)
+	testGen := generator.NewTestGenerator(fileFinder, aiClient, lang, examples, contextFiles, tracker)
+	testGen.SetContextDiscovery(cfg.GetContextDiscovery())
+	testGen.SetWorkspace(ws)
+	testGen.SetKeepFailed(*keepFailed)
+	testGen.SetOutputSink(sink)
+	if reviewing {
+		testGen.SetReviewer(review.NewTerminal(os.Stdin, logOutput()))
+	}
+
+	if options, ok := flakinessOptions(cfg); ok {
+		testGen.EnableFlakinessCheck(options)
+	}
+
+	if settings := cfg.GetMutation(); settings.Enabled || *mutation {
+		timeout, err := time.ParseDuration(settings.Timeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid mutation timeout: %w", err)
+		}
+		testGen.EnableMutationTesting(generator.MutationOptions{
+			Threshold:   settings.Threshold,
+			MaxMutants:  settings.MaxMutants,
+			MaxAttempts: settings.MaxAttempts,
+			Timeout:     timeout,
+		})
+	}
+
+	return testGen, fileFinder, nil
+}
 
+func generateTests(ctx context.Context, cfg types.IConfig, testGen *generator.TestGenerator, tracker types.IUsageTracker) error {
 	genCount := 0
 	for *numGens == -1 || genCount < *numGens {
+		if ctx.Err() != nil {
+			slog.Warn("stopping test generation", "reason", ctx.Err())
+			return nil
+		}
+
+		if err := tracker.CheckBudget(); err != nil {
+			slog.Warn("stopping test generation", "reason", err)
+			break
+		}
+
 		slog.Info("starting test generation", "iteration", genCount+1)
-		err := testGen.GenerateNextTest(*dir, cfg)
+		err := testGen.GenerateNextTest(ctx, *dir, cfg)
 		if err != nil {
+			if ctx.Err() != nil {
+				slog.Warn("stopping test generation", "reason", ctx.Err())
+				return nil
+			}
+			if errors.Is(err, generator.ErrReviewQuit) {
+				slog.Info("review quit, stopping generation")
+				break
+			}
 			if err.Error() == "no files found needing tests" {
 				slog.Info("no more files need tests, stopping generation")
 				break
@@ -152,6 +471,100 @@ func generateTests(cfg types.IConfig, lang types.ILanguage, examples []types.Tes
 	return nil
 }
 
+// watchTests generates tests for changed and new functions that lack tests, as their
+// files are saved, until interrupted or out of budget
+func watchTests(ctx context.Context, cfg types.IConfig, testGen *generator.TestGenerator, fileFinder *finder.FileFinder, lang types.ILanguage, tracker types.IUsageTracker) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watcher := watch.New(fileFinder, lang, cfg, watch.Options{Debounce: *debounce, Poll: *poll})
+	return watcher.Run(ctx, func(ctx context.Context, sourcePath string, functions []string) error {
+		if err := tracker.CheckBudget(); err != nil {
+			slog.Warn("stopping watch", "reason", err)
+			cancel()
+			return nil
+		}
+
+		err := testGen.GenerateFunctions(ctx, *dir, cfg, sourcePath, functions)
+		if errors.Is(err, generator.ErrReviewQuit) {
+			slog.Info("review quit, stopping watch")
+			cancel()
+			return nil
+		}
+		if err != nil && ctx.Err() != nil {
+			return nil
+		}
+		return err
+	})
+}
+
+// flakinessOptions combines the configured flakiness check with -flaky-runs, and reports
+// whether the check is enabled
+func flakinessOptions(cfg types.IConfig) (generator.FlakinessOptions, bool) {
+	settings := cfg.GetFlakiness()
+	enabled := *settings.Enabled
+	if *flakyRuns >= 0 {
+		settings.Runs = *flakyRuns
+		enabled = *flakyRuns > 0
+	}
+	return generator.FlakinessOptions{
+		RerunOptions: types.RerunOptions{
+			Runs:    settings.Runs,
+			Shuffle: settings.Shuffle,
+			Race:    settings.Race,
+		},
+		MaxAttempts: settings.MaxAttempts,
+	}, enabled
+}
+
+// budgetLimits combines the configured budget with any limits given on the command line
+func budgetLimits(cfg types.IConfig) (usage.Limits, error) {
+	budget := cfg.GetBudget()
+	limits := usage.Limits{
+		MaxTokens:           budget.MaxTokens,
+		MaxCost:             budget.MaxCost,
+		MaxCallsPerFunction: budget.MaxCallsPerFunction,
+	}
+	if budget.MaxDuration != "" {
+		duration, err := time.ParseDuration(budget.MaxDuration)
+		if err != nil {
+			return usage.Limits{}, fmt.Errorf("invalid budget max_duration: %w", err)
+		}
+		limits.MaxDuration = duration
+	}
+
+	if *maxTokens > 0 {
+		limits.MaxTokens = *maxTokens
+	}
+	if *maxCost > 0 {
+		limits.MaxCost = *maxCost
+	}
+	if *maxTime > 0 {
+		limits.MaxDuration = *maxTime
+	}
+	if *maxCallsPerFunction > 0 {
+		limits.MaxCallsPerFunction = *maxCallsPerFunction
+	}
+
+	return limits, nil
+}
+
+// reportUsage prints the token and cost summary and saves it with the prompt logs
+func reportUsage(tracker types.IUsageTracker, logger types.IPromptLogger) {
+	summary := tracker.Summary()
+	if summary.Total.Calls == 0 {
+		return
+	}
+
+	out := logOutput()
+	fmt.Fprintln(out)
+	fmt.Fprint(out, usage.FormatSummary(summary))
+
+	if err := logger.LogSummary(summary); err != nil {
+		slog.Warn("failed to log usage summary", "error", err)
+	}
+}
+
 func setupLogger() {
 	var level slog.Level
 	switch *logLevel {
@@ -181,7 +594,16 @@ func setupLogger() {
 		},
 	}
 
-	handler := slog.NewTextHandler(os.Stdout, opts)
+	handler := slog.NewTextHandler(logOutput(), opts)
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 }
+
+// logOutput returns where logs and other messages go. A dry run prints its patch on
+// stdout, so it can be piped to git apply, and everything else goes to stderr.
+func logOutput() io.Writer {
+	if *dryRun && *outputPatch == "" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
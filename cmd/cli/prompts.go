@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gwkline/artestian/pkg/finder"
+	"github.com/gwkline/artestian/pkg/generator"
+	"github.com/gwkline/artestian/pkg/prompts"
+	"github.com/gwkline/artestian/pkg/usage"
+	"github.com/gwkline/artestian/types"
+)
+
+const promptsUsage = "usage: artestian prompts render -dir <project> [-file <source file>] [-function <name>] [-operation <prompt>] [-example <name>] [-test-file <test file>]"
+
+// runPrompts handles the prompts subcommand, which prints a rendered prompt without calling a model
+func runPrompts(args []string) error {
+	if len(args) == 0 || args[0] != "render" {
+		return errors.New(promptsUsage)
+	}
+
+	flags := flag.NewFlagSet("prompts render", flag.ContinueOnError)
+	projectDir := flags.String("dir", "", "Path to project root")
+	file := flags.String("file", "", "Source file to render the prompt for (defaults to the next file needing tests)")
+	function := flags.String("function", "", "Function to render the prompt for, as Name or Receiver.Name (defaults to the first function)")
+	operation := flags.String("operation", string(prompts.OperationGenerateTest), "Prompt to render (generate_test, fix_type_errors, fix_test_failures, pick_example)")
+	exampleName := flags.String("example", "", "Example to use (defaults to the best local matches, after any pinned examples)")
+	testFile := flags.String("test-file", "", "Existing test code to include in fix_type_errors and fix_test_failures prompts")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	// Keep stdout for the prompt itself
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	if *projectDir == "" {
+		return fmt.Errorf("project directory is required. Use -dir flag to specify the path")
+	}
+	if !prompts.IsValidOperation(*operation) {
+		return fmt.Errorf("unknown prompt: %s", *operation)
+	}
+
+	cfg, err := loadConfiguration(*projectDir)
+	if err != nil {
+		return err
+	}
+
+	examples, contextFiles, err := loadTestResources(cfg)
+	if err != nil {
+		return err
+	}
+
+	lang, err := initializeLanguage(cfg, nil)
+	if err != nil {
+		return err
+	}
+
+	renderer, err := loadPromptRenderer(cfg)
+	if err != nil {
+		return err
+	}
+
+	fileFinder := finder.NewFileFinder(lang)
+	sourcePath := *file
+	if sourcePath == "" {
+		sourcePath, err = fileFinder.FindNextFile(cfg)
+		if err != nil {
+			return fmt.Errorf("error finding file: %w", err)
+		}
+		if sourcePath == "" {
+			return fmt.Errorf("no files found needing tests")
+		}
+	}
+	sourcePath, err = filepath.Abs(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	sourceCode, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return fmt.Errorf("error reading source file: %w", err)
+	}
+
+	testGen := generator.NewTestGenerator(fileFinder, nil, lang, examples, contextFiles, usage.NewTracker(nil, usage.Limits{}))
+	testGen.SetContextDiscovery(cfg.GetContextDiscovery())
+
+	var prompt string
+	if prompts.Operation(*operation) == prompts.OperationPickExample {
+		prompt, err = renderer.PickExample(string(sourceCode), examples)
+	} else {
+		prompt, err = renderTargetPrompt(renderer, prompts.Operation(*operation), targetOptions{
+			generator:         testGen,
+			language:          lang,
+			sourcePath:        sourcePath,
+			sourceCode:        string(sourceCode),
+			testPath:          fileFinder.GetTestPath(sourcePath),
+			function:          *function,
+			projectDir:        *projectDir,
+			example:           *exampleName,
+			examples:          examples,
+			examplesPerPrompt: cfg.GetExamplesPerPrompt(),
+			testFile:          *testFile,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(prompt)
+	return nil
+}
+
+type targetOptions struct {
+	generator         *generator.TestGenerator
+	language          types.ILanguage
+	sourcePath        string
+	sourceCode        string
+	testPath          string
+	function          string
+	projectDir        string
+	example           string
+	examples          []types.TestExample
+	examplesPerPrompt int
+	testFile          string
+}
+
+// renderTargetPrompt renders a per-function prompt with the same parameters the generator would use
+func renderTargetPrompt(renderer *prompts.Renderer, op prompts.Operation, opts targetOptions) (string, error) {
+	functions, err := opts.language.GetFunctions(opts.sourceCode)
+	if err != nil {
+		return "", fmt.Errorf("error getting functions: %w", err)
+	}
+
+	function, err := selectFunction(functions, opts.function)
+	if err != nil {
+		return "", err
+	}
+
+	examples, err := selectExamples(opts)
+	if err != nil {
+		return "", err
+	}
+
+	params := opts.generator.BuildParams(context.Background(), opts.sourcePath, opts.sourceCode, function, examples, opts.testPath)
+	if op == prompts.OperationGenerateTest {
+		return renderer.GenerateTest(params)
+	}
+
+	iterateParams := types.IterateTestParams{GenerateTestParams: params}
+	if opts.testFile != "" {
+		testCode, err := os.ReadFile(opts.testFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading test file: %w", err)
+		}
+		iterateParams.TestCode = string(testCode)
+	}
+
+	if op == prompts.OperationFixTypeErrors {
+		return renderer.FixTypeErrors(iterateParams)
+	}
+	return renderer.FixTestFailures(iterateParams)
+}
+
+func selectFunction(functions []types.Function, name string) (types.Function, error) {
+	if len(functions) == 0 {
+		return types.Function{}, fmt.Errorf("no functions found in source file")
+	}
+	if name == "" {
+		return functions[0], nil
+	}
+
+	receiver, funcName, isMethod := strings.Cut(name, ".")
+	for _, fn := range functions {
+		if isMethod && fn.Receiver == receiver && fn.Name == funcName {
+			return fn, nil
+		}
+		if !isMethod && fn.Name == name {
+			return fn, nil
+		}
+	}
+	return types.Function{}, fmt.Errorf("function %s not found in source file", name)
+}
+
+// selectExamples returns the named example, or the examples the generator would choose
+// with local ranking, so rendering never calls a model
+func selectExamples(opts targetOptions) ([]types.TestExample, error) {
+	if opts.example == "" {
+		relPath := opts.sourcePath
+		if projectDir, err := filepath.Abs(opts.projectDir); err == nil {
+			if rel, err := filepath.Rel(projectDir, opts.sourcePath); err == nil {
+				relPath = rel
+			}
+		}
+		return opts.generator.SelectExamples(context.Background(), types.ExampleSelectionLocal, opts.examplesPerPrompt, relPath, opts.sourceCode), nil
+	}
+
+	for _, example := range opts.examples {
+		if example.Name == opts.example {
+			return []types.TestExample{example}, nil
+		}
+	}
+	return nil, fmt.Errorf("example %s not found in config", opts.example)
+}
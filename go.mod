@@ -5,17 +5,19 @@ go 1.23.6
 require (
 	github.com/anthropics/anthropic-sdk-go v0.2.0-alpha.10
 	github.com/joho/godotenv v1.5.1
+	github.com/stretchr/testify v1.10.0
+	golang.org/x/tools v0.36.0
 // github.com/openai/openai-go v0.1.0-alpha.56
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/objx v0.5.2 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
 	github.com/tidwall/gjson v1.14.4 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
+	golang.org/x/mod v0.27.0 // indirect
+	golang.org/x/sync v0.16.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
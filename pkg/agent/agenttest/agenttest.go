@@ -0,0 +1,75 @@
+package agenttest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// Agent is an IAgent that fails on a schedule. Each call takes the next
+// entry of Errors, failing with it unless it is nil, and succeeds once Errors runs out.
+type Agent struct {
+	Name     string
+	Response string   // Returned by successful generate and fix calls
+	Errors   []error  // Outcome of each call in order, nil for success
+	Calls    []string // Operations called, in order
+
+	mu sync.Mutex
+}
+
+func (f *Agent) GenerateTest(ctx context.Context, params types.GenerateTestParams) (string, error) {
+	if err := f.next("generate_test"); err != nil {
+		return "", err
+	}
+	return f.Response, nil
+}
+
+func (f *Agent) FixTypeErrors(ctx context.Context, params types.IterateTestParams) (string, error) {
+	if err := f.next("fix_type_errors"); err != nil {
+		return "", err
+	}
+	return f.Response, nil
+}
+
+func (f *Agent) FixTestFailures(ctx context.Context, params types.IterateTestParams) (string, error) {
+	if err := f.next("fix_test_failures"); err != nil {
+		return "", err
+	}
+	return f.Response, nil
+}
+
+func (f *Agent) PickExample(ctx context.Context, sourceCode string, testExamples []types.TestExample) (types.TestExample, error) {
+	if err := f.next("pick_example"); err != nil {
+		return types.TestExample{}, err
+	}
+	if len(testExamples) == 0 {
+		return types.TestExample{}, fmt.Errorf("no test examples provided")
+	}
+	return testExamples[0], nil
+}
+
+func (f *Agent) GetName() string {
+	return f.Name
+}
+
+// CallCount returns the number of calls made so far
+func (f *Agent) CallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.Calls)
+}
+
+func (f *Agent) next(operation string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Calls = append(f.Calls, operation)
+	if len(f.Errors) == 0 {
+		return nil
+	}
+	err := f.Errors[0]
+	f.Errors = f.Errors[1:]
+	return err
+}
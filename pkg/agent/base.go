@@ -2,21 +2,33 @@ package agent
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/gwkline/artestian/pkg/config"
+	"github.com/gwkline/artestian/pkg/prompts"
 	"github.com/gwkline/artestian/types"
 )
 
-var MAX_TOKENS = 8192
+// DefaultModelSettings are used for any setting the config leaves empty
+var DefaultModelSettings = types.ModelSettings{
+	Provider:  "anthropic",
+	Model:     string(anthropic.ModelClaude3_5SonnetLatest),
+	MaxTokens: 8192,
+}
 
 type AnthropicProvider struct {
-	client *anthropic.Client
-	logger types.IPromptLogger
+	client  *anthropic.Client
+	ai      types.AI
+	logger  types.IPromptLogger
+	prompts *prompts.Renderer
+	usage   types.IUsageTracker
 }
 
-func NewAnthropicProvider(logger types.IPromptLogger) (*AnthropicProvider, error) {
+func NewAnthropicProvider(logger types.IPromptLogger, renderer *prompts.Renderer, usage types.IUsageTracker, ai types.AI) (*AnthropicProvider, error) {
 	apiKey := os.Getenv("ANTHROPIC_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable not set")
@@ -25,7 +37,70 @@ func NewAnthropicProvider(logger types.IPromptLogger) (*AnthropicProvider, error
 	return &AnthropicProvider{
 		client: anthropic.NewClient(
 			option.WithAPIKey(apiKey),
+			// Retries are handled by RetryingAgent, which also rate limits and falls back
+			option.WithMaxRetries(0),
 		),
-		logger: logger,
+		ai:      ai,
+		logger:  logger,
+		prompts: renderer,
+		usage:   usage,
 	}, nil
 }
+
+// WithModel returns a copy of the provider that uses model for every operation, sharing its client
+func (p *AnthropicProvider) WithModel(model string) *AnthropicProvider {
+	provider := *p
+	provider.ai = config.OverrideAI(p.ai, types.ModelSettings{Model: model})
+	return &provider
+}
+
+// GetName returns the model the provider generates tests with
+func (p *AnthropicProvider) GetName() string {
+	return p.settings(prompts.OperationGenerateTest).Model
+}
+
+// settings returns the model settings of an operation, filling in the defaults
+func (p *AnthropicProvider) settings(operation prompts.Operation) types.ModelSettings {
+	return config.MergeModelSettings(DefaultModelSettings, config.ModelSettingsFor(p.ai, string(operation)))
+}
+
+// messageParams builds a request for the given settings and conversation
+func messageParams(settings types.ModelSettings, messages ...anthropic.MessageParam) anthropic.MessageNewParams {
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.F(anthropic.Model(settings.Model)),
+		MaxTokens: anthropic.F(settings.MaxTokens),
+		Messages:  anthropic.F(messages),
+	}
+	if settings.Temperature != nil {
+		params.Temperature = anthropic.F(*settings.Temperature)
+	}
+	if settings.SystemPrompt != "" {
+		params.System = anthropic.F([]anthropic.TextBlockParam{anthropic.NewTextBlock(settings.SystemPrompt)})
+	}
+	return params
+}
+
+// recordUsage converts the usage reported by the API and adds it to the run's totals
+func (p *AnthropicProvider) recordUsage(operation string, model string, msg *anthropic.Message, duration time.Duration) types.Usage {
+	usage := types.Usage{
+		Calls:               1,
+		InputTokens:         msg.Usage.InputTokens,
+		OutputTokens:        msg.Usage.OutputTokens,
+		CacheCreationTokens: msg.Usage.CacheCreationInputTokens,
+		CacheReadTokens:     msg.Usage.CacheReadInputTokens,
+		Duration:            duration,
+	}
+	if p.usage != nil {
+		usage = p.usage.Record(operation, model, usage)
+	}
+
+	slog.Debug("completion finished",
+		"promptId", operation,
+		"inputTokens", usage.InputTokens,
+		"outputTokens", usage.OutputTokens,
+		"cacheReadTokens", usage.CacheReadTokens,
+		"duration", duration,
+		"cost", usage.Cost)
+
+	return usage
+}
@@ -6,6 +6,9 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/gwkline/artestian/pkg/prompt_logger"
+	"github.com/gwkline/artestian/pkg/prompts"
+	"github.com/gwkline/artestian/pkg/usage"
+	"github.com/gwkline/artestian/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,7 +52,10 @@ func TestNewAnthropicProvider(t *testing.T) {
 			if err != nil {
 				t.Fatalf("failed to create prompt logger: %v", err)
 			}
-			provider, err := NewAnthropicProvider(logger)
+			renderer, err := prompts.NewRenderer(types.PromptTemplates{})
+			assert.NoError(t, err)
+
+			provider, err := NewAnthropicProvider(logger, renderer, usage.NewTracker(nil, usage.Limits{}), types.AI{})
 
 			// Assert
 			if tt.expectedError != "" {
@@ -65,3 +71,76 @@ func TestNewAnthropicProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestAnthropicProvider_Settings(t *testing.T) {
+	temperature := 0.2
+	ai := types.AI{
+		ModelSettings: types.ModelSettings{Temperature: &temperature, SystemPrompt: "You write Go tests."},
+		Operations: map[string]types.ModelSettings{
+			"pick_example": {Model: "claude-3-5-haiku-latest", MaxTokens: 256},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		provider  *AnthropicProvider
+		operation prompts.Operation
+		expected  types.ModelSettings
+	}{
+		{
+			name:      "defaults",
+			provider:  &AnthropicProvider{},
+			operation: prompts.OperationGenerateTest,
+			expected:  DefaultModelSettings,
+		},
+		{
+			name:      "configured defaults",
+			provider:  &AnthropicProvider{ai: ai},
+			operation: prompts.OperationFixTypeErrors,
+			expected: types.ModelSettings{
+				Provider:     "anthropic",
+				Model:        DefaultModelSettings.Model,
+				MaxTokens:    DefaultModelSettings.MaxTokens,
+				Temperature:  &temperature,
+				SystemPrompt: "You write Go tests.",
+			},
+		},
+		{
+			name:      "operation override",
+			provider:  &AnthropicProvider{ai: ai},
+			operation: prompts.OperationPickExample,
+			expected: types.ModelSettings{
+				Provider:     "anthropic",
+				Model:        "claude-3-5-haiku-latest",
+				MaxTokens:    256,
+				Temperature:  &temperature,
+				SystemPrompt: "You write Go tests.",
+			},
+		},
+		{
+			name:      "fallback model replaces every operation",
+			provider:  (&AnthropicProvider{ai: ai}).WithModel("claude-3-5-sonnet-20241022"),
+			operation: prompts.OperationPickExample,
+			expected: types.ModelSettings{
+				Provider:     "anthropic",
+				Model:        "claude-3-5-sonnet-20241022",
+				MaxTokens:    256,
+				Temperature:  &temperature,
+				SystemPrompt: "You write Go tests.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := tt.provider.settings(tt.operation)
+			assert.Equal(t, tt.expected, settings)
+
+			params := messageParams(settings, anthropic.NewUserMessage(anthropic.NewTextBlock("prompt")))
+			assert.Equal(t, anthropic.Model(tt.expected.Model), params.Model.Value)
+			assert.Equal(t, tt.expected.MaxTokens, params.MaxTokens.Value)
+			assert.Equal(t, tt.expected.Temperature != nil, params.Temperature.Present)
+			assert.Equal(t, tt.expected.SystemPrompt != "", params.System.Present)
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package agent
 
 import (
+	"context"
 	"log"
 	"strings"
 	"testing"
 
 	"github.com/gwkline/artestian/pkg/prompt_logger"
+	"github.com/gwkline/artestian/pkg/prompts"
+	"github.com/gwkline/artestian/pkg/usage"
 	"github.com/gwkline/artestian/types"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
@@ -71,11 +74,14 @@ func TestAddition(t *testing.T) {
 			logger, err := prompt_logger.Init(false)
 			assert.NoError(t, err)
 
-			provider, err := NewAnthropicProvider(logger)
+			renderer, err := prompts.NewRenderer(types.PromptTemplates{})
+			assert.NoError(t, err)
+
+			provider, err := NewAnthropicProvider(logger, renderer, usage.NewTracker(nil, usage.Limits{}), types.AI{})
 			assert.NoError(t, err)
 
 			// Execute
-			result, err := provider.FixTestFailures(types.IterateTestParams{
+			result, err := provider.FixTestFailures(context.Background(), types.IterateTestParams{
 				GenerateTestParams: types.GenerateTestParams{
 					SourceCode:   tt.sourceCode,
 					ContextFiles: tt.contextFiles,
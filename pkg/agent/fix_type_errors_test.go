@@ -1,11 +1,14 @@
 package agent
 
 import (
+	"context"
 	"log"
 	"strings"
 	"testing"
 
 	"github.com/gwkline/artestian/pkg/prompt_logger"
+	"github.com/gwkline/artestian/pkg/prompts"
+	"github.com/gwkline/artestian/pkg/usage"
 	"github.com/gwkline/artestian/types"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
@@ -69,10 +72,13 @@ func TestAddition(t *testing.T) {
 			logger, err := prompt_logger.Init(false)
 			assert.NoError(t, err)
 
-			provider, err := NewAnthropicProvider(logger)
+			renderer, err := prompts.NewRenderer(types.PromptTemplates{})
 			assert.NoError(t, err)
 
-			result, err := provider.FixTypeErrors(types.IterateTestParams{
+			provider, err := NewAnthropicProvider(logger, renderer, usage.NewTracker(nil, usage.Limits{}), types.AI{})
+			assert.NoError(t, err)
+
+			result, err := provider.FixTypeErrors(context.Background(), types.IterateTestParams{
 				GenerateTestParams: types.GenerateTestParams{
 					ContextFiles: tt.contextFiles,
 				},
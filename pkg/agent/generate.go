@@ -5,60 +5,51 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
-	"github.com/gwkline/artestian/pkg/prompt_utils"
+	"github.com/gwkline/artestian/pkg/prompts"
 	"github.com/gwkline/artestian/types"
 )
 
-func (p *AnthropicProvider) GenerateTest(params types.GenerateTestParams) (string, error) {
+func (p *AnthropicProvider) GenerateTest(ctx context.Context, params types.GenerateTestParams) (string, error) {
 	slog.Debug("preparing test generation prompt",
 		"exampleName", params.Example.Name,
 		"exampleType", params.Example.Type,
+		"otherExamples", len(params.OtherExamples),
 		"sourceCodeLength", len(params.SourceCode))
 
-	xmlParams, err := prompt_utils.StructToXMLString(params)
+	prompt, err := p.prompts.GenerateTest(params)
 	if err != nil {
-		return "", fmt.Errorf("failed to format params: %w", err)
+		return "", err
 	}
 
-	prompt := fmt.Sprintf(`Generate a test for the function provided. 
-The test should:
-	1. Follow the same/similar patterns as the example
-	2. Focus on testing the core functionality and happy path. Don't waste time testing unlikely edge cases or invalid inputs
-	3. You should basically never use mocks, except for external API calls
-	4. Use the supplied language and test runner to write the test
-	5. Include all necessary imports, the current working directory is %s
-
-Return ONLY the test code, no explanations.
-
-%s`, params.TestPath, xmlParams)
+	settings := p.settings(prompts.OperationGenerateTest)
 	slog.Info("completion started",
 		"promptId", "generate_test",
-		"model", anthropic.ModelClaude3_5SonnetLatest,
-		"maxTokens", MAX_TOKENS)
+		"model", settings.Model,
+		"maxTokens", settings.MaxTokens)
 
-	msg, err := p.client.Messages.New(context.Background(), anthropic.MessageNewParams{
-		Model:     anthropic.F(anthropic.ModelClaude3_5SonnetLatest),
-		MaxTokens: anthropic.F(int64(MAX_TOKENS)),
-		Messages: anthropic.F([]anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-			anthropic.NewAssistantMessage(anthropic.NewTextBlock(fmt.Sprintf("```%s", params.Language.GetName()))),
-		}),
-	})
+	start := time.Now()
+	msg, err := p.client.Messages.New(ctx, messageParams(settings,
+		anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		anthropic.NewAssistantMessage(anthropic.NewTextBlock(fmt.Sprintf("```%s", params.Language.GetName()))),
+	))
 
 	if err != nil {
-		if err := p.logger.Log("generate_test", prompt, ""); err != nil {
+		if err := p.logger.Log("generate_test", prompt, "", types.Usage{}); err != nil {
 			slog.Warn("failed to log prompt", "error", err)
 		}
 		slog.Error("failed to generate test with Anthropic API", "error", err)
 		return "", fmt.Errorf("failed to generate test: %w", err)
 	}
 
+	usage := p.recordUsage("generate_test", settings.Model, msg, time.Since(start))
+
 	response := removeBackticks(fmt.Sprintf("```%s%s", params.Language.GetName(), msg.Content[0].Text))
 
 	// Log the prompt and response
-	if err := p.logger.Log("generate_test", prompt, response); err != nil {
+	if err := p.logger.Log("generate_test", prompt, response, usage); err != nil {
 		slog.Warn("failed to log prompt", "error", err)
 	}
 
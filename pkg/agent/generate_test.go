@@ -1,11 +1,14 @@
 package agent
 
 import (
+	"context"
 	"log"
 	"testing"
 
 	"github.com/gwkline/artestian/pkg/golang"
 	"github.com/gwkline/artestian/pkg/prompt_logger"
+	"github.com/gwkline/artestian/pkg/prompts"
+	"github.com/gwkline/artestian/pkg/usage"
 	"github.com/gwkline/artestian/types"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
@@ -56,10 +59,13 @@ func Multiply(a int, b int) int {
 			logger, err := prompt_logger.Init(false)
 			assert.NoError(t, err)
 
-			provider, err := NewAnthropicProvider(logger)
+			renderer, err := prompts.NewRenderer(types.PromptTemplates{})
 			assert.NoError(t, err)
 
-			result, err := provider.GenerateTest(tt.params)
+			provider, err := NewAnthropicProvider(logger, renderer, usage.NewTracker(nil, usage.Limits{}), types.AI{})
+			assert.NoError(t, err)
+
+			result, err := provider.GenerateTest(context.Background(), tt.params)
 
 			if tt.expectedError != "" {
 				assert.Error(t, err)
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter enforces client-side requests-per-minute and tokens-per-minute limits over a
+// sliding window. It is safe for concurrent use, so one limiter can be shared by every
+// agent talking to the same account.
+type Limiter struct {
+	mu                sync.Mutex
+	requestsPerMinute int
+	tokensPerMinute   int
+	window            time.Duration
+	events            []limiterEvent
+	pausedUntil       time.Time
+}
+
+type limiterEvent struct {
+	at       time.Time
+	requests int
+	tokens   int
+}
+
+// NewLimiter creates a limiter. Zero limits are unlimited.
+func NewLimiter(requestsPerMinute, tokensPerMinute int) *Limiter {
+	return &Limiter{
+		requestsPerMinute: requestsPerMinute,
+		tokensPerMinute:   tokensPerMinute,
+		window:            time.Minute,
+	}
+}
+
+// Wait blocks until a request of roughly tokens fits within the limits, then reserves it
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	for {
+		delay := l.reserve(time.Now(), tokens)
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Add counts tokens that are only known after a request, like the response
+func (l *Limiter) Add(tokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, limiterEvent{at: time.Now(), tokens: tokens})
+}
+
+// Pause holds back every request until d has passed, e.g. when the provider asks to retry later
+func (l *Limiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// reserve records the request and returns zero if it fits, or how long to wait before trying again
+func (l *Limiter) reserve(now time.Time, tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	// Drop events that have left the window
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(l.events) && !l.events[i].at.After(cutoff) {
+		i++
+	}
+	l.events = l.events[i:]
+
+	var requests, used int
+	for _, event := range l.events {
+		requests += event.requests
+		used += event.tokens
+	}
+
+	// A request larger than the whole budget is let through once the window is empty
+	fits := (l.requestsPerMinute <= 0 || requests < l.requestsPerMinute) &&
+		(l.tokensPerMinute <= 0 || used+tokens <= l.tokensPerMinute || len(l.events) == 0)
+	if fits {
+		l.events = append(l.events, limiterEvent{at: now, requests: 1, tokens: tokens})
+		return 0
+	}
+
+	return l.events[0].at.Add(l.window).Sub(now)
+}
@@ -5,68 +5,44 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gwkline/artestian/pkg/prompts"
 	"github.com/gwkline/artestian/types"
 )
 
 const assistantMessage = `{"exampleIndex":`
 
-func (p *AnthropicProvider) PickExample(sourceCode string, testExamples []types.TestExample) (types.TestExample, error) {
+func (p *AnthropicProvider) PickExample(ctx context.Context, sourceCode string, testExamples []types.TestExample) (types.TestExample, error) {
 	if len(testExamples) == 0 {
 		return types.TestExample{}, fmt.Errorf("no test examples provided")
 	}
 
-	type TruncatedExample struct {
-		Name        string
-		Type        types.TestType
-		Description string
-	}
-
-	truncatedExamples := make([]TruncatedExample, len(testExamples))
-	for i, example := range testExamples {
-		truncatedExamples[i] = TruncatedExample{
-			Name:        example.Name,
-			Type:        example.Type,
-			Description: example.Description,
-		}
+	prompt, err := p.prompts.PickExample(sourceCode, testExamples)
+	if err != nil {
+		return types.TestExample{}, err
 	}
 
-	prompt := fmt.Sprintf(`Given this source code:
-
-%s
-
-And these test examples:
-
-%v
-
-Which test example would be the best match for testing this code? Consider:
-1. The complexity and structure of the code
-2. The testing patterns demonstrated in each example
-3. The similarity between the example and what needs to be tested
-
-Return a JSON object with the key "exampleIndex" and the value being the index number of the best matching example. 
-Do not include any other text or explanations in your response.`, sourceCode, truncatedExamples)
-
+	settings := p.settings(prompts.OperationPickExample)
 	slog.Info("completion started",
 		"promptId", "pick_example",
-		"model", anthropic.ModelClaude3_5SonnetLatest,
-		"maxTokens", MAX_TOKENS)
-
-	msg, err := p.client.Messages.New(context.Background(), anthropic.MessageNewParams{
-		Model:     anthropic.F(anthropic.ModelClaude3_5SonnetLatest),
-		MaxTokens: anthropic.F(int64(MAX_TOKENS)),
-		Messages: anthropic.F([]anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-			anthropic.NewAssistantMessage(anthropic.NewTextBlock(assistantMessage)),
-		}),
-	})
+		"model", settings.Model,
+		"maxTokens", settings.MaxTokens)
+
+	start := time.Now()
+	msg, err := p.client.Messages.New(ctx, messageParams(settings,
+		anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+		anthropic.NewAssistantMessage(anthropic.NewTextBlock(assistantMessage)),
+	))
 
 	if err != nil {
 		slog.Error("failed to pick example with Anthropic API", "error", err)
 		return types.TestExample{}, fmt.Errorf("failed to pick example: %w", err)
 	}
 
+	usage := p.recordUsage("pick_example", settings.Model, msg, time.Since(start))
+
 	response := msg.Content[0].Text
 
 	selectedIndex, err := parseExampleIndex(response)
@@ -74,7 +50,7 @@ Do not include any other text or explanations in your response.`, sourceCode, tr
 		return types.TestExample{}, fmt.Errorf("failed to parse example index: %w", err)
 	}
 
-	if err := p.logger.Log("pick_example", prompt, msg.Content[0].Text); err != nil {
+	if err := p.logger.Log("pick_example", prompt, msg.Content[0].Text, usage); err != nil {
 		slog.Warn("failed to log prompt", "error", err)
 	}
 
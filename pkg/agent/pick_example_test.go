@@ -1,10 +1,13 @@
 package agent
 
 import (
+	"context"
 	"log"
 	"testing"
 
 	"github.com/gwkline/artestian/pkg/prompt_logger"
+	"github.com/gwkline/artestian/pkg/prompts"
+	"github.com/gwkline/artestian/pkg/usage"
 	"github.com/gwkline/artestian/types"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
@@ -77,10 +80,13 @@ func TestAdd(t *testing.T) {
 			logger, err := prompt_logger.Init(false)
 			assert.NoError(t, err)
 
-			provider, err := NewAnthropicProvider(logger)
+			renderer, err := prompts.NewRenderer(types.PromptTemplates{})
 			assert.NoError(t, err)
 
-			result, err := provider.PickExample(tt.sourceCode, tt.testExamples)
+			provider, err := NewAnthropicProvider(logger, renderer, usage.NewTracker(nil, usage.Limits{}), types.AI{})
+			assert.NoError(t, err)
+
+			result, err := provider.PickExample(context.Background(), tt.sourceCode, tt.testExamples)
 
 			if tt.expectedError != "" {
 				assert.Error(t, err)
@@ -0,0 +1,233 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gwkline/artestian/pkg/prompt_utils"
+	"github.com/gwkline/artestian/types"
+)
+
+// RetryOptions configures a RetryingAgent. Zero values use the defaults.
+type RetryOptions struct {
+	MaxRetries int           // Retries per agent before falling back, default 4
+	BaseDelay  time.Duration // Delay before the first retry, doubled for each retry, default 2s
+	MaxDelay   time.Duration // Longest delay between retries, default 1m
+	Limiter    *Limiter      // Shared rate limiter, nil for none
+}
+
+// RetryableError marks an error as temporary, to be retried after RetryAfter if it is set
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// RetryingAgent wraps agents with retries, exponential backoff with jitter and rate limiting.
+// When an agent keeps failing with temporary errors, the next one is tried, so fallbacks can
+// be other models or providers.
+type RetryingAgent struct {
+	agents  []types.IAgent
+	options RetryOptions
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetryingAgent(primary types.IAgent, options RetryOptions, fallbacks ...types.IAgent) *RetryingAgent {
+	if options.MaxRetries == 0 {
+		options.MaxRetries = 4
+	}
+	if options.BaseDelay == 0 {
+		options.BaseDelay = 2 * time.Second
+	}
+	if options.MaxDelay == 0 {
+		options.MaxDelay = time.Minute
+	}
+
+	return &RetryingAgent{
+		agents:  append([]types.IAgent{primary}, fallbacks...),
+		options: options,
+		sleep:   sleep,
+	}
+}
+
+func (r *RetryingAgent) GenerateTest(ctx context.Context, params types.GenerateTestParams) (string, error) {
+	return do(ctx, r, "generate_test", estimateTokens(params), func(agent types.IAgent) (string, error) {
+		return agent.GenerateTest(ctx, params)
+	}, prompt_utils.EstimateTokens)
+}
+
+func (r *RetryingAgent) FixTypeErrors(ctx context.Context, params types.IterateTestParams) (string, error) {
+	return do(ctx, r, "fix_type_errors", estimateTokens(params), func(agent types.IAgent) (string, error) {
+		return agent.FixTypeErrors(ctx, params)
+	}, prompt_utils.EstimateTokens)
+}
+
+func (r *RetryingAgent) FixTestFailures(ctx context.Context, params types.IterateTestParams) (string, error) {
+	return do(ctx, r, "fix_test_failures", estimateTokens(params), func(agent types.IAgent) (string, error) {
+		return agent.FixTestFailures(ctx, params)
+	}, prompt_utils.EstimateTokens)
+}
+
+func (r *RetryingAgent) PickExample(ctx context.Context, sourceCode string, testExamples []types.TestExample) (types.TestExample, error) {
+	tokens := prompt_utils.EstimateTokens(sourceCode)
+	for _, example := range testExamples {
+		tokens += prompt_utils.EstimateTokens(example.Name + example.Description)
+	}
+
+	return do(ctx, r, "pick_example", tokens, func(agent types.IAgent) (types.TestExample, error) {
+		return agent.PickExample(ctx, sourceCode, testExamples)
+	}, func(types.TestExample) int { return 0 })
+}
+
+// do calls each agent in turn until one succeeds, retrying temporary errors with backoff.
+// responseTokens estimates the size of a response for the tokens-per-minute limit.
+func do[T any](ctx context.Context, r *RetryingAgent, operation string, tokens int, call func(types.IAgent) (T, error), responseTokens func(T) int) (T, error) {
+	var zero T
+	var err error
+
+	for i, agent := range r.agents {
+		if i > 0 {
+			slog.Warn("falling back to next agent", "promptId", operation, "agent", agentName(agent), "error", err)
+		}
+
+		for attempt := 0; attempt <= r.options.MaxRetries; attempt++ {
+			if r.options.Limiter != nil {
+				if err := r.options.Limiter.Wait(ctx, tokens); err != nil {
+					return zero, err
+				}
+			}
+
+			var result T
+			result, err = call(agent)
+			if err == nil {
+				if r.options.Limiter != nil {
+					r.options.Limiter.Add(responseTokens(result))
+				}
+				return result, nil
+			}
+
+			retryable, retryAfter := classify(err)
+			if !retryable || ctx.Err() != nil {
+				return zero, err
+			}
+			if attempt == r.options.MaxRetries {
+				break
+			}
+
+			delay := r.backoff(attempt)
+			if retryAfter > 0 {
+				delay = retryAfter
+				if r.options.Limiter != nil {
+					// The provider is throttling the account, so hold back every caller
+					r.options.Limiter.Pause(retryAfter)
+				}
+			}
+
+			slog.Warn("retrying agent call",
+				"promptId", operation,
+				"agent", agentName(agent),
+				"attempt", attempt+1,
+				"delay", delay,
+				"error", err)
+
+			if err := r.sleep(ctx, delay); err != nil {
+				return zero, err
+			}
+		}
+	}
+
+	return zero, fmt.Errorf("%s failed after retries: %w", operation, err)
+}
+
+// backoff returns the delay before a retry: exponential, capped, with jitter in [d/2, d]
+func (r *RetryingAgent) backoff(attempt int) time.Duration {
+	delay := r.options.BaseDelay << attempt
+	if delay <= 0 || delay > r.options.MaxDelay {
+		delay = r.options.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// classify reports whether an error is temporary and how long the provider asked to wait
+func classify(err error) (bool, time.Duration) {
+	var retryable *RetryableError
+	if errors.As(err, &retryable) {
+		return true, retryable.RetryAfter
+	}
+
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusRequestTimeout,
+			apiErr.StatusCode == http.StatusConflict,
+			apiErr.StatusCode == http.StatusTooManyRequests,
+			apiErr.StatusCode >= http.StatusInternalServerError: // Includes 529 overloaded
+			return true, retryAfter(apiErr.Response)
+		}
+	}
+
+	return false, 0
+}
+
+// retryAfter parses the retry-after-ms and retry-after headers of a response
+func retryAfter(response *http.Response) time.Duration {
+	if response == nil {
+		return 0
+	}
+
+	if ms, err := strconv.ParseFloat(response.Header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	value := response.Header.Get("retry-after")
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// estimateTokens approximates the prompt size of a request for the tokens-per-minute limit
+func estimateTokens(params interface{}) int {
+	prompt, err := prompt_utils.StructToXMLString(params)
+	if err != nil {
+		return 0
+	}
+	return prompt_utils.EstimateTokens(prompt)
+}
+
+func agentName(agent types.IAgent) string {
+	if n, ok := agent.(interface{ GetName() string }); ok {
+		return n.GetName()
+	}
+	return fmt.Sprintf("%T", agent)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
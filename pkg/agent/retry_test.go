@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gwkline/artestian/pkg/agent/agenttest"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func apiError(status int, header http.Header) error {
+	return &anthropic.Error{
+		StatusCode: status,
+		Request:    httptest.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil),
+		Response:   &http.Response{StatusCode: status, Header: header},
+	}
+}
+
+func TestRetryingAgent(t *testing.T) {
+	overloaded := apiError(529, http.Header{})
+	rateLimited := apiError(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"7"}})
+	badRequest := apiError(http.StatusBadRequest, http.Header{})
+
+	tests := []struct {
+		name              string
+		primaryErrors     []error
+		fallbackErrors    []error
+		expected          string
+		expectedError     error
+		expectedPrimary   int
+		expectedFallback  int
+		expectedDelays    int
+		expectedMinDelays []time.Duration
+	}{
+		{
+			name:            "succeeds first time",
+			expected:        "primary",
+			expectedPrimary: 1,
+		},
+		{
+			name:            "retries overloaded errors",
+			primaryErrors:   []error{overloaded, overloaded},
+			expected:        "primary",
+			expectedPrimary: 3,
+			expectedDelays:  2,
+		},
+		{
+			name:              "honours retry-after",
+			primaryErrors:     []error{rateLimited},
+			expected:          "primary",
+			expectedPrimary:   2,
+			expectedDelays:    1,
+			expectedMinDelays: []time.Duration{7 * time.Second},
+		},
+		{
+			name:            "does not retry bad requests",
+			primaryErrors:   []error{badRequest},
+			expectedError:   badRequest,
+			expectedPrimary: 1,
+		},
+		{
+			name:             "falls back after retries",
+			primaryErrors:    []error{overloaded, overloaded, overloaded},
+			expected:         "fallback",
+			expectedPrimary:  3,
+			expectedFallback: 1,
+			expectedDelays:   2,
+		},
+		{
+			name:             "fails when every agent is exhausted",
+			primaryErrors:    []error{overloaded, overloaded, overloaded},
+			fallbackErrors:   []error{&RetryableError{Err: errors.New("down")}, overloaded, overloaded},
+			expectedError:    overloaded,
+			expectedPrimary:  3,
+			expectedFallback: 3,
+			expectedDelays:   4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &agenttest.Agent{Name: "primary", Response: "primary", Errors: tt.primaryErrors}
+			fallback := &agenttest.Agent{Name: "fallback", Response: "fallback", Errors: tt.fallbackErrors}
+
+			retrying := NewRetryingAgent(primary, RetryOptions{MaxRetries: 2, BaseDelay: time.Second}, fallback)
+			var delays []time.Duration
+			retrying.sleep = func(ctx context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			}
+
+			result, err := retrying.GenerateTest(context.Background(), types.GenerateTestParams{SourceCode: "func Add() {}"})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			assert.Equal(t, tt.expectedPrimary, primary.CallCount())
+			assert.Equal(t, tt.expectedFallback, fallback.CallCount())
+			assert.Len(t, delays, tt.expectedDelays)
+			for i, min := range tt.expectedMinDelays {
+				assert.GreaterOrEqual(t, delays[i], min)
+			}
+		})
+	}
+}
+
+func TestRetryingAgent_Backoff(t *testing.T) {
+	retrying := NewRetryingAgent(&agenttest.Agent{}, RetryOptions{BaseDelay: time.Second, MaxDelay: 10 * time.Second})
+
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		delay := retrying.backoff(attempt)
+		assert.GreaterOrEqual(t, delay, max/2, "attempt %d", attempt)
+		assert.LessOrEqual(t, delay, max, "attempt %d", attempt)
+	}
+}
+
+func TestRetryingAgent_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	primary := &agenttest.Agent{Errors: []error{apiError(529, http.Header{})}}
+
+	retrying := NewRetryingAgent(primary, RetryOptions{})
+	retrying.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	_, err := retrying.FixTypeErrors(ctx, types.IterateTestParams{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, primary.CallCount())
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+	}{
+		{name: "no header", header: http.Header{}},
+		{name: "milliseconds", header: http.Header{"Retry-After-Ms": []string{"1500"}}, expected: 1500 * time.Millisecond},
+		{name: "seconds", header: http.Header{"Retry-After": []string{"30"}}, expected: 30 * time.Second},
+		{name: "invalid", header: http.Header{"Retry-After": []string{"soon"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, retryAfter(&http.Response{Header: tt.header}))
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("requests per minute", func(t *testing.T) {
+		limiter := NewLimiter(2, 0)
+		now := time.Now()
+
+		assert.Zero(t, limiter.reserve(now, 0))
+		assert.Zero(t, limiter.reserve(now, 0))
+		assert.Equal(t, time.Minute, limiter.reserve(now, 0))
+		assert.Zero(t, limiter.reserve(now.Add(time.Minute), 0))
+	})
+
+	t.Run("tokens per minute", func(t *testing.T) {
+		limiter := NewLimiter(0, 1000)
+		now := time.Now()
+
+		assert.Zero(t, limiter.reserve(now, 600))
+		assert.Equal(t, 50*time.Second, limiter.reserve(now.Add(10*time.Second), 600))
+		assert.Zero(t, limiter.reserve(now.Add(10*time.Second), 400))
+	})
+
+	t.Run("oversized request runs alone", func(t *testing.T) {
+		limiter := NewLimiter(0, 1000)
+		assert.Zero(t, limiter.reserve(time.Now(), 5000))
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		limiter := NewLimiter(0, 0)
+		limiter.Pause(time.Hour)
+		assert.Greater(t, limiter.reserve(time.Now(), 0), 59*time.Minute)
+	})
+
+	t.Run("shared across goroutines", func(t *testing.T) {
+		limiter := NewLimiter(5, 0)
+		limiter.window = 50 * time.Millisecond
+
+		var wg sync.WaitGroup
+		start := time.Now()
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, limiter.Wait(context.Background(), 0))
+			}()
+		}
+		wg.Wait()
+
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+}
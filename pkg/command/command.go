@@ -0,0 +1,39 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// WaitDelay is how long a cancelled command has to exit after being interrupted
+// before it is killed
+var WaitDelay = 5 * time.Second
+
+// New returns a command run in dir that is interrupted when ctx is done, so tools
+// like go test and jest get a chance to stop their own child processes
+func New(ctx context.Context, dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = WaitDelay
+	return cmd
+}
+
+// TimedOut reports whether ctx ended because its deadline passed
+func TimedOut(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// Canceled returns ctx's error if it was cancelled rather than timed out, meaning
+// the caller asked to stop and the command's output should be discarded
+func Canceled(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	t.Run("deadline interrupts the command", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := New(ctx, t.TempDir(), "sleep", "10").Run()
+
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.True(t, TimedOut(ctx))
+		assert.NoError(t, Canceled(ctx))
+	})
+
+	t.Run("cancellation is reported", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := New(ctx, t.TempDir(), "sleep", "10").Run()
+
+		assert.Error(t, err)
+		assert.False(t, TimedOut(ctx))
+		assert.ErrorIs(t, Canceled(ctx), context.Canceled)
+	})
+}
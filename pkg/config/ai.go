@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gwkline/artestian/pkg/prompts"
+	"github.com/gwkline/artestian/types"
+)
+
+// Providers lists the AI providers artestian can use
+var Providers = []string{"anthropic"}
+
+// GetAI returns the model settings, including any environment overrides
+func (c *Config) GetAI() types.AI {
+	return c.AI
+}
+
+// OverrideAI returns ai with the non-empty fields of override applied to every operation
+func OverrideAI(ai types.AI, override types.ModelSettings) types.AI {
+	ai.ModelSettings = MergeModelSettings(ai.ModelSettings, override)
+
+	operations := make(map[string]types.ModelSettings, len(ai.Operations))
+	for op, settings := range ai.Operations {
+		operations[op] = MergeModelSettings(settings, override)
+	}
+	ai.Operations = operations
+	return ai
+}
+
+// applyAIEnv applies ARTESTIAN_AI_<SETTING> to every operation, then
+// ARTESTIAN_AI_<OPERATION>_<SETTING> to a single operation
+func (c *Config) applyAIEnv() error {
+	override, err := modelSettingsFromEnv("ARTESTIAN_AI_")
+	if err != nil {
+		return err
+	}
+	c.AI = OverrideAI(c.AI, override)
+
+	for _, op := range prompts.Operations {
+		override, err := modelSettingsFromEnv("ARTESTIAN_AI_" + strings.ToUpper(string(op)) + "_")
+		if err != nil {
+			return err
+		}
+		if override == (types.ModelSettings{}) {
+			continue
+		}
+		if c.AI.Operations == nil {
+			c.AI.Operations = make(map[string]types.ModelSettings)
+		}
+		c.AI.Operations[string(op)] = MergeModelSettings(c.AI.Operations[string(op)], override)
+	}
+	return nil
+}
+
+func modelSettingsFromEnv(prefix string) (types.ModelSettings, error) {
+	settings := types.ModelSettings{
+		Provider:     os.Getenv(prefix + "PROVIDER"),
+		Model:        os.Getenv(prefix + "MODEL"),
+		SystemPrompt: os.Getenv(prefix + "SYSTEM_PROMPT"),
+	}
+
+	if value := os.Getenv(prefix + "MAX_TOKENS"); value != "" {
+		maxTokens, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return types.ModelSettings{}, fmt.Errorf("invalid %sMAX_TOKENS %q: %w", prefix, value, err)
+		}
+		settings.MaxTokens = maxTokens
+	}
+	if value := os.Getenv(prefix + "TEMPERATURE"); value != "" {
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return types.ModelSettings{}, fmt.Errorf("invalid %sTEMPERATURE %q: %w", prefix, value, err)
+		}
+		settings.Temperature = &temperature
+	}
+
+	return settings, nil
+}
+
+// ModelSettingsFor returns the settings of an operation, falling back to the defaults
+func ModelSettingsFor(ai types.AI, operation string) types.ModelSettings {
+	return MergeModelSettings(ai.ModelSettings, ai.Operations[operation])
+}
+
+// MergeModelSettings returns base with the non-empty fields of override applied
+func MergeModelSettings(base, override types.ModelSettings) types.ModelSettings {
+	if override.Provider != "" {
+		base.Provider = override.Provider
+	}
+	if override.Model != "" {
+		base.Model = override.Model
+	}
+	if override.MaxTokens != 0 {
+		base.MaxTokens = override.MaxTokens
+	}
+	if override.Temperature != nil {
+		base.Temperature = override.Temperature
+	}
+	if override.SystemPrompt != "" {
+		base.SystemPrompt = override.SystemPrompt
+	}
+	return base
+}
+
+// ValidateAI checks the default and per-operation model settings
+func ValidateAI(ai types.AI) error {
+	if err := validateModelSettings(ai.ModelSettings); err != nil {
+		return fmt.Errorf("ai: %w", err)
+	}
+
+	for op, settings := range ai.Operations {
+		if !prompts.IsValidOperation(op) {
+			var ops []string
+			for _, o := range prompts.Operations {
+				ops = append(ops, string(o))
+			}
+			return fmt.Errorf("ai: unknown operation %q. Must be one of: %s", op, strings.Join(ops, ", "))
+		}
+		if err := validateModelSettings(settings); err != nil {
+			return fmt.Errorf("ai operation %s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+func validateModelSettings(settings types.ModelSettings) error {
+	if settings.Provider != "" && !isValidProvider(settings.Provider) {
+		return fmt.Errorf("unsupported provider %q. Must be one of: %s", settings.Provider, strings.Join(Providers, ", "))
+	}
+	if settings.Model != "" && strings.TrimSpace(settings.Model) != settings.Model {
+		return fmt.Errorf("model %q cannot contain surrounding whitespace", settings.Model)
+	}
+	if settings.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens cannot be negative")
+	}
+	if settings.Temperature != nil && (*settings.Temperature < 0 || *settings.Temperature > 1) {
+		return fmt.Errorf("temperature must be between 0 and 1, got %g", *settings.Temperature)
+	}
+	return nil
+}
+
+func isValidProvider(provider string) bool {
+	for _, p := range Providers {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
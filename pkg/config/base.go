@@ -10,16 +10,15 @@ import (
 	"github.com/gwkline/artestian/types"
 )
 
-type ContextFile struct {
-	Path        string `json:"path"`
-	Description string `json:"description"`
-	Type        string `json:"type"` // e.g., "types", "utils", "constants", etc.
-}
 type Config struct {
-	Version  string          `json:"version"`
-	Examples []types.Example `json:"examples"`
-	Settings types.Settings  `json:"settings"`
-	Context  types.Context   `json:"context"`
+	Version  string                 `json:"version"`
+	Examples []types.Example        `json:"examples"`
+	Settings types.Settings         `json:"settings"`
+	Context  types.Context          `json:"context"`
+	Prompts  types.Prompts          `json:"prompts"`
+	Pricing  map[string]types.Price `json:"pricing"` // Model name to price, overriding the built-in prices
+	Budget   types.Budget           `json:"budget"`
+	AI       types.AI               `json:"ai"`
 	basePath string
 }
 
@@ -107,6 +106,11 @@ func Init(configPath string) (types.IConfig, error) {
 	// Store the base path
 	config.basePath = absPath
 
+	// Environment variables override the file, and are validated with it
+	if err := config.applyAIEnv(); err != nil {
+		return nil, err
+	}
+
 	// Validate the configuration
 	if err := config.validate(); err != nil {
 		return nil, err
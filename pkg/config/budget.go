@@ -0,0 +1,8 @@
+package config
+
+import "github.com/gwkline/artestian/types"
+
+// GetBudget returns the configured run limits
+func (c *Config) GetBudget() types.Budget {
+	return c.Budget
+}
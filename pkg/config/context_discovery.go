@@ -0,0 +1,7 @@
+package config
+
+// GetContextDiscovery returns whether declarations referenced by each function are added
+// to its prompt automatically, defaulting to true
+func (c *Config) GetContextDiscovery() bool {
+	return c.Context.Auto == nil || *c.Context.Auto
+}
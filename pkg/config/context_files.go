@@ -2,32 +2,160 @@ package config
 
 import (
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/gwkline/artestian/pkg/glob"
 	"github.com/gwkline/artestian/types"
 )
 
-// LoadContextFiles loads all context files specified in the configuration
+// skippedContextDirs are never searched when expanding a directory or glob
+var skippedContextDirs = map[string]bool{".git": true, "node_modules": true}
+
+// LoadContextFiles loads all context files specified in the configuration, expanding
+// directories and globs. A file matched by several entries is only loaded once.
 func (c *Config) LoadContextFiles() ([]types.ContextFile, error) {
 	if len(c.Context.Files) == 0 {
 		return nil, nil
 	}
 
-	files := make([]types.ContextFile, 0, len(c.Context.Files))
-	for _, file := range c.Context.Files {
-		fullPath := c.resolveFilePath(file.Path)
-		content, err := os.ReadFile(fullPath)
+	var files []types.ContextFile
+	seen := make(map[string]bool)
+	for _, source := range c.Context.Files {
+		paths, err := c.expandContextSource(source)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read context file %s: %w", fullPath, err)
+			return nil, err
 		}
 
-		files = append(files, types.ContextFile{
-			Path:        file.Path,
-			Content:     string(content),
-			Description: file.Description,
-			Type:        file.Type,
-		})
+		for _, fullPath := range paths {
+			if seen[fullPath] {
+				continue
+			}
+			seen[fullPath] = true
+
+			content, err := os.ReadFile(fullPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read context file %s: %w", fullPath, err)
+			}
+
+			files = append(files, types.ContextFile{
+				Path:        c.contextFilePath(source, fullPath),
+				Content:     string(content),
+				Description: source.Description,
+				Type:        source.Type,
+			})
+		}
 	}
 
 	return files, nil
 }
+
+// contextFilePath returns the path a context file is shown with: as configured for single
+// files, and relative to the config file for files found through a directory or glob
+func (c *Config) contextFilePath(source types.ContextSource, fullPath string) string {
+	if c.resolveFilePath(source.Path) == fullPath {
+		return source.Path
+	}
+	rel, err := filepath.Rel(c.basePath, fullPath)
+	if err != nil {
+		return fullPath
+	}
+	return filepath.ToSlash(rel)
+}
+
+// expandContextSource returns the files a context entry refers to, in lexical order. A
+// missing single file is an error, while a directory or glob may match nothing.
+func (c *Config) expandContextSource(source types.ContextSource) ([]string, error) {
+	dir, pattern := glob.Split(filepath.ToSlash(source.Path))
+	root := c.resolveFilePath(filepath.FromSlash(dir))
+
+	info, err := os.Stat(root)
+	if err != nil {
+		if pattern != "" && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read context file %s: %w", root, err)
+	}
+
+	if !info.IsDir() {
+		if pattern != "" {
+			return nil, nil
+		}
+		if source.MaxSize > 0 && info.Size() > source.MaxSize {
+			slog.Warn("skipping context file larger than max_size", "path", source.Path, "size", info.Size(), "max_size", source.MaxSize)
+			return nil, nil
+		}
+		return []string{root}, nil
+	}
+
+	if pattern == "" {
+		pattern = "**"
+	}
+
+	var paths []string
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && skippedContextDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !glob.Match(pattern, rel) || !includedContextFile(source, rel) {
+			return nil
+		}
+
+		if source.MaxSize > 0 {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			if info.Size() > source.MaxSize {
+				slog.Warn("skipping context file larger than max_size", "path", path, "size", info.Size(), "max_size", source.MaxSize)
+				return nil
+			}
+		}
+
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand context files %s: %w", source.Path, err)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// includedContextFile applies an entry's include and exclude globs to a path relative to
+// the entry's base directory
+func includedContextFile(source types.ContextSource, rel string) bool {
+	if len(source.Include) > 0 && !matchesAny(source.Include, rel) {
+		return false
+	}
+	return !matchesAny(source.Exclude, rel)
+}
+
+// matchesAny reports whether rel matches any of the patterns. Like .gitignore, a pattern
+// without a slash matches the file name at any depth.
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if glob.Match(pattern, rel) || !strings.Contains(pattern, "/") && glob.Match(pattern, path.Base(rel)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package config
+
+import "github.com/gwkline/artestian/types"
+
+// GetExampleSelection returns how examples are chosen, defaulting to asking the model
+func (c *Config) GetExampleSelection() types.ExampleSelection {
+	if c.Settings.ExampleSelection == "" {
+		return types.ExampleSelectionLLM
+	}
+	return types.ExampleSelection(c.Settings.ExampleSelection)
+}
+
+// GetExamplesPerPrompt returns how many examples to include in each prompt, defaulting to one
+func (c *Config) GetExamplesPerPrompt() int {
+	if c.Settings.ExamplesPerPrompt <= 0 {
+		return 1
+	}
+	return c.Settings.ExamplesPerPrompt
+}
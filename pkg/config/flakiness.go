@@ -0,0 +1,26 @@
+package config
+
+import "github.com/gwkline/artestian/types"
+
+// Defaults for flakiness settings left unset
+const (
+	defaultFlakinessRuns        = 3
+	defaultFlakinessMaxAttempts = 2
+)
+
+// GetFlakiness returns the settings for re-running passing tests, with defaults for
+// unset values. The check is off unless enabled, since it multiplies test runs.
+func (c *Config) GetFlakiness() types.Flakiness {
+	flakiness := c.Settings.Flakiness
+	if flakiness.Enabled == nil {
+		enabled := false
+		flakiness.Enabled = &enabled
+	}
+	if flakiness.Runs == 0 {
+		flakiness.Runs = defaultFlakinessRuns
+	}
+	if flakiness.MaxAttempts == 0 {
+		flakiness.MaxAttempts = defaultFlakinessMaxAttempts
+	}
+	return flakiness
+}
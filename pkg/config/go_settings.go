@@ -0,0 +1,8 @@
+package config
+
+import "github.com/gwkline/artestian/types"
+
+// GetGoSettings returns the go vet and go test settings
+func (c *Config) GetGoSettings() types.GoSettings {
+	return c.Settings.Go
+}
@@ -23,6 +23,8 @@ func (c *Config) LoadExamples() ([]types.TestExample, error) {
 			Type:        types.TestType(ex.Type),
 			SourceCode:  string(content),
 			Description: ex.Description,
+			AppliesTo:   ex.AppliesTo,
+			Priority:    ex.Priority,
 		})
 	}
 
@@ -0,0 +1,29 @@
+package config
+
+import "github.com/gwkline/artestian/types"
+
+// Defaults for mutation testing settings left unset
+const (
+	defaultMutationThreshold   = 0.6
+	defaultMutationMaxMutants  = 15
+	defaultMutationMaxAttempts = 2
+	defaultMutationTimeout     = "2m"
+)
+
+// GetMutation returns the mutation testing settings, with defaults for unset values
+func (c *Config) GetMutation() types.Mutation {
+	mutation := c.Settings.Mutation
+	if mutation.Threshold == 0 {
+		mutation.Threshold = defaultMutationThreshold
+	}
+	if mutation.MaxMutants == 0 {
+		mutation.MaxMutants = defaultMutationMaxMutants
+	}
+	if mutation.MaxAttempts == 0 {
+		mutation.MaxAttempts = defaultMutationMaxAttempts
+	}
+	if mutation.Timeout == "" {
+		mutation.Timeout = defaultMutationTimeout
+	}
+	return mutation
+}
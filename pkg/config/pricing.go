@@ -0,0 +1,8 @@
+package config
+
+import "github.com/gwkline/artestian/types"
+
+// GetPricing returns the configured model prices
+func (c *Config) GetPricing() map[string]types.Price {
+	return c.Pricing
+}
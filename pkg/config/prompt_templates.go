@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// LoadPromptTemplates reads the prompt template overrides specified in the configuration
+func (c *Config) LoadPromptTemplates() (types.PromptTemplates, error) {
+	templates := types.PromptTemplates{
+		Templates:     make(map[string]string),
+		ByExampleType: make(map[types.TestType]map[string]string),
+	}
+
+	for op, path := range c.Prompts.Templates {
+		content, err := c.readPromptTemplate(path)
+		if err != nil {
+			return types.PromptTemplates{}, err
+		}
+		templates.Templates[op] = content
+	}
+
+	for exampleType, overrides := range c.Prompts.ByExampleType {
+		templates.ByExampleType[types.TestType(exampleType)] = make(map[string]string)
+		for op, path := range overrides {
+			content, err := c.readPromptTemplate(path)
+			if err != nil {
+				return types.PromptTemplates{}, err
+			}
+			templates.ByExampleType[types.TestType(exampleType)][op] = content
+		}
+	}
+
+	return templates, nil
+}
+
+func (c *Config) readPromptTemplate(path string) (string, error) {
+	fullPath := c.resolveFilePath(path)
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template %s: %w", fullPath, err)
+	}
+	return string(content), nil
+}
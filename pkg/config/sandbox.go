@@ -0,0 +1,32 @@
+package config
+
+import "github.com/gwkline/artestian/types"
+
+// Defaults for sandbox settings left unset
+const (
+	defaultSandboxMode    = "auto"
+	defaultSandboxCPUTime = "10m"
+	defaultSandboxTimeout = "10m"
+)
+
+// GetSandbox returns the sandbox settings, with defaults for unset values and writable
+// directories resolved relative to the config file
+func (c *Config) GetSandbox() types.Sandbox {
+	sandbox := c.Settings.Sandbox
+	if sandbox.Mode == "" {
+		sandbox.Mode = defaultSandboxMode
+	}
+	if sandbox.CPUTime == "" {
+		sandbox.CPUTime = defaultSandboxCPUTime
+	}
+	if sandbox.Timeout == "" {
+		sandbox.Timeout = defaultSandboxTimeout
+	}
+
+	writable := make([]string, len(sandbox.Writable))
+	for i, dir := range sandbox.Writable {
+		writable[i] = c.resolveFilePath(dir)
+	}
+	sandbox.Writable = writable
+	return sandbox
+}
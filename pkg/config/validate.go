@@ -6,7 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/gwkline/artestian/pkg/glob"
+	"github.com/gwkline/artestian/pkg/prompts"
 	"github.com/gwkline/artestian/types"
 )
 
@@ -57,6 +60,13 @@ func (c *Config) validate() error {
 		if example.Description == "" {
 			return fmt.Errorf("example #%d (%s): description is required", i+1, example.Name)
 		}
+
+		// Validate pinning globs
+		for _, pattern := range example.AppliesTo {
+			if pattern == "" || !glob.Valid(pattern) {
+				return fmt.Errorf("example #%d (%s): invalid applies_to glob %q", i+1, example.Name, pattern)
+			}
+		}
 	}
 
 	// Validate context files if present
@@ -65,9 +75,32 @@ func (c *Config) validate() error {
 			return fmt.Errorf("context file #%d: path is required", i+1)
 		}
 
-		fullPath := c.resolveFilePath(file.Path)
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-			return fmt.Errorf("context file #%d: file not found at path: %s", i+1, fullPath)
+		if !glob.Valid(filepath.ToSlash(file.Path)) {
+			return fmt.Errorf("context file #%d: invalid glob %q", i+1, file.Path)
+		}
+
+		// Single files must exist, while directories and globs only warn when they match nothing
+		if _, pattern := glob.Split(filepath.ToSlash(file.Path)); pattern == "" {
+			fullPath := c.resolveFilePath(file.Path)
+			if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+				return fmt.Errorf("context file #%d: file not found at path: %s", i+1, fullPath)
+			}
+		}
+
+		for _, pattern := range append(append([]string{}, file.Include...), file.Exclude...) {
+			if pattern == "" || !glob.Valid(pattern) {
+				return fmt.Errorf("context file #%d: invalid include or exclude glob %q", i+1, pattern)
+			}
+		}
+
+		if file.MaxSize < 0 {
+			return fmt.Errorf("context file #%d: max_size must not be negative", i+1)
+		}
+
+		if paths, err := c.expandContextSource(file); err != nil {
+			return fmt.Errorf("context file #%d: %w", i+1, err)
+		} else if len(paths) == 0 {
+			slog.Warn("context file pattern matches no files", "index", i+1, "path", file.Path)
 		}
 
 		if file.Description == "" {
@@ -81,7 +114,8 @@ func (c *Config) validate() error {
 
 	contextDirs := make([]string, len(c.Context.Files))
 	for i, file := range c.Context.Files {
-		contextDirs[i] = filepath.Dir(c.resolveFilePath(file.Path))
+		dir, _ := glob.Split(filepath.ToSlash(file.Path))
+		contextDirs[i] = c.resolveFilePath(filepath.FromSlash(dir))
 	}
 	slog.Debug("Validated config", "config", c, "num_context_files", len(c.Context.Files), "context_dirs", contextDirs)
 
@@ -155,9 +189,141 @@ func (c *Config) validate() error {
 		}
 	}
 
+	// Example selection validation
+	switch types.ExampleSelection(c.Settings.ExampleSelection) {
+	case "", types.ExampleSelectionLLM, types.ExampleSelectionLocal, types.ExampleSelectionHybrid:
+		// Valid mode
+	default:
+		return fmt.Errorf("invalid example_selection %q. Must be one of: llm, local, hybrid", c.Settings.ExampleSelection)
+	}
+
+	if c.Settings.ExamplesPerPrompt < 0 {
+		return fmt.Errorf("examples_per_prompt cannot be negative")
+	}
+
+	// Go settings validation
+	if c.Settings.Go.Timeout != "" {
+		if _, err := time.ParseDuration(c.Settings.Go.Timeout); err != nil {
+			return fmt.Errorf("invalid go timeout %q: %w", c.Settings.Go.Timeout, err)
+		}
+	}
+	for i, tag := range c.Settings.Go.Tags {
+		if tag == "" || strings.ContainsAny(tag, " ,") {
+			return fmt.Errorf("go build tag at index %d must be a single non-empty tag", i)
+		}
+	}
+
+	// Pricing validation
+	for model, price := range c.Pricing {
+		if price.Input < 0 || price.Output < 0 || price.CacheWrite < 0 || price.CacheRead < 0 {
+			return fmt.Errorf("pricing for model %s cannot be negative", model)
+		}
+	}
+
+	// Mutation testing validation
+	mutation := c.Settings.Mutation
+	if mutation.Threshold < 0 || mutation.Threshold > 1 {
+		return fmt.Errorf("mutation threshold must be between 0 and 1, got %v", mutation.Threshold)
+	}
+	if mutation.MaxMutants < 0 || mutation.MaxAttempts < 0 {
+		return fmt.Errorf("mutation max_mutants and max_attempts cannot be negative")
+	}
+	if mutation.Timeout != "" {
+		if _, err := time.ParseDuration(mutation.Timeout); err != nil {
+			return fmt.Errorf("invalid mutation timeout %q: %w", mutation.Timeout, err)
+		}
+	}
+
+	// Flakiness validation
+	if c.Settings.Flakiness.Runs < 0 || c.Settings.Flakiness.MaxAttempts < 0 {
+		return fmt.Errorf("flakiness runs and max_attempts cannot be negative")
+	}
+
+	// Sandbox validation
+	sandbox := c.Settings.Sandbox
+	switch sandbox.Mode {
+	case "", "auto", "bwrap", "namespaces", "copy":
+	default:
+		return fmt.Errorf("invalid sandbox mode %q: must be auto, bwrap, namespaces or copy", sandbox.Mode)
+	}
+	if sandbox.MemoryMB < 0 || sandbox.MaxProcesses < 0 {
+		return fmt.Errorf("sandbox memory_mb and max_processes cannot be negative")
+	}
+	if sandbox.CPUTime != "" {
+		if _, err := time.ParseDuration(sandbox.CPUTime); err != nil {
+			return fmt.Errorf("invalid sandbox cpu_time %q: %w", sandbox.CPUTime, err)
+		}
+	}
+	if sandbox.Timeout != "" {
+		if _, err := time.ParseDuration(sandbox.Timeout); err != nil {
+			return fmt.Errorf("invalid sandbox timeout %q: %w", sandbox.Timeout, err)
+		}
+	}
+
+	// Budget validation
+	if c.Budget.MaxTokens < 0 || c.Budget.MaxCost < 0 || c.Budget.MaxCallsPerFunction < 0 {
+		return fmt.Errorf("budget limits cannot be negative")
+	}
+	if c.Budget.MaxDuration != "" {
+		if _, err := time.ParseDuration(c.Budget.MaxDuration); err != nil {
+			return fmt.Errorf("invalid budget max_duration %q: %w", c.Budget.MaxDuration, err)
+		}
+	}
+
+	// AI settings validation
+	if err := ValidateAI(c.AI); err != nil {
+		return err
+	}
+
+	// Prompt template overrides validation
+	for op, path := range c.Prompts.Templates {
+		if err := c.validatePromptTemplate(op, path); err != nil {
+			return err
+		}
+	}
+	for exampleType, overrides := range c.Prompts.ByExampleType {
+		if !isValidTestType(exampleType) {
+			return fmt.Errorf("prompt overrides: invalid test type %q. Must be one of: unit, integration, worker, prompt", exampleType)
+		}
+		for op, path := range overrides {
+			if err := c.validatePromptTemplate(op, path); err != nil {
+				return fmt.Errorf("%s: %w", exampleType, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePromptTemplate checks that a prompt override names a known operation and an existing file
+func (c *Config) validatePromptTemplate(op, path string) error {
+	if !prompts.IsValidOperation(op) {
+		var ops []string
+		for _, o := range prompts.Operations {
+			ops = append(ops, string(o))
+		}
+		return fmt.Errorf("unknown prompt %q. Must be one of: %s", op, strings.Join(ops, ", "))
+	}
+	if path == "" {
+		return fmt.Errorf("prompt %s: template path is required", op)
+	}
+
+	fullPath := c.resolveFilePath(path)
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		return fmt.Errorf("prompt %s: template not found at path: %s", op, fullPath)
+	}
 	return nil
 }
 
+// isValidTestType checks if the given test type is one of the allowed values
+func isValidTestType(testType string) bool {
+	switch types.TestType(testType) {
+	case types.TestTypeUnit, types.TestTypeIntegration, types.TestTypeWorker, types.TestTypePrompt:
+		return true
+	}
+	return false
+}
+
 // IsValidLanguage checks if the given language is supported
 func isValidLanguage(lang string) bool {
 	slog.Debug("checking language validity", "language", lang, "supported_languages", languageRunnerMap)
@@ -0,0 +1,53 @@
+package finder
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// IsExcludedDir reports whether dir is one of the configured excluded directories, or
+// inside one
+func (f *FileFinder) IsExcludedDir(cfg types.IConfig, dir string) bool {
+	for _, excludeDir := range cfg.GetExcludedDirs() {
+		// Use filepath.Clean to normalize paths for comparison
+		cleanPath := filepath.Clean(dir)
+		cleanExcludeDir := filepath.Clean(excludeDir)
+
+		// Check if the current path matches the excluded directory exactly
+		// or if it's a subdirectory of the excluded directory
+		rel, err := filepath.Rel(cleanExcludeDir, cleanPath)
+		if err == nil && (rel == "." || !strings.HasPrefix(rel, "..")) {
+			slog.Debug("skipping excluded directory", "path", dir, "excludeDir", excludeDir)
+			return true
+		}
+	}
+	return false
+}
+
+// IsSourceFile reports whether path is a source file tests can be generated for: it has
+// the language's extension, isn't a test, and isn't excluded by name or directory
+func (f *FileFinder) IsSourceFile(cfg types.IConfig, path string) bool {
+	if !strings.HasSuffix(path, f.language.GetFileExtension()) {
+		slog.Debug("skipping non-target file", "path", path, "extension", filepath.Ext(path))
+		return false
+	}
+
+	// Skip test files
+	if strings.HasSuffix(path, f.language.GetTestFilePattern()) {
+		slog.Debug("skipping test file", "path", path)
+		return false
+	}
+
+	// Skip if file name matches any excluded file pattern
+	for _, excludedFile := range cfg.GetExcludedFiles() {
+		if strings.HasSuffix(filepath.Base(path), excludedFile) {
+			slog.Debug("skipping excluded file", "path", path)
+			return false
+		}
+	}
+
+	return !f.IsExcludedDir(cfg, filepath.Dir(path))
+}
@@ -0,0 +1,53 @@
+package finder
+
+import (
+	"testing"
+
+	"github.com/gwkline/artestian/pkg/golang"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type filterConfig struct {
+	types.IConfig
+}
+
+func (c *filterConfig) GetExcludedDirs() []string {
+	return []string{"/project/vendor", "/project/internal/gen"}
+}
+
+func (c *filterConfig) GetExcludedFiles() []string {
+	return []string{"_mock.go"}
+}
+
+func TestIsSourceFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{name: "source file", path: "/project/pkg/math.go", expected: true},
+		{name: "other extension", path: "/project/pkg/README.md", expected: false},
+		{name: "test file", path: "/project/pkg/math_test.go", expected: false},
+		{name: "excluded file", path: "/project/pkg/client_mock.go", expected: false},
+		{name: "excluded directory", path: "/project/vendor/lib.go", expected: false},
+		{name: "inside excluded directory", path: "/project/internal/gen/proto/api.go", expected: false},
+		{name: "similarly named directory", path: "/project/vendored/lib.go", expected: true},
+	}
+
+	f := NewFileFinder(golang.NewGoSupport())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, f.IsSourceFile(&filterConfig{}, tt.path))
+		})
+	}
+}
+
+func TestIsExcludedDir(t *testing.T) {
+	f := NewFileFinder(golang.NewGoSupport())
+	cfg := &filterConfig{}
+
+	assert.True(t, f.IsExcludedDir(cfg, "/project/vendor"))
+	assert.True(t, f.IsExcludedDir(cfg, "/project/internal/gen/proto"))
+	assert.False(t, f.IsExcludedDir(cfg, "/project/internal"))
+}
@@ -5,7 +5,6 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/gwkline/artestian/types"
 )
@@ -33,30 +32,13 @@ func (f *FileFinder) FindNextFile(cfg types.IConfig) (string, error) {
 		// Skip directories and non-typescript files
 		if info.IsDir() {
 			// Skip excluded directories
-			for _, excludeDir := range excludeDirs {
-				// Use filepath.Clean to normalize paths for comparison
-				cleanPath := filepath.Clean(path)
-				cleanExcludeDir := filepath.Clean(excludeDir)
-
-				// Check if the current path matches the excluded directory exactly
-				// or if it's a subdirectory of the excluded directory
-				rel, err := filepath.Rel(cleanExcludeDir, cleanPath)
-				if err == nil && (rel == "." || !strings.HasPrefix(rel, "..")) {
-					slog.Debug("skipping excluded directory", "path", path, "excludeDir", excludeDir)
-					return filepath.SkipDir
-				}
+			if f.IsExcludedDir(cfg, path) {
+				return filepath.SkipDir
 			}
 			slog.Debug("skipping directory", "path", path)
 			return nil
 		}
-		if !strings.HasSuffix(path, f.language.GetFileExtension()) {
-			slog.Debug("skipping non-target file", "path", path, "extension", filepath.Ext(path))
-			return nil
-		}
-
-		// Skip test files
-		if strings.HasSuffix(path, f.language.GetTestFilePattern()) {
-			slog.Debug("skipping test file", "path", path)
+		if !f.IsSourceFile(cfg, path) {
 			return nil
 		}
 
@@ -67,14 +49,6 @@ func (f *FileFinder) FindNextFile(cfg types.IConfig) (string, error) {
 			return nil
 		}
 
-		// Skip if file name matches any excluded file pattern
-		for _, excludedFile := range exludedFileNames {
-			if strings.HasSuffix(filepath.Base(path), excludedFile) {
-				slog.Debug("skipping excluded file", "path", path)
-				return nil
-			}
-		}
-
 		// Add eligible file to the list
 		eligibleFiles = append(eligibleFiles, path)
 		return nil
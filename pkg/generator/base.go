@@ -1,6 +1,8 @@
 package generator
 
 import (
+	"github.com/gwkline/artestian/pkg/output"
+	"github.com/gwkline/artestian/pkg/workspace"
 	"github.com/gwkline/artestian/types"
 )
 
@@ -10,6 +12,15 @@ type TestGenerator struct {
 	language     types.ILanguage
 	examples     []types.TestExample
 	contextFiles []types.ContextFile
+	usage        types.IUsageTracker
+	sink         types.IOutputSink // Where final test files go, the project by default
+
+	workspace       *workspace.Workspace // Nil to generate in the project itself
+	keepFailed      bool
+	discoverContext bool
+	mutation        *MutationOptions  // Nil unless mutation testing is enabled
+	flakiness       *FlakinessOptions // Nil unless passing tests are re-run
+	reviewer        types.IReviewer   // Nil to keep every passing test
 }
 
 func NewTestGenerator(
@@ -18,6 +29,7 @@ func NewTestGenerator(
 	language types.ILanguage,
 	examples []types.TestExample,
 	contextFiles []types.ContextFile,
+	usage types.IUsageTracker,
 ) *TestGenerator {
 	return &TestGenerator{
 		finder:       finder,
@@ -25,5 +37,29 @@ func NewTestGenerator(
 		language:     language,
 		examples:     examples,
 		contextFiles: contextFiles,
+		usage:        usage,
+		sink:         output.NewFileSink(),
 	}
 }
+
+// SetContextDiscovery enables adding the declarations each function references to its prompt
+func (g *TestGenerator) SetContextDiscovery(enabled bool) {
+	g.discoverContext = enabled
+}
+
+// SetWorkspace generates and repairs tests in a scratch copy of the project, so only the
+// final test file of each source file is written to the project
+func (g *TestGenerator) SetWorkspace(w *workspace.Workspace) {
+	g.workspace = w
+}
+
+// SetKeepFailed keeps tests that couldn't be fixed next to the workspace, so they can be
+// looked at after the run
+func (g *TestGenerator) SetKeepFailed(keep bool) {
+	g.keepFailed = keep
+}
+
+// SetOutputSink sends final test files to sink instead of writing them to the project
+func (g *TestGenerator) SetOutputSink(sink types.IOutputSink) {
+	g.sink = sink
+}
@@ -1,20 +1,128 @@
 package generator
 
 import (
+	"context"
 	"log/slog"
+	"path/filepath"
+	"sort"
 
+	"github.com/gwkline/artestian/pkg/glob"
+	"github.com/gwkline/artestian/pkg/ranker"
 	"github.com/gwkline/artestian/types"
 )
 
-func (g *TestGenerator) findBestExample(sourceCode string) types.TestExample {
-	if len(g.examples) == 0 {
+// In hybrid mode the model only chooses between the top candidates, and only when
+// the local ranking is close
+const (
+	hybridCandidates = 3
+	hybridMargin     = 0.1
+)
+
+// SelectExamples returns up to count examples for a source file, most relevant first.
+// Examples pinned to the file with applies_to come first, highest priority first. Any
+// remaining slots are filled by ranking the other examples, with the first of them chosen
+// according to selection.
+func (g *TestGenerator) SelectExamples(ctx context.Context, selection types.ExampleSelection, count int, relPath, sourceCode string) []types.TestExample {
+	if len(g.examples) == 0 || count <= 0 {
+		return nil
+	}
+
+	pinned, rest := pinnedExamples(g.examples, filepath.ToSlash(relPath))
+	if len(pinned) > 0 {
+		slog.Info("using pinned test examples", "path", relPath, "count", len(pinned), "first", pinned[0].Name)
+	}
+	if len(pinned) >= count || len(rest) == 0 {
+		return pinned[:min(count, len(pinned))]
+	}
+
+	selected := pinned
+	if len(pinned) == 0 {
+		best := g.findBestExample(ctx, selection, rest, sourceCode)
+		selected = append(selected, best)
+	}
+
+	for _, score := range ranker.Rank(sourceCode, rest) {
+		if len(selected) >= count {
+			break
+		}
+		if !containsExample(selected, score.Example.Name) {
+			selected = append(selected, score.Example)
+		}
+	}
+
+	return selected
+}
+
+// pinnedExamples splits examples into those whose applies_to globs match the path, highest
+// priority first, and the rest in configured order
+func pinnedExamples(examples []types.TestExample, relPath string) ([]types.TestExample, []types.TestExample) {
+	var pinned, rest []types.TestExample
+	for _, example := range examples {
+		if appliesTo(example, relPath) {
+			pinned = append(pinned, example)
+		} else {
+			rest = append(rest, example)
+		}
+	}
+
+	sort.SliceStable(pinned, func(i, j int) bool {
+		return pinned[i].Priority > pinned[j].Priority
+	})
+	return pinned, rest
+}
+
+func appliesTo(example types.TestExample, relPath string) bool {
+	for _, pattern := range example.AppliesTo {
+		if glob.Match(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsExample(examples []types.TestExample, name string) bool {
+	for _, example := range examples {
+		if example.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findBestExample picks the single most relevant example among candidates
+func (g *TestGenerator) findBestExample(ctx context.Context, selection types.ExampleSelection, examples []types.TestExample, sourceCode string) types.TestExample {
+	if len(examples) == 0 {
 		return types.TestExample{}
 	}
 
-	example, err := g.ai.PickExample(sourceCode, g.examples)
+	scores := ranker.Rank(sourceCode, examples)
+	local := scores[0].Example
+
+	candidates := examples
+	switch selection {
+	case types.ExampleSelectionLocal:
+		slog.Info("selected test example", "name", local.Name, "type", local.Type, "score", scores[0].Score)
+		return local
+
+	case types.ExampleSelectionHybrid:
+		if len(scores) == 1 || scores[0].Score-scores[1].Score >= hybridMargin {
+			slog.Info("selected test example", "name", local.Name, "type", local.Type, "score", scores[0].Score)
+			return local
+		}
+
+		candidates = nil
+		for _, score := range scores[:min(hybridCandidates, len(scores))] {
+			candidates = append(candidates, score.Example)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, AgentTimeout)
+	defer cancel()
+
+	example, err := g.ai.PickExample(ctx, sourceCode, candidates)
 	if err != nil {
-		slog.Error("failed to pick example", "error", err)
-		return g.examples[0]
+		slog.Warn("failed to pick example, using the best local match", "name", local.Name, "error", err)
+		return local
 	}
 
 	return example
@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gwkline/artestian/pkg/agent/agenttest"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindBestExample(t *testing.T) {
+	examples := []types.TestExample{
+		{Name: "pure function", SourceCode: "func TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fail()\n\t}\n}"},
+		{Name: "repository", SourceCode: "import \"database/sql\"\n\nfunc TestFindUser(t *testing.T) {\n\tdb, _ := sql.Open(\"sqlite\", \":memory:\")\n\tNewRepository(db).FindUser(1)\n}"},
+	}
+	sourceCode := "import \"database/sql\"\n\nfunc (r *Repository) FindUser(id int) error {\n\treturn r.db.QueryRow(\"SELECT 1\").Err()\n}"
+
+	tests := []struct {
+		name          string
+		selection     types.ExampleSelection
+		examples      []types.TestExample
+		agentErrors   []error
+		expected      string
+		expectedCalls int
+	}{
+		{
+			name:          "local ranking makes no model call",
+			selection:     types.ExampleSelectionLocal,
+			examples:      examples,
+			expected:      "repository",
+			expectedCalls: 0,
+		},
+		{
+			name:          "hybrid with a clear winner makes no model call",
+			selection:     types.ExampleSelectionHybrid,
+			examples:      examples,
+			expected:      "repository",
+			expectedCalls: 0,
+		},
+		{
+			name:          "hybrid asks the model between close candidates",
+			selection:     types.ExampleSelectionHybrid,
+			examples:      []types.TestExample{{Name: "first", SourceCode: "func TestA() {}"}, {Name: "second", SourceCode: "func TestA() {}"}},
+			expected:      "first",
+			expectedCalls: 1,
+		},
+		{
+			name:          "llm asks the model",
+			selection:     types.ExampleSelectionLLM,
+			examples:      examples,
+			expected:      "pure function",
+			expectedCalls: 1,
+		},
+		{
+			name:          "llm failure falls back to the best local match",
+			selection:     types.ExampleSelectionLLM,
+			examples:      examples,
+			agentErrors:   []error{errors.New("model unavailable")},
+			expected:      "repository",
+			expectedCalls: 1,
+		},
+		{
+			name:      "no examples",
+			selection: types.ExampleSelectionLLM,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &agenttest.Agent{Errors: tt.agentErrors}
+			g := NewTestGenerator(nil, fake, nil, tt.examples, nil, nil)
+
+			example := g.findBestExample(context.Background(), tt.selection, tt.examples, sourceCode)
+
+			assert.Equal(t, tt.expected, example.Name)
+			assert.Equal(t, tt.expectedCalls, fake.CallCount())
+		})
+	}
+}
+
+func TestSelectExamples(t *testing.T) {
+	examples := []types.TestExample{
+		{Name: "pure function", SourceCode: "func TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fail()\n\t}\n}"},
+		{Name: "repository", SourceCode: "import \"database/sql\"\n\nfunc TestFindUser(t *testing.T) {\n\tdb, _ := sql.Open(\"sqlite\", \":memory:\")\n\tNewRepository(db).FindUser(1)\n}"},
+		{Name: "worker", SourceCode: "func TestProcess(t *testing.T) {\n\tgo Process(jobs)\n}", AppliesTo: []string{"internal/workers/**"}},
+		{Name: "email worker", SourceCode: "func TestSend(t *testing.T) {}", AppliesTo: []string{"internal/workers/email/*.go"}, Priority: 10},
+	}
+	sourceCode := "import \"database/sql\"\n\nfunc (r *Repository) FindUser(id int) error {\n\treturn r.db.QueryRow(\"SELECT 1\").Err()\n}"
+
+	tests := []struct {
+		name          string
+		relPath       string
+		count         int
+		expected      []string
+		expectedCalls int
+	}{
+		{
+			name:          "ranked examples after the model's pick",
+			relPath:       "internal/store/user.go",
+			count:         2,
+			expected:      []string{"pure function", "repository"},
+			expectedCalls: 1,
+		},
+		{
+			name:     "pinned example skips the model",
+			relPath:  "internal/workers/queue.go",
+			count:    2,
+			expected: []string{"worker", "repository"},
+		},
+		{
+			name:     "pinned examples by priority",
+			relPath:  "internal/workers/email/send.go",
+			count:    1,
+			expected: []string{"email worker"},
+		},
+		{
+			name:     "count above the number of examples",
+			relPath:  "internal/workers/email/send.go",
+			count:    10,
+			expected: []string{"email worker", "worker", "repository", "pure function"},
+		},
+		{
+			name:    "no examples requested",
+			relPath: "internal/store/user.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &agenttest.Agent{}
+			g := NewTestGenerator(nil, fake, nil, examples, nil, nil)
+
+			selected := g.SelectExamples(context.Background(), types.ExampleSelectionLLM, tt.count, tt.relPath, sourceCode)
+
+			var names []string
+			for _, example := range selected {
+				names = append(names, example.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+			assert.Equal(t, tt.expectedCalls, fake.CallCount())
+		})
+	}
+}
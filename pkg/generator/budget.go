@@ -0,0 +1,9 @@
+package generator
+
+// checkBudget returns an error if the run or the current function may not make another agent call
+func (g *TestGenerator) checkBudget() error {
+	if err := g.usage.CheckBudget(); err != nil {
+		return err
+	}
+	return g.usage.CheckFunctionBudget()
+}
@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// contextFilesFor returns the context files for a function: the declarations it references
+// from other files when discovery is enabled, followed by the configured context files.
+// Discovered files already covered by a configured file are left out.
+func (g *TestGenerator) contextFilesFor(ctx context.Context, sourcePath string, function types.Function) []types.ContextFile {
+	configured := g.selectContextFiles(function)
+	if !g.discoverContext {
+		return configured
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, TypeCheckTimeout)
+	defer cancel()
+
+	discovered, err := g.language.ResolveContext(ctx, sourcePath, function)
+	if err != nil {
+		slog.Warn("failed to discover context declarations", "function", function.Name, "error", err)
+		return configured
+	}
+
+	var files []types.ContextFile
+	for _, file := range discovered {
+		if !coveredBy(file, configured) {
+			files = append(files, file)
+		}
+	}
+	if len(files) > 0 {
+		slog.Debug("discovered context declarations", "function", function.Name, "files", len(files))
+	}
+	return append(files, configured...)
+}
+
+// coveredBy reports whether every declaration in a discovered file already appears in
+// one of the configured files
+func coveredBy(file types.ContextFile, configured []types.ContextFile) bool {
+	for _, other := range configured {
+		covered := true
+		for _, block := range strings.Split(file.Content, "\n\n") {
+			if strings.HasPrefix(block, "package ") {
+				continue
+			}
+			if !strings.Contains(other.Content, strings.TrimSpace(block)) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// selectContextFiles orders the configured context files by how many of the
+// function's references they mention. Files that mention none are dropped,
+// unless no file matches at all, in which case every file is kept.
+func (g *TestGenerator) selectContextFiles(function types.Function) []types.ContextFile {
+	if len(function.References) == 0 || len(g.contextFiles) == 0 {
+		return g.contextFiles
+	}
+
+	type scoredFile struct {
+		file  types.ContextFile
+		score int
+	}
+
+	// Match the bare identifier, so "types.Config" matches a file declaring Config
+	patterns := make([]*regexp.Regexp, len(function.References))
+	for i, ref := range function.References {
+		name := ref[strings.LastIndex(ref, ".")+1:]
+		patterns[i] = regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+	}
+
+	var scored []scoredFile
+	for _, file := range g.contextFiles {
+		score := 0
+		for _, pattern := range patterns {
+			if pattern.MatchString(file.Content) {
+				score++
+			}
+		}
+		if score > 0 {
+			scored = append(scored, scoredFile{file: file, score: score})
+		}
+	}
+
+	if len(scored) == 0 {
+		return g.contextFiles
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	files := make([]types.ContextFile, len(scored))
+	for i, s := range scored {
+		files[i] = s.file
+	}
+	return files
+}
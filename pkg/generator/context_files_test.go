@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// contextLanguage resolves a fixed set of context files
+type contextLanguage struct {
+	types.ILanguage
+	files []types.ContextFile
+	err   error
+}
+
+func (l *contextLanguage) ResolveContext(ctx context.Context, sourcePath string, function types.Function) ([]types.ContextFile, error) {
+	return l.files, l.err
+}
+
+func TestContextFilesFor(t *testing.T) {
+	configured := types.ContextFile{Path: "types.go", Content: "package types\n\ntype User struct {\n\tName string\n}\n\ntype Role string"}
+	user := types.ContextFile{Path: "example.com/app/types/user.go", Content: "package types\n\ntype User struct {\n\tName string\n}"}
+	order := types.ContextFile{Path: "example.com/app/orders/order.go", Content: "package orders\n\ntype Order struct{}"}
+
+	tests := []struct {
+		name     string
+		discover bool
+		files    []types.ContextFile
+		err      error
+		expected []string
+	}{
+		{
+			name:     "discovery disabled",
+			discover: false,
+			files:    []types.ContextFile{order},
+			expected: []string{"types.go"},
+		},
+		{
+			name:     "discovered files come first",
+			discover: true,
+			files:    []types.ContextFile{order},
+			expected: []string{"example.com/app/orders/order.go", "types.go"},
+		},
+		{
+			name:     "files covered by configured files are dropped",
+			discover: true,
+			files:    []types.ContextFile{user, order},
+			expected: []string{"example.com/app/orders/order.go", "types.go"},
+		},
+		{
+			name:     "errors fall back to configured files",
+			discover: true,
+			err:      errors.New("no packages found"),
+			expected: []string{"types.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			language := &contextLanguage{files: tt.files, err: tt.err}
+			g := NewTestGenerator(nil, nil, language, nil, []types.ContextFile{configured}, nil)
+			g.SetContextDiscovery(tt.discover)
+
+			var paths []string
+			for _, file := range g.contextFilesFor(context.Background(), "orders/service.go", types.Function{Name: "Place"}) {
+				paths = append(paths, file.Path)
+			}
+			assert.Equal(t, tt.expected, paths)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// maxPanicLines limits how much of a panic stack trace is sent back to the model
+const maxPanicLines = 20
+
+// relevantFailures extracts the failures from a run that should be sent back to
+// the model. Diagnostics in other files are dropped when the test file has its own,
+// and the raw output is used only when nothing structured could be parsed.
+func relevantFailures(result types.RunResult, testPath string) []string {
+	var failures []string
+
+	diagnostics := result.Diagnostics
+	var inTestFile []types.Diagnostic
+	for _, d := range diagnostics {
+		if filepath.Base(d.File) == filepath.Base(testPath) {
+			inTestFile = append(inTestFile, d)
+		}
+	}
+	if len(inTestFile) > 0 {
+		diagnostics = inTestFile
+	}
+	for _, d := range diagnostics {
+		failures = append(failures, formatDiagnostic(d))
+	}
+
+	for _, tc := range result.Tests {
+		if tc.Status != types.TestStatusFail {
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("FAIL: %s\n%s", tc.Name, strings.Join(tc.Failures, "\n")))
+	}
+
+	if result.Panic != "" {
+		lines := strings.Split(result.Panic, "\n")
+		if len(lines) > maxPanicLines {
+			lines = append(lines[:maxPanicLines], "...")
+		}
+		failures = append(failures, strings.Join(lines, "\n"))
+	}
+
+	if result.TimedOut {
+		failures = append(failures, "the test run timed out")
+	}
+
+	if len(failures) == 0 {
+		return strings.Split(result.Output, "\n")
+	}
+	return failures
+}
+
+func formatDiagnostic(d types.Diagnostic) string {
+	location := d.File
+	if d.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, d.Line)
+		if d.Column > 0 {
+			location = fmt.Sprintf("%s:%d", location, d.Column)
+		}
+	}
+	message := d.Message
+	if d.Code != "" {
+		message = d.Code + ": " + message
+	}
+	// Global diagnostics, e.g. from the compiler config, have no location
+	if location == "" {
+		return message
+	}
+	return location + ": " + message
+}
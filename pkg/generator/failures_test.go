@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelevantFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   types.RunResult
+		expected []string
+	}{
+		{
+			name: "keeps only diagnostics in the test file",
+			result: types.RunResult{
+				Diagnostics: []types.Diagnostic{
+					{Location: types.Location{File: "./other.go", Line: 1, Column: 2}, Message: "unrelated"},
+					{Location: types.Location{File: "./add_test.go", Line: 5, Column: 3}, Message: "undefined: sub"},
+				},
+			},
+			expected: []string{"./add_test.go:5:3: undefined: sub"},
+		},
+		{
+			name: "global diagnostics without a location",
+			result: types.RunResult{
+				Diagnostics: []types.Diagnostic{
+					{Code: "TS5083", Message: "Cannot read file 'tsconfig.base.json'."},
+				},
+			},
+			expected: []string{"TS5083: Cannot read file 'tsconfig.base.json'."},
+		},
+		{
+			name: "failing tests, panics and timeouts",
+			result: types.RunResult{
+				Tests: []types.TestCase{
+					{Name: "TestAdd", Status: types.TestStatusPass},
+					{Name: "TestSub", Status: types.TestStatusFail, Failures: []string{"add_test.go:9: got 1, want 2"}},
+				},
+				Panic:    "panic: boom",
+				TimedOut: true,
+			},
+			expected: []string{
+				"FAIL: TestSub\nadd_test.go:9: got 1, want 2",
+				"panic: boom",
+				"the test run timed out",
+			},
+		},
+		{
+			name:     "falls back to raw output",
+			result:   types.RunResult{Output: "line one\nline two"},
+			expected: []string{"line one", "line two"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, relevantFailures(tt.result, "/src/add_test.go"))
+		})
+	}
+}
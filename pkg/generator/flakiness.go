@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// FlakinessOptions configures re-running passing tests to catch flaky ones
+type FlakinessOptions struct {
+	types.RerunOptions
+	MaxAttempts int // Times the model is asked to fix a flaky test
+}
+
+// EnableFlakinessCheck re-runs every passing test, and treats a test that doesn't pass
+// on every run as failing
+func (g *TestGenerator) EnableFlakinessCheck(options FlakinessOptions) {
+	g.flakiness = &options
+}
+
+// stabilizeTest re-runs a passing test and, while any run fails, sends the failures back
+// to the model to make the test deterministic
+func (g *TestGenerator) stabilizeTest(ctx context.Context, params types.GenerateTestParams, testCode, projectDir string) (string, error) {
+	if g.flakiness == nil {
+		return testCode, nil
+	}
+
+	runner := g.language.GetTestRunner()
+	for attempt := 0; ; attempt++ {
+		slog.Debug("re-running tests", "runs", g.flakiness.Runs, "shuffle", g.flakiness.Shuffle, "race", g.flakiness.Race, "path", params.TestPath)
+		stepCtx, cancel := context.WithTimeout(ctx, TestRunTimeout)
+		result, err := runner.RerunTests(stepCtx, projectDir, params.TestPath, g.flakiness.RerunOptions)
+		cancel()
+		if err != nil {
+			return "", fmt.Errorf("error re-running tests: %w", err)
+		}
+		if result.Passed {
+			slog.Info("tests passed on every re-run", "runs", g.flakiness.Runs)
+			return testCode, nil
+		}
+
+		failures := flakyFailures(result, params.TestPath)
+		slog.Warn("test is flaky", "function", params.Function.Name, "attempt", attempt+1)
+		slog.Debug("flaky test failures", "failures", failures)
+		if attempt >= g.flakiness.MaxAttempts {
+			return "", fmt.Errorf("test is still flaky after %d attempts", attempt)
+		}
+
+		if err := g.checkBudget(); err != nil {
+			return "", err
+		}
+
+		slog.Info("fixing flaky test", "attempt", attempt+1)
+		agentCtx, cancel := context.WithTimeout(ctx, AgentTimeout)
+		fixedCode, err := g.ai.FixTestFailures(agentCtx, types.IterateTestParams{
+			GenerateTestParams: params,
+			TestCode:           testCode,
+			Errors:             failures,
+		})
+		cancel()
+		if err != nil {
+			return "", fmt.Errorf("error fixing flaky test: %w", err)
+		}
+
+		if err := os.WriteFile(params.TestPath, []byte(fixedCode), 0644); err != nil {
+			return "", fmt.Errorf("error writing fixed test file: %w", err)
+		}
+		// The fixed test must pass once before it's re-run
+		testCode, err = g.iterateTestFailures(ctx, params, fixedCode, projectDir)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// flakyFailures describes the failed re-runs of a test that passed once. Each failing
+// test is reported once, with how many of its runs failed.
+func flakyFailures(result types.RunResult, testPath string) []string {
+	runs := make(map[string]int)
+	failed := make(map[string]int)
+	var firstFailures []types.TestCase
+	for _, tc := range result.Tests {
+		runs[tc.Name]++
+		if tc.Status != types.TestStatusFail {
+			continue
+		}
+		failed[tc.Name]++
+		if failed[tc.Name] == 1 {
+			firstFailures = append(firstFailures, tc)
+		}
+	}
+
+	messages := []string{"the test passed once but failed when re-run, so it depends on something that changes between runs, such as map or test order, time, randomness or goroutine scheduling; make it deterministic"}
+	for _, tc := range firstFailures {
+		messages = append(messages, fmt.Sprintf("%s failed in %d of %d runs", tc.Name, failed[tc.Name], runs[tc.Name]))
+	}
+
+	collapsed := result
+	collapsed.Tests = firstFailures
+	return append(messages, relevantFailures(collapsed, testPath)...)
+}
@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gwkline/artestian/pkg/usage"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rerunLanguage passes every single run and returns queued results for re-runs
+type rerunLanguage struct {
+	types.ILanguage
+	reruns []types.RunResult
+}
+
+func (l *rerunLanguage) GetTestRunner() types.ITestRunner {
+	return l
+}
+
+func (l *rerunLanguage) GetName() string {
+	return "fake"
+}
+
+func (l *rerunLanguage) RunTests(ctx context.Context, rootDir, testFilePath string) (types.RunResult, error) {
+	return types.RunResult{Passed: true}, nil
+}
+
+func (l *rerunLanguage) RerunTests(ctx context.Context, rootDir, testFilePath string, options types.RerunOptions) (types.RunResult, error) {
+	result := l.reruns[0]
+	l.reruns = l.reruns[1:]
+	return result, nil
+}
+
+// fixingAgent records the errors it's asked to fix
+type fixingAgent struct {
+	types.IAgent
+	errors [][]string
+}
+
+func (a *fixingAgent) FixTestFailures(ctx context.Context, params types.IterateTestParams) (string, error) {
+	a.errors = append(a.errors, params.Errors)
+	return "fixed", nil
+}
+
+func TestStabilizeTest(t *testing.T) {
+	flaky := types.RunResult{
+		Tests: []types.TestCase{
+			{Name: "TestSort", Status: types.TestStatusPass},
+			{Name: "TestSort", Status: types.TestStatusFail, Failures: []string{"sort_test.go:9: got [b a]"}},
+			{Name: "TestSort", Status: types.TestStatusFail, Failures: []string{"sort_test.go:9: got [b a]"}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		reruns      []types.RunResult
+		expected    string
+		expectedErr string
+		fixes       int
+	}{
+		{
+			name:     "stable test is kept",
+			reruns:   []types.RunResult{{Passed: true}},
+			expected: "original",
+		},
+		{
+			name:     "flaky test is fixed",
+			reruns:   []types.RunResult{flaky, {Passed: true}},
+			expected: "fixed",
+			fixes:    1,
+		},
+		{
+			name:        "test that stays flaky fails",
+			reruns:      []types.RunResult{flaky, flaky, flaky},
+			expectedErr: "test is still flaky after 2 attempts",
+			fixes:       2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testPath := filepath.Join(t.TempDir(), "sort_test.go")
+			require.NoError(t, os.WriteFile(testPath, []byte("original"), 0644))
+
+			ai := &fixingAgent{}
+			g := NewTestGenerator(nil, ai, &rerunLanguage{reruns: tt.reruns}, nil, nil, usage.NewTracker(nil, usage.Limits{}))
+			g.EnableFlakinessCheck(FlakinessOptions{RerunOptions: types.RerunOptions{Runs: 3}, MaxAttempts: 2})
+
+			testCode, err := g.stabilizeTest(context.Background(), types.GenerateTestParams{TestPath: testPath}, "original", t.TempDir())
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, testCode)
+			}
+			assert.Len(t, ai.errors, tt.fixes)
+		})
+	}
+}
+
+func TestFlakyFailures(t *testing.T) {
+	result := types.RunResult{
+		Tests: []types.TestCase{
+			{Name: "TestSort", Status: types.TestStatusPass},
+			{Name: "TestClock", Status: types.TestStatusFail, Failures: []string{"clock_test.go:4: expected 10:00"}},
+			{Name: "TestSort", Status: types.TestStatusFail, Failures: []string{"sort_test.go:9: got [b a]"}},
+			{Name: "TestClock", Status: types.TestStatusFail, Failures: []string{"clock_test.go:4: expected 10:00"}},
+			{Name: "TestSort", Status: types.TestStatusFail, Failures: []string{"sort_test.go:9: got [c a]"}},
+		},
+	}
+
+	failures := flakyFailures(result, "sort_test.go")
+	require.Len(t, failures, 5)
+	assert.Contains(t, failures[0], "passed once but failed when re-run")
+	assert.Equal(t, []string{
+		"TestClock failed in 2 of 2 runs",
+		"TestSort failed in 2 of 3 runs",
+		"FAIL: TestClock\nclock_test.go:4: expected 10:00",
+		"FAIL: TestSort\nsort_test.go:9: got [b a]",
+	}, failures[1:])
+}
@@ -1,15 +1,30 @@
 package generator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/gwkline/artestian/pkg/output"
 	"github.com/gwkline/artestian/types"
 )
 
-func (g *TestGenerator) GenerateNextTest(projectDir string, cfg types.IConfig) error {
+// Per-step timeouts, so a hung API call, compiler or test run can't stall the whole run
+var (
+	AgentTimeout     = 5 * time.Minute
+	TypeCheckTimeout = 5 * time.Minute
+	TestRunTimeout   = 15 * time.Minute
+)
+
+// GenerateNextTest generates tests for every function in the next file that needs them.
+// When ctx is cancelled no new work is started, and tests that already pass are still written.
+func (g *TestGenerator) GenerateNextTest(ctx context.Context, projectDir string, cfg types.IConfig) error {
 	slog.Debug("finding next file that needs tests", "rootDir", cfg.GetRootDir())
 
 	sourcePath, err := g.finder.FindNextFile(cfg)
@@ -22,6 +37,42 @@ func (g *TestGenerator) GenerateNextTest(projectDir string, cfg types.IConfig) e
 		return fmt.Errorf("no files found needing tests")
 	}
 
+	return g.generateFile(ctx, projectDir, cfg, sourcePath, nil)
+}
+
+// GenerateFunctions generates tests for the named functions in sourcePath, given as Name
+// or Receiver.Name. When the file already has a test file the tests go to a new one
+// next to it, so existing tests are never overwritten.
+func (g *TestGenerator) GenerateFunctions(ctx context.Context, projectDir string, cfg types.IConfig, sourcePath string, names []string) error {
+	only := make(map[string]bool, len(names))
+	for _, name := range names {
+		only[name] = true
+	}
+	return g.generateFile(ctx, projectDir, cfg, sourcePath, only)
+}
+
+// generateFile generates tests for the functions in sourcePath, or only the ones in
+// only when it isn't nil
+func (g *TestGenerator) generateFile(ctx context.Context, projectDir string, cfg types.IConfig, sourcePath string, only map[string]bool) error {
+	// The test file is written to the project, everything else happens in the workspace
+	realSourcePath := sourcePath
+	finalTestPath := g.finder.GetTestPath(sourcePath)
+	if g.workspace != nil {
+		if err := g.workspace.Sync(); err != nil {
+			return err
+		}
+		workspacePath, err := g.workspace.Path(sourcePath)
+		if err != nil {
+			return fmt.Errorf("error finding source file in workspace: %w", err)
+		}
+		// The workspace may be a copy of the whole repository the project is in
+		workspaceDir, err := g.workspace.Path(projectDir)
+		if err != nil {
+			return fmt.Errorf("error finding project in workspace: %w", err)
+		}
+		sourcePath, projectDir = workspacePath, workspaceDir
+	}
+
 	sourceCode, err := os.ReadFile(sourcePath)
 	if err != nil {
 		slog.Error("failed to read source file", "path", sourcePath, "error", err)
@@ -39,78 +90,296 @@ func (g *TestGenerator) GenerateNextTest(projectDir string, cfg types.IConfig) e
 		return fmt.Errorf("no functions found in source file")
 	}
 
-	slog.Debug("finding best example for source code")
-	example := g.findBestExample(string(sourceCode))
+	if only != nil {
+		var selected []types.Function
+		for _, function := range functions {
+			if only[qualifiedName(function)] {
+				selected = append(selected, function)
+			}
+		}
+		if len(selected) == 0 {
+			slog.Info("functions are no longer in source file", "path", sourcePath)
+			return nil
+		}
+		functions = selected
+	}
 
-	var allTestCode string
+	// Tests for only some of the functions, e.g. while watching, go to a new file so the
+	// existing tests are kept. A normal run replaces a stale or partial test file.
+	if _, err := os.Stat(finalTestPath); err == nil && only != nil {
+		finalTestPath = additionalTestPath(finalTestPath, g.language.GetTestFilePattern(), functions[0])
+	}
+
+	relPath, err := filepath.Rel(projectDir, sourcePath)
+	if err != nil {
+		relPath = sourcePath
+	}
+	g.usage.SetTarget(relPath, "")
+
+	baselineCtx, cancel := context.WithTimeout(ctx, TypeCheckTimeout)
+	err = g.language.CaptureBaseline(baselineCtx, sourcePath)
+	cancel()
+	if err != nil {
+		slog.Warn("failed to capture type check baseline", "path", sourcePath, "error", err)
+	}
+
+	slog.Debug("selecting examples for source code")
+	examples := g.SelectExamples(ctx, cfg.GetExampleSelection(), cfg.GetExamplesPerPrompt(), relPath, string(sourceCode))
+
+	var tests []types.FunctionTest
+	quit := false
 	testPath := g.finder.GetTestPath(sourcePath)
 
 	for _, function := range functions {
-		slog.Info("generating test for function", "function", function.Name)
-
-		// Create temp file in the test directory
-		tempFile, err := os.CreateTemp(filepath.Dir(testPath), fmt.Sprintf("%s*%s", function.Name, g.language.GetTestFilePattern()))
-		if err != nil {
-			slog.Error("failed to create temp file", "function", function.Name, "error", err)
-			continue
+		if ctx.Err() != nil {
+			slog.Warn("stopping test generation for file", "path", relPath, "reason", ctx.Err())
+			break
 		}
-		tempPath := tempFile.Name()
-		tempFile.Close()
-
-		params := types.GenerateTestParams{
-			Language:       g.language,
-			TestRunner:     g.language.GetTestRunner(),
-			TestPath:       tempPath,
-			Function:       function,
-			SourceCode:     string(sourceCode),
-			SourceCodePath: sourcePath,
-			Example:        example,
-			ContextFiles:   g.contextFiles,
+
+		// Stop scheduling new functions once the run is over budget, keeping the tests that already pass
+		if err := g.usage.CheckBudget(); err != nil {
+			slog.Warn("stopping test generation for file", "path", relPath, "reason", err)
+			break
 		}
 
-		testCode, err := g.ai.GenerateTest(params)
+		slog.Info("generating test for function", "function", function.Name, "receiver", function.Receiver)
+		g.usage.SetTarget(relPath, qualifiedName(function))
+
+		testCode, err := g.generateFunctionTest(ctx, projectDir, sourcePath, string(sourceCode), function, examples, testPath)
+		if errors.Is(err, ErrReviewQuit) {
+			quit = true
+			break
+		}
+		if errors.Is(err, errRejected) {
+			slog.Info("test rejected in review", "function", function.Name)
+			g.usage.RecordTest(false)
+			continue
+		}
 		if err != nil {
 			slog.Error("failed to generate test", "function", function.Name, "error", err)
+			g.usage.RecordTest(false)
 			continue
 		}
 
-		if err := os.WriteFile(tempPath, []byte(testCode), 0644); err != nil {
-			slog.Error("failed to write temp test file", "path", tempPath, "error", err)
-			continue
-		}
+		g.usage.RecordTest(true)
+		tests = append(tests, types.FunctionTest{
+			Function: function,
+			TestCode: testCode,
+			Attempts: g.usage.FunctionCalls(relPath, qualifiedName(function)),
+		})
+	}
 
-		testCode, err = g.iterateTypeErrors(params, testCode)
-		if err != nil {
-			slog.Error("error fixing type errors", "function", function.Name, "error", err)
-			continue
+	if len(tests) > 0 {
+		for _, file := range g.testFiles(ctx, sourcePath, realSourcePath, finalTestPath, tests) {
+			slog.Info("writing final test file", "path", file.Path)
+			if err := g.sink.WriteFile(file); err != nil {
+				slog.Error("failed to write test file", "path", file.Path, "error", err)
+				return err
+			}
 		}
+	}
 
-		testCode, err = g.iterateTestFailures(params, testCode, projectDir)
-		if err != nil {
-			slog.Error("error fixing test errors", "function", function.Name, "error", err)
-			continue
+	if quit {
+		return ErrReviewQuit
+	}
+	return ctx.Err()
+}
+
+// testFiles returns the files the tests of a source file are written to. The tests are
+// merged into one file when it type checks, and otherwise each gets a file of its own.
+func (g *TestGenerator) testFiles(ctx context.Context, sourcePath, realSourcePath, finalTestPath string, tests []types.FunctionTest) []types.TestFile {
+	if len(tests) == 1 {
+		return []types.TestFile{{SourcePath: realSourcePath, Path: finalTestPath, Content: output.Content(tests), Tests: tests}}
+	}
+
+	merged, err := g.mergeTests(ctx, sourcePath, tests)
+	if err == nil {
+		return []types.TestFile{{SourcePath: realSourcePath, Path: finalTestPath, Content: []byte(merged), Tests: tests}}
+	}
+	slog.Warn("failed to merge tests, writing a test file per function", "path", finalTestPath, "error", err)
+
+	files := make([]types.TestFile, len(tests))
+	for i, test := range tests {
+		path := finalTestPath
+		if i > 0 {
+			path = additionalTestPath(g.finder.GetTestPath(realSourcePath), g.language.GetTestFilePattern(), test.Function)
 		}
+		files[i] = types.TestFile{SourcePath: realSourcePath, Path: path, Content: output.Content(tests[i : i+1]), Tests: tests[i : i+1]}
+	}
+	return files
+}
 
-		// Clean up the temp file only if the test code is valid
-		// otherwise, keep it around for further iteration
-		defer os.Remove(tempPath)
+// mergeTests merges the tests into one file, and type checks it next to the source file
+// like the tests were on their own
+func (g *TestGenerator) mergeTests(ctx context.Context, sourcePath string, tests []types.FunctionTest) (string, error) {
+	codes := make([]string, len(tests))
+	for i, test := range tests {
+		codes[i] = test.TestCode
+	}
+	merged, err := g.language.MergeTests(codes)
+	if err != nil {
+		return "", err
+	}
 
-		allTestCode += testCode + "\n"
+	tempFile, err := os.CreateTemp(filepath.Dir(g.finder.GetTestPath(sourcePath)), "merged*"+g.language.GetTestFilePattern())
+	if err != nil {
+		return "", fmt.Errorf("error creating temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.WriteString(merged)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("error writing merged tests: %w", err)
 	}
 
-	if allTestCode != "" {
-		slog.Info("writing final test file", "path", testPath)
+	checkCtx, cancel := context.WithTimeout(ctx, TypeCheckTimeout)
+	result, err := g.language.CheckTypes(checkCtx, tempFile.Name())
+	cancel()
+	if err != nil {
+		return "", fmt.Errorf("error type checking merged tests: %w", err)
+	}
+	if !result.Passed {
+		return "", fmt.Errorf("merged tests don't type check: %s", strings.Join(relevantFailures(result, tempFile.Name()), "; "))
+	}
+	return merged, nil
+}
 
-		if err := os.MkdirAll(filepath.Dir(testPath), 0755); err != nil {
-			slog.Error("failed to create test directory", "path", filepath.Dir(testPath), "error", err)
-			return fmt.Errorf("error creating test directory: %w", err)
+// generateFunctionTest generates a test for one function in a temp file next to the
+// final test file, iterating until it type-checks and passes. With a reviewer, the
+// test is only returned once the user accepts it.
+func (g *TestGenerator) generateFunctionTest(ctx context.Context, projectDir, sourcePath, sourceCode string, function types.Function, examples []types.TestExample, testPath string) (testCode string, err error) {
+	tempFile, err := os.CreateTemp(filepath.Dir(testPath), fmt.Sprintf("%s*%s", functionID(function), g.language.GetTestFilePattern()))
+	if err != nil {
+		return "", fmt.Errorf("error creating temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	tempFile.Close()
+	// The temp file sits among the package's other tests, so it never outlives this call
+	defer func() {
+		if err != nil && ctx.Err() == nil && g.keepFailed && !reviewed(err) {
+			g.keepFailedTest(tempPath, function)
 		}
+		os.Remove(tempPath)
+	}()
+
+	params := g.BuildParams(ctx, sourcePath, sourceCode, function, examples, tempPath)
+
+	testCode, score, err := g.buildTest(ctx, params, projectDir)
+	if err != nil {
+		return "", err
+	}
+	if g.reviewer == nil {
+		return testCode, nil
+	}
+	return g.reviewTest(ctx, params, testCode, score, projectDir)
+}
+
+// buildTest generates a test and iterates until it type-checks and passes, then makes
+// it stable and strong enough. It returns the test with its mutation score, if any.
+func (g *TestGenerator) buildTest(ctx context.Context, params types.GenerateTestParams, projectDir string) (string, *float64, error) {
+	agentCtx, cancel := context.WithTimeout(ctx, AgentTimeout)
+	testCode, err := g.ai.GenerateTest(agentCtx, params)
+	cancel()
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating test: %w", err)
+	}
+
+	if err := os.WriteFile(params.TestPath, []byte(testCode), 0644); err != nil {
+		return "", nil, fmt.Errorf("error writing temp test file: %w", err)
+	}
+
+	testCode, err = g.iterateTypeErrors(ctx, params, testCode)
+	if err != nil {
+		return "", nil, fmt.Errorf("error fixing type errors: %w", err)
+	}
+
+	testCode, err = g.iterateTestFailures(ctx, params, testCode, projectDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("error fixing test errors: %w", err)
+	}
+
+	testCode, err = g.stabilizeTest(ctx, params, testCode, projectDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("error fixing flaky test: %w", err)
+	}
+
+	testCode, score, err := g.strengthenTest(ctx, params, testCode, projectDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("error strengthening test: %w", err)
+	}
+
+	return testCode, score, nil
+}
+
+// keepFailedTest copies a test that couldn't be fixed out of the workspace
+func (g *TestGenerator) keepFailedTest(tempPath string, function types.Function) {
+	if g.workspace == nil {
+		return
+	}
+	kept, err := g.workspace.Keep(tempPath)
+	if err != nil {
+		slog.Warn("failed to keep failed test", "function", function.Name, "error", err)
+		return
+	}
+	slog.Info("kept failed test", "function", function.Name, "path", kept)
+}
+
+// BuildParams assembles the prompt parameters for generating a test for a single function.
+// Examples are ordered most relevant first.
+func (g *TestGenerator) BuildParams(ctx context.Context, sourcePath, sourceCode string, function types.Function, examples []types.TestExample, testPath string) types.GenerateTestParams {
+	var example types.TestExample
+	var otherExamples []types.TestExample
+	if len(examples) > 0 {
+		example, otherExamples = examples[0], examples[1:]
+	}
+
+	return types.GenerateTestParams{
+		Language:       g.language,
+		TestRunner:     g.language.GetTestRunner(),
+		TestPath:       testPath,
+		Function:       function,
+		SourceCode:     sourceCode,
+		SourceCodePath: sourcePath,
+		Example:        example,
+		OtherExamples:  otherExamples,
+		ContextFiles:   g.contextFilesFor(ctx, sourcePath, function),
+	}
+}
 
-		if err := os.WriteFile(testPath, []byte(allTestCode), 0644); err != nil {
-			slog.Error("failed to write test file", "path", testPath, "error", err)
-			return fmt.Errorf("error writing test file: %w", err)
+// additionalTestPath returns a free path next to an existing test file, named after the
+// first function it tests, e.g. math_Calculator_Add_test.go next to math_test.go
+func additionalTestPath(testPath, pattern string, function types.Function) string {
+	base := strings.TrimSuffix(testPath, pattern) + "_" + functionID(function)
+	path := base + pattern
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); err != nil {
+			return path
 		}
+		path = fmt.Sprintf("%s_%d%s", base, i, pattern)
 	}
+}
+
+// qualifiedName returns the function name prefixed with its receiver, e.g. "Calculator.Add"
+func qualifiedName(function types.Function) string {
+	if function.Receiver == "" {
+		return function.Name
+	}
+	return function.Receiver + "." + function.Name
+}
 
-	return nil
+// functionID returns a file-name-safe identifier that distinguishes methods
+// from free functions with the same name
+func functionID(function types.Function) string {
+	id := function.Name
+	if function.Receiver != "" {
+		id = function.Receiver + "_" + function.Name
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, id)
 }
@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gwkline/artestian/pkg/usage"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string // Test files already next to the source file
+		names    []string // Nil generates tests for the whole file, like a normal run
+		expected map[string]string
+	}{
+		{
+			name:     "no test file",
+			names:    []string{"Add"},
+			expected: map[string]string{"math_test.go": "pass Add\n"},
+		},
+		{
+			name:     "existing test file",
+			existing: []string{"math_test.go"},
+			names:    []string{"Add"},
+			expected: map[string]string{"math_test.go": "existing", "math_Add_test.go": "pass Add\n"},
+		},
+		{
+			name:     "existing additional test file",
+			existing: []string{"math_test.go", "math_Add_test.go"},
+			names:    []string{"Add"},
+			expected: map[string]string{"math_test.go": "existing", "math_Add_test.go": "existing", "math_Add_2_test.go": "pass Add\n"},
+		},
+		{
+			name:     "normal run with a stale test file",
+			existing: []string{"math_test.go"},
+			expected: map[string]string{"math_test.go": "pass Add\n"},
+		},
+		{
+			name:     "function no longer in file",
+			names:    []string{"Mul"},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			dir := filepath.Join(project, "pkg")
+			require.NoError(t, os.MkdirAll(dir, 0755))
+			sourcePath := filepath.Join(dir, "math.go")
+			require.NoError(t, os.WriteFile(sourcePath, []byte("package pkg\n"), 0644))
+			for _, name := range tt.existing {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("existing"), 0644))
+			}
+
+			g := NewTestGenerator(&singleFileFinder{path: sourcePath}, &functionAgent{}, &workspaceLanguage{project: project}, nil, nil, usage.NewTracker(nil, usage.Limits{}))
+			if tt.names == nil {
+				require.NoError(t, g.GenerateNextTest(context.Background(), project, &rootConfig{root: project}))
+			} else {
+				require.NoError(t, g.GenerateFunctions(context.Background(), project, &rootConfig{root: project}, sourcePath, tt.names))
+			}
+
+			files := map[string]string{}
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				if entry.Name() == "math.go" {
+					continue
+				}
+				content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+				require.NoError(t, err)
+				files[entry.Name()] = string(content)
+			}
+			assert.Equal(t, tt.expected, files)
+		})
+	}
+}
+
+func TestAdditionalTestPath(t *testing.T) {
+	dir := t.TempDir()
+	testPath := filepath.Join(dir, "math_test.go")
+
+	assert.Equal(t, filepath.Join(dir, "math_Calculator_Add_test.go"), additionalTestPath(testPath, "_test.go", types.Function{Name: "Add", Receiver: "Calculator"}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "math_Add_test.go"), nil, 0644))
+	assert.Equal(t, filepath.Join(dir, "math_Add_2_test.go"), additionalTestPath(testPath, "_test.go", types.Function{Name: "Add"}))
+}
+
+// mergeLanguage merges tests under a "merged" line, and fails the type check of merged
+// files when conflict is set
+type mergeLanguage struct {
+	*workspaceLanguage
+	mergeErr error
+	conflict bool
+}
+
+func (l *mergeLanguage) MergeTests(tests []string) (string, error) {
+	if l.mergeErr != nil {
+		return "", l.mergeErr
+	}
+	return "merged\n" + strings.Join(tests, "\n") + "\n", nil
+}
+
+func (l *mergeLanguage) CheckTypes(ctx context.Context, testFilePath string) (types.RunResult, error) {
+	if l.conflict && strings.HasPrefix(filepath.Base(testFilePath), "merged") {
+		return types.RunResult{Diagnostics: []types.Diagnostic{{Message: "TestHelper redeclared"}}}, nil
+	}
+	return types.RunResult{Passed: true}, nil
+}
+
+// passingAgent writes a passing test for every function
+type passingAgent struct {
+	types.IAgent
+}
+
+func (a *passingAgent) GenerateTest(ctx context.Context, params types.GenerateTestParams) (string, error) {
+	return "pass " + params.Function.Name, nil
+}
+
+func TestGenerateNextTest_Merge(t *testing.T) {
+	tests := []struct {
+		name     string
+		mergeErr error
+		conflict bool
+		expected map[string]string
+	}{
+		{
+			name:     "merged",
+			expected: map[string]string{"math_test.go": "merged\npass Add\npass Sub\n"},
+		},
+		{
+			name:     "merged file doesn't type check",
+			conflict: true,
+			expected: map[string]string{"math_test.go": "pass Add\n", "math_Sub_test.go": "pass Sub\n"},
+		},
+		{
+			name:     "tests can't be merged",
+			mergeErr: errors.New("tests are in different packages"),
+			expected: map[string]string{"math_test.go": "pass Add\n", "math_Sub_test.go": "pass Sub\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			dir := filepath.Join(project, "pkg")
+			require.NoError(t, os.MkdirAll(dir, 0755))
+			sourcePath := filepath.Join(dir, "math.go")
+			require.NoError(t, os.WriteFile(sourcePath, []byte("package pkg\n"), 0644))
+
+			language := &mergeLanguage{workspaceLanguage: &workspaceLanguage{project: project}, mergeErr: tt.mergeErr, conflict: tt.conflict}
+			g := NewTestGenerator(&singleFileFinder{path: sourcePath}, &passingAgent{}, language, nil, nil, usage.NewTracker(nil, usage.Limits{}))
+			require.NoError(t, g.GenerateNextTest(context.Background(), project, &rootConfig{root: project}))
+
+			files := map[string]string{}
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				if entry.Name() != "math.go" {
+					files[entry.Name()] = readTestFile(t, filepath.Join(dir, entry.Name()))
+				}
+			}
+			assert.Equal(t, tt.expected, files)
+		})
+	}
+}
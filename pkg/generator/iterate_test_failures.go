@@ -1,42 +1,51 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
-	"strings"
 
 	"github.com/gwkline/artestian/types"
 )
 
-func (g *TestGenerator) iterateTestFailures(params types.GenerateTestParams, testCode string, projectDir string) (string, error) {
+func (g *TestGenerator) iterateTestFailures(ctx context.Context, params types.GenerateTestParams, testCode string, projectDir string) (string, error) {
 	var attempts []types.ErrorAttempt
 	maxTestAttempts := 3
 	runner := g.language.GetTestRunner()
 
 	for i := 0; i < maxTestAttempts; i++ {
 		slog.Debug("running tests", "attempt", i+1, "path", params.TestPath, "projectDir", projectDir)
-		ok, testErrors, err := runner.RunTests(projectDir, params.TestPath)
+		stepCtx, cancel := context.WithTimeout(ctx, TestRunTimeout)
+		result, err := runner.RunTests(stepCtx, projectDir, params.TestPath)
+		cancel()
 		if err != nil {
 			return "", fmt.Errorf("error running tests: %w", err)
 		}
-		if ok {
+		if result.Passed {
 			slog.Info("tests passed")
 			return testCode, nil
 		}
 
+		testErrors := relevantFailures(result, params.TestPath)
 		slog.Debug("test errors", "errors", testErrors)
 		attempts = append(attempts, types.ErrorAttempt{
 			Code:   testCode,
-			Errors: strings.Split(testErrors, "\n"),
+			Errors: testErrors,
 		})
 
+		if err := g.checkBudget(); err != nil {
+			return "", err
+		}
+
 		slog.Info("fixing test errors", "attempt", i+1)
-		fixedCode, err := g.ai.FixTestFailures(types.IterateTestParams{
+		agentCtx, cancel := context.WithTimeout(ctx, AgentTimeout)
+		fixedCode, err := g.ai.FixTestFailures(agentCtx, types.IterateTestParams{
 			GenerateTestParams: params,
 			TestCode:           testCode,
-			Errors:             strings.Split(testErrors, "\n"),
+			Errors:             testErrors,
 		})
+		cancel()
 		if err != nil {
 			return "", fmt.Errorf("error fixing test errors: %w", err)
 		}
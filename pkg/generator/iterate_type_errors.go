@@ -1,41 +1,50 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
-	"strings"
 
 	"github.com/gwkline/artestian/types"
 )
 
-func (g *TestGenerator) iterateTypeErrors(params types.GenerateTestParams, testCode string) (string, error) {
+func (g *TestGenerator) iterateTypeErrors(ctx context.Context, params types.GenerateTestParams, testCode string) (string, error) {
 	var attempts []types.ErrorAttempt
 	maxTypeAttempts := 5
 
 	for i := 0; i < maxTypeAttempts; i++ {
 		slog.Debug("checking types", "attempt", i+1, "path", params.TestPath)
-		ok, typeErrors, err := g.language.CheckTypes(params.TestPath)
+		stepCtx, cancel := context.WithTimeout(ctx, TypeCheckTimeout)
+		result, err := g.language.CheckTypes(stepCtx, params.TestPath)
+		cancel()
 		if err != nil {
 			return "", fmt.Errorf("error checking types: %w", err)
 		}
-		if ok {
+		if result.Passed {
 			slog.Info("type check passed")
 			return testCode, nil
 		}
 
+		typeErrors := relevantFailures(result, params.TestPath)
 		slog.Debug("type errors", "errors", typeErrors)
 		attempts = append(attempts, types.ErrorAttempt{
 			Code:   testCode,
-			Errors: strings.Split(typeErrors, "\n"),
+			Errors: typeErrors,
 		})
 
+		if err := g.checkBudget(); err != nil {
+			return "", err
+		}
+
 		slog.Info("fixing type errors", "attempt", i+1)
-		fixedCode, err := g.ai.FixTypeErrors(types.IterateTestParams{
+		agentCtx, cancel := context.WithTimeout(ctx, AgentTimeout)
+		fixedCode, err := g.ai.FixTypeErrors(agentCtx, types.IterateTestParams{
 			GenerateTestParams: params,
 			TestCode:           testCode,
-			Errors:             strings.Split(typeErrors, "\n"),
+			Errors:             typeErrors,
 		})
+		cancel()
 		if err != nil {
 			return "", fmt.Errorf("error fixing type errors: %w", err)
 		}
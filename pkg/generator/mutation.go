@@ -0,0 +1,204 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// MutationOptions configures checking generated tests against mutants of the function
+type MutationOptions struct {
+	Threshold   float64       // Share of valid mutants the test must fail on
+	MaxMutants  int           // Mutants run per function
+	MaxAttempts int           // Times the model is asked to strengthen a test below the threshold
+	Timeout     time.Duration // Test run timeout per mutant
+}
+
+// mutationReport is the outcome of running a test against a function's mutants
+type mutationReport struct {
+	killed   int
+	invalid  int // Mutants that didn't compile, which don't count towards the score
+	survived []types.Mutant
+}
+
+func (r mutationReport) valid() int {
+	return r.killed + len(r.survived)
+}
+
+// score is the share of valid mutants the test failed on
+func (r mutationReport) score() float64 {
+	if r.valid() == 0 {
+		return 1
+	}
+	return float64(r.killed) / float64(r.valid())
+}
+
+// EnableMutationTesting checks every passing test against mutants of its function, and
+// asks the model to strengthen tests that don't fail on enough of them
+func (g *TestGenerator) EnableMutationTesting(options MutationOptions) {
+	g.mutation = &options
+}
+
+// strengthenTest runs a passing test against mutants of the function and, while its
+// score is below the threshold, asks the model to add assertions that catch the
+// surviving mutants. The best scoring passing version of the test is returned with its
+// score, which is nil when no mutants could be scored.
+func (g *TestGenerator) strengthenTest(ctx context.Context, params types.GenerateTestParams, testCode, projectDir string) (string, *float64, error) {
+	if g.mutation == nil {
+		return testCode, nil, nil
+	}
+
+	best, bestScore := testCode, -1.0
+	for attempt := 0; ; attempt++ {
+		report, err := g.runMutants(ctx, params, projectDir)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", nil, ctx.Err()
+			}
+			slog.Warn("skipping mutation testing", "function", params.Function.Name, "error", err)
+			return best, nil, nil
+		}
+		if report.valid() == 0 {
+			slog.Info("no valid mutants for function", "function", params.Function.Name, "invalid", report.invalid)
+			return best, nil, nil
+		}
+
+		score := report.score()
+		slog.Info("mutation score", "function", params.Function.Name, "score", score, "killed", report.killed, "survived", len(report.survived), "invalid", report.invalid)
+		if score > bestScore {
+			best, bestScore = testCode, score
+		}
+		if score >= g.mutation.Threshold || attempt >= g.mutation.MaxAttempts {
+			break
+		}
+
+		if err := g.checkBudget(); err != nil {
+			slog.Warn("stopping test strengthening", "function", params.Function.Name, "reason", err)
+			break
+		}
+
+		slog.Info("strengthening test", "attempt", attempt+1, "survived", len(report.survived))
+		agentCtx, cancel := context.WithTimeout(ctx, AgentTimeout)
+		strengthened, err := g.ai.FixTestFailures(agentCtx, types.IterateTestParams{
+			GenerateTestParams: params,
+			TestCode:           testCode,
+			Errors:             survivorMessages(report.survived),
+		})
+		cancel()
+		if err != nil {
+			slog.Warn("failed to strengthen test", "function", params.Function.Name, "error", err)
+			break
+		}
+
+		if err := os.WriteFile(params.TestPath, []byte(strengthened), 0644); err != nil {
+			return "", nil, fmt.Errorf("error writing strengthened test file: %w", err)
+		}
+		// The stronger test must still pass against the original function
+		testCode, err = g.iterateTestFailures(ctx, params, strengthened, projectDir)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", nil, ctx.Err()
+			}
+			slog.Warn("strengthened test does not pass, keeping the previous version", "function", params.Function.Name, "error", err)
+			break
+		}
+	}
+
+	if bestScore < g.mutation.Threshold {
+		slog.Warn("test is below the mutation score threshold", "function", params.Function.Name, "score", bestScore, "threshold", g.mutation.Threshold)
+	}
+	if err := os.WriteFile(params.TestPath, []byte(best), 0644); err != nil {
+		return "", nil, fmt.Errorf("error writing test file: %w", err)
+	}
+	return best, &bestScore, nil
+}
+
+// runMutants writes each mutant over the source file in turn and runs the test against
+// it. The original source is always restored, even when ctx is cancelled.
+func (g *TestGenerator) runMutants(ctx context.Context, params types.GenerateTestParams, projectDir string) (report mutationReport, err error) {
+	original, err := os.ReadFile(params.SourceCodePath)
+	if err != nil {
+		return report, fmt.Errorf("error reading source file: %w", err)
+	}
+
+	mutants, err := g.language.Mutate(string(original), params.Function)
+	if err != nil {
+		return report, fmt.Errorf("error creating mutants: %w", err)
+	}
+	mutants = spreadMutants(mutants, g.mutation.MaxMutants)
+
+	defer func() {
+		if restoreErr := os.WriteFile(params.SourceCodePath, original, 0); restoreErr != nil {
+			slog.Error("failed to restore source file after mutation testing", "path", params.SourceCodePath, "error", restoreErr)
+			err = fmt.Errorf("error restoring source file: %w", restoreErr)
+		}
+	}()
+
+	runner := g.language.GetTestRunner()
+	for _, mutant := range mutants {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		if err := os.WriteFile(params.SourceCodePath, []byte(mutant.SourceCode), 0); err != nil {
+			return report, fmt.Errorf("error writing mutant: %w", err)
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, g.mutation.Timeout)
+		result, err := runner.RunTests(runCtx, projectDir, params.TestPath)
+		cancel()
+		if err != nil {
+			return report, fmt.Errorf("error running tests against mutant: %w", err)
+		}
+
+		switch {
+		case result.Passed:
+			report.survived = append(report.survived, mutant)
+		case killed(result):
+			report.killed++
+		default:
+			report.invalid++
+		}
+		slog.Debug("ran mutant", "line", mutant.Line, "mutation", mutant.Description, "passed", result.Passed)
+	}
+	return report, nil
+}
+
+// killed reports whether a failed run failed because of the test, rather than because
+// the mutant didn't compile
+func killed(result types.RunResult) bool {
+	if result.TimedOut || result.Panic != "" {
+		return true
+	}
+	for _, tc := range result.Tests {
+		if tc.Status == types.TestStatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// spreadMutants picks up to limit mutants evenly spread through the function
+func spreadMutants(mutants []types.Mutant, limit int) []types.Mutant {
+	if limit <= 0 || len(mutants) <= limit {
+		return mutants
+	}
+	spread := make([]types.Mutant, limit)
+	for i := range spread {
+		spread[i] = mutants[i*len(mutants)/limit]
+	}
+	return spread
+}
+
+// survivorMessages describes the surviving mutants for the model
+func survivorMessages(survived []types.Mutant) []string {
+	messages := make([]string, len(survived))
+	for i, mutant := range survived {
+		messages[i] = fmt.Sprintf("the test still passes when the function is changed at line %d (%s); add assertions that fail for this change", mutant.Line, mutant.Description)
+	}
+	return messages
+}
@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mutantLanguage returns fixed mutants and runs tests with results keyed by source
+type mutantLanguage struct {
+	types.ILanguage
+	mutants []types.Mutant
+	results map[string]types.RunResult
+}
+
+func (l *mutantLanguage) Mutate(sourceCode string, function types.Function) ([]types.Mutant, error) {
+	return l.mutants, nil
+}
+
+func (l *mutantLanguage) GetTestRunner() types.ITestRunner {
+	return l
+}
+
+func (l *mutantLanguage) GetName() string {
+	return "fake"
+}
+
+func (l *mutantLanguage) RunTests(ctx context.Context, rootDir, testFilePath string) (types.RunResult, error) {
+	source, err := os.ReadFile(filepath.Join(rootDir, "add.go"))
+	if err != nil {
+		return types.RunResult{}, err
+	}
+	return l.results[string(source)], nil
+}
+
+func (l *mutantLanguage) RerunTests(ctx context.Context, rootDir, testFilePath string, options types.RerunOptions) (types.RunResult, error) {
+	return l.RunTests(ctx, rootDir, testFilePath)
+}
+
+func TestRunMutants(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "add.go")
+	require.NoError(t, os.WriteFile(sourcePath, []byte("original"), 0644))
+
+	language := &mutantLanguage{
+		mutants: []types.Mutant{
+			{Line: 1, Description: "replaced + with -", SourceCode: "failing"},
+			{Line: 2, Description: "replaced 1 with 2", SourceCode: "passing"},
+			{Line: 3, Description: "returned zero values", SourceCode: "broken"},
+			{Line: 4, Description: "negated if condition", SourceCode: "hanging"},
+		},
+		results: map[string]types.RunResult{
+			"failing": {Tests: []types.TestCase{{Name: "TestAdd", Status: types.TestStatusFail}}},
+			"passing": {Passed: true},
+			"broken":  {Diagnostics: []types.Diagnostic{{Message: "undefined: x"}}},
+			"hanging": {TimedOut: true},
+		},
+	}
+	g := NewTestGenerator(nil, nil, language, nil, nil, nil)
+	g.EnableMutationTesting(MutationOptions{Threshold: 0.6, Timeout: time.Minute})
+
+	report, err := g.runMutants(context.Background(), types.GenerateTestParams{SourceCodePath: sourcePath, TestPath: filepath.Join(dir, "add_test.go")}, dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.killed)
+	assert.Equal(t, 1, report.invalid)
+	assert.Equal(t, []types.Mutant{language.mutants[1]}, report.survived)
+	assert.InDelta(t, 2.0/3, report.score(), 1e-9)
+	assert.Equal(t, []string{"the test still passes when the function is changed at line 2 (replaced 1 with 2); add assertions that fail for this change"}, survivorMessages(report.survived))
+
+	// The original source is restored
+	source, err := os.ReadFile(sourcePath)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(source))
+}
+
+func TestSpreadMutants(t *testing.T) {
+	mutants := make([]types.Mutant, 10)
+	for i := range mutants {
+		mutants[i].Line = i
+	}
+
+	tests := []struct {
+		name     string
+		limit    int
+		expected []int
+	}{
+		{name: "no limit", limit: 0, expected: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{name: "under the limit", limit: 20, expected: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{name: "spread through the function", limit: 4, expected: []int{0, 2, 5, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []int
+			for _, mutant := range spreadMutants(mutants, tt.limit) {
+				lines = append(lines, mutant.Line)
+			}
+			assert.Equal(t, tt.expected, lines)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// ErrReviewQuit is returned once the user quits a review, after the tests they already
+// accepted for the file are written
+var ErrReviewQuit = errors.New("review quit by user")
+
+// errRejected is returned for a test the user rejected in review
+var errRejected = errors.New("test rejected in review")
+
+// SetReviewer shows every passing test to reviewer, and only keeps the ones it accepts
+func (g *TestGenerator) SetReviewer(reviewer types.IReviewer) {
+	g.reviewer = reviewer
+}
+
+// reviewed reports whether err is the user's decision rather than a failure
+func reviewed(err error) bool {
+	return errors.Is(err, errRejected) || errors.Is(err, ErrReviewQuit)
+}
+
+// reviewTest shows a passing test to the reviewer until it's accepted or rejected.
+// Edited tests are checked again before they're shown, and regenerated tests go through
+// the whole pipeline.
+func (g *TestGenerator) reviewTest(ctx context.Context, params types.GenerateTestParams, testCode string, score *float64, projectDir string) (string, error) {
+	result, err := g.checkTest(ctx, params, projectDir)
+	if err != nil {
+		return "", err
+	}
+	relPath, err := filepath.Rel(projectDir, params.SourceCodePath)
+	if err != nil {
+		relPath = params.SourceCodePath
+	}
+
+	for {
+		decision, err := g.reviewer.Review(ctx, types.ReviewItem{
+			SourcePath:    relPath,
+			TestPath:      params.TestPath,
+			Function:      params.Function,
+			TestCode:      testCode,
+			Result:        result,
+			MutationScore: score,
+		})
+		if err != nil {
+			return "", fmt.Errorf("error reviewing test: %w", err)
+		}
+
+		switch decision.Action {
+		case types.ReviewAccept:
+			return testCode, nil
+		case types.ReviewReject:
+			return "", errRejected
+		case types.ReviewQuit:
+			return "", ErrReviewQuit
+		case types.ReviewEdit:
+			testCode, score = decision.TestCode, nil
+			if err := os.WriteFile(params.TestPath, []byte(testCode), 0644); err != nil {
+				return "", fmt.Errorf("error writing edited test file: %w", err)
+			}
+		case types.ReviewRegenerate:
+			regenerated := params
+			regenerated.Instructions = decision.Instruction
+			slog.Info("regenerating test", "function", params.Function.Name, "instruction", decision.Instruction)
+
+			code, codeScore, err := g.buildTest(ctx, regenerated, projectDir)
+			if err != nil {
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
+				slog.Warn("regenerated test does not pass, keeping the previous version", "function", params.Function.Name, "error", err)
+				if err := os.WriteFile(params.TestPath, []byte(testCode), 0644); err != nil {
+					return "", fmt.Errorf("error writing test file: %w", err)
+				}
+				break
+			}
+			testCode, score = code, codeScore
+		default:
+			return "", fmt.Errorf("unknown review action: %s", decision.Action)
+		}
+
+		if result, err = g.checkTest(ctx, params, projectDir); err != nil {
+			return "", err
+		}
+	}
+}
+
+// checkTest type checks and runs the test file, so the reviewer sees its current result
+func (g *TestGenerator) checkTest(ctx context.Context, params types.GenerateTestParams, projectDir string) (types.RunResult, error) {
+	stepCtx, cancel := context.WithTimeout(ctx, TypeCheckTimeout)
+	result, err := g.language.CheckTypes(stepCtx, params.TestPath)
+	cancel()
+	if err != nil {
+		return types.RunResult{}, fmt.Errorf("error checking types: %w", err)
+	}
+	if !result.Passed {
+		return result, nil
+	}
+
+	stepCtx, cancel = context.WithTimeout(ctx, TestRunTimeout)
+	defer cancel()
+	result, err = g.language.GetTestRunner().RunTests(stepCtx, projectDir, params.TestPath)
+	if err != nil {
+		return types.RunResult{}, fmt.Errorf("error running tests: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gwkline/artestian/pkg/usage"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedReviewer makes the given decisions in order, and records what it was shown
+type scriptedReviewer struct {
+	decisions []types.ReviewDecision
+	items     []types.ReviewItem
+}
+
+func (r *scriptedReviewer) Review(ctx context.Context, item types.ReviewItem) (types.ReviewDecision, error) {
+	r.items = append(r.items, item)
+	decision := r.decisions[0]
+	r.decisions = r.decisions[1:]
+	return decision, nil
+}
+
+// instructedAgent writes passing tests that include any instructions
+type instructedAgent struct {
+	types.IAgent
+}
+
+func (a *instructedAgent) GenerateTest(ctx context.Context, params types.GenerateTestParams) (string, error) {
+	if params.Instructions != "" {
+		return "pass " + params.Function.Name + ", " + params.Instructions, nil
+	}
+	return "pass " + params.Function.Name, nil
+}
+
+func TestGenerateNextTest_Review(t *testing.T) {
+	accept := types.ReviewDecision{Action: types.ReviewAccept}
+	reject := types.ReviewDecision{Action: types.ReviewReject}
+	quit := types.ReviewDecision{Action: types.ReviewQuit}
+
+	tests := []struct {
+		name      string
+		decisions []types.ReviewDecision
+		expected  string // Test file content, empty when no file is written
+		err       error
+		check     func(t *testing.T, items []types.ReviewItem)
+	}{
+		{
+			name:      "accept",
+			decisions: []types.ReviewDecision{accept, accept},
+			expected:  "pass Add\npass Sub\n",
+			check: func(t *testing.T, items []types.ReviewItem) {
+				assert.Equal(t, filepath.Join("pkg", "math.go"), items[0].SourcePath)
+				assert.Equal(t, "Add", items[0].Function.Name)
+				assert.Equal(t, "pass Add", items[0].TestCode)
+				assert.True(t, items[0].Result.Passed)
+				assert.Nil(t, items[0].MutationScore)
+			},
+		},
+		{
+			name:      "reject",
+			decisions: []types.ReviewDecision{reject, accept},
+			expected:  "pass Sub\n",
+		},
+		{
+			name: "edit",
+			decisions: []types.ReviewDecision{
+				{Action: types.ReviewEdit, TestCode: "fail edited"},
+				{Action: types.ReviewEdit, TestCode: "pass edited"},
+				accept,
+				reject,
+			},
+			expected: "pass edited\n",
+			check: func(t *testing.T, items []types.ReviewItem) {
+				// Edited tests are run again before they're shown
+				assert.Equal(t, "fail edited", items[1].TestCode)
+				assert.False(t, items[1].Result.Passed)
+				assert.Equal(t, "pass edited", items[2].TestCode)
+				assert.True(t, items[2].Result.Passed)
+			},
+		},
+		{
+			name: "regenerate",
+			decisions: []types.ReviewDecision{
+				{Action: types.ReviewRegenerate, Instruction: "use table tests"},
+				accept,
+				accept,
+			},
+			expected: "pass Add, use table tests\npass Sub\n",
+		},
+		{
+			name:      "quit",
+			decisions: []types.ReviewDecision{quit},
+			err:       ErrReviewQuit,
+		},
+		{
+			name:      "quit keeps accepted tests",
+			decisions: []types.ReviewDecision{accept, quit},
+			expected:  "pass Add\n",
+			err:       ErrReviewQuit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			sourcePath := filepath.Join(project, "pkg", "math.go")
+			require.NoError(t, os.MkdirAll(filepath.Dir(sourcePath), 0755))
+			require.NoError(t, os.WriteFile(sourcePath, []byte("package pkg\n"), 0644))
+
+			reviewer := &scriptedReviewer{decisions: tt.decisions}
+			g := NewTestGenerator(&singleFileFinder{path: sourcePath}, &instructedAgent{}, &workspaceLanguage{project: project}, nil, nil, usage.NewTracker(nil, usage.Limits{}))
+			g.SetReviewer(reviewer)
+
+			err := g.GenerateNextTest(context.Background(), project, &rootConfig{root: project})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Empty(t, reviewer.decisions, "every decision is used")
+
+			testPath := filepath.Join(project, "pkg", "math_test.go")
+			if tt.expected == "" {
+				assert.NoFileExists(t, testPath)
+			} else {
+				content, err := os.ReadFile(testPath)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, string(content))
+			}
+			entries, err := os.ReadDir(filepath.Dir(sourcePath))
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(entries), 2, "temp files are removed")
+
+			if tt.check != nil {
+				tt.check(t, reviewer.items)
+			}
+		})
+	}
+}
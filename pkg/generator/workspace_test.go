@@ -0,0 +1,240 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gwkline/artestian/pkg/output"
+	"github.com/gwkline/artestian/pkg/usage"
+	"github.com/gwkline/artestian/pkg/workspace"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// workspaceLanguage passes tests that contain "pass", and records the files in the
+// project while tests run
+type workspaceLanguage struct {
+	types.ILanguage
+	project    string
+	seen       []string
+	testPaths  []string
+	runnerDirs []string
+}
+
+func (l *workspaceLanguage) GetFunctions(sourceCode string) ([]types.Function, error) {
+	return []types.Function{{Name: "Add"}, {Name: "Sub"}}, nil
+}
+
+func (l *workspaceLanguage) GetTestFilePattern() string {
+	return "_test.go"
+}
+
+func (l *workspaceLanguage) MergeTests(tests []string) (string, error) {
+	return strings.Join(tests, "\n") + "\n", nil
+}
+
+func (l *workspaceLanguage) CaptureBaseline(ctx context.Context, sourcePath string) error {
+	return nil
+}
+
+func (l *workspaceLanguage) CheckTypes(ctx context.Context, testFilePath string) (types.RunResult, error) {
+	return types.RunResult{Passed: true}, nil
+}
+
+func (l *workspaceLanguage) GetTestRunner() types.ITestRunner {
+	return l
+}
+
+func (l *workspaceLanguage) GetName() string {
+	return "fake"
+}
+
+func (l *workspaceLanguage) RunTests(ctx context.Context, rootDir, testFilePath string) (types.RunResult, error) {
+	entries, err := os.ReadDir(filepath.Join(l.project, "pkg"))
+	if err != nil {
+		return types.RunResult{}, err
+	}
+	for _, entry := range entries {
+		l.seen = append(l.seen, entry.Name())
+	}
+	l.testPaths = append(l.testPaths, testFilePath)
+	l.runnerDirs = append(l.runnerDirs, rootDir)
+
+	content, err := os.ReadFile(testFilePath)
+	if err != nil {
+		return types.RunResult{}, err
+	}
+	return types.RunResult{Passed: strings.Contains(string(content), "pass")}, nil
+}
+
+func (l *workspaceLanguage) RerunTests(ctx context.Context, rootDir, testFilePath string, options types.RerunOptions) (types.RunResult, error) {
+	return l.RunTests(ctx, rootDir, testFilePath)
+}
+
+// functionAgent writes a passing test for Add and a failing one for everything else
+type functionAgent struct {
+	types.IAgent
+}
+
+func (a *functionAgent) GenerateTest(ctx context.Context, params types.GenerateTestParams) (string, error) {
+	if params.Function.Name == "Add" {
+		return "pass " + params.Function.Name, nil
+	}
+	return "fail " + params.Function.Name, nil
+}
+
+func (a *functionAgent) FixTestFailures(ctx context.Context, params types.IterateTestParams) (string, error) {
+	return params.TestCode, nil
+}
+
+type singleFileFinder struct {
+	path string
+}
+
+func (f *singleFileFinder) FindNextFile(cfg types.IConfig) (string, error) {
+	return f.path, nil
+}
+
+func (f *singleFileFinder) GetTestPath(sourcePath string) string {
+	return strings.TrimSuffix(sourcePath, ".go") + "_test.go"
+}
+
+type rootConfig struct {
+	types.IConfig
+	root string
+}
+
+func (c *rootConfig) GetRootDir() string {
+	return c.root
+}
+
+func (c *rootConfig) GetExampleSelection() types.ExampleSelection {
+	return types.ExampleSelectionLocal
+}
+
+func (c *rootConfig) GetExamplesPerPrompt() int {
+	return 1
+}
+
+func TestGenerateNextTest_Workspace(t *testing.T) {
+	project := t.TempDir()
+	sourcePath := filepath.Join(project, "pkg", "math.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(sourcePath), 0755))
+	require.NoError(t, os.WriteFile(sourcePath, []byte("package pkg\n"), 0644))
+
+	ws, err := workspace.New(project)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	language := &workspaceLanguage{project: project}
+	g := NewTestGenerator(&singleFileFinder{path: sourcePath}, &functionAgent{}, language, nil, nil, usage.NewTracker(nil, usage.Limits{}))
+	g.SetWorkspace(ws)
+	g.SetKeepFailed(true)
+
+	require.NoError(t, g.GenerateNextTest(context.Background(), project, &rootConfig{root: project}))
+
+	// Tests only ever ran in the workspace, and the project only ever held the source file
+	assert.Equal(t, []string{"math.go"}, unique(language.seen))
+	for i, testPath := range language.testPaths {
+		assert.True(t, strings.HasPrefix(testPath, ws.Dir()), testPath)
+		assert.Equal(t, ws.Dir(), language.runnerDirs[i])
+	}
+
+	// Only the passing test is written to the project
+	content, err := os.ReadFile(filepath.Join(project, "pkg", "math_test.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "pass Add\n", string(content))
+	entries, err := os.ReadDir(filepath.Join(project, "pkg"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// The failing test is kept outside the workspace
+	kept, err := filepath.Glob(filepath.Join(filepath.Dir(ws.Dir()), "failed", "pkg", "Sub*_test.go"))
+	require.NoError(t, err)
+	require.Len(t, kept, 1)
+	content, err = os.ReadFile(kept[0])
+	require.NoError(t, err)
+	assert.Equal(t, "fail Sub", string(content))
+}
+
+func TestGenerateNextTest_WorkspaceAboveProject(t *testing.T) {
+	repo := t.TempDir()
+	project := filepath.Join(repo, "services", "api")
+	sourcePath := filepath.Join(project, "pkg", "math.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(sourcePath), 0755))
+	require.NoError(t, os.WriteFile(sourcePath, []byte("package pkg\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "go.work"), []byte("go 1.23\n"), 0644))
+
+	ws, err := workspace.New(repo)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	language := &workspaceLanguage{project: project}
+	g := NewTestGenerator(&singleFileFinder{path: sourcePath}, &functionAgent{}, language, nil, nil, usage.NewTracker(nil, usage.Limits{}))
+	g.SetWorkspace(ws)
+
+	require.NoError(t, g.GenerateNextTest(context.Background(), project, &rootConfig{root: project}))
+
+	// Tests run from the project's copy, with the rest of the repository around it
+	workspaceProject := filepath.Join(ws.Dir(), "services", "api")
+	require.NotEmpty(t, language.runnerDirs)
+	for _, dir := range language.runnerDirs {
+		assert.Equal(t, workspaceProject, dir)
+	}
+	assert.FileExists(t, filepath.Join(ws.Dir(), "go.work"))
+	assert.Equal(t, "pass Add\n", readTestFile(t, filepath.Join(project, "pkg", "math_test.go")))
+}
+
+func TestGenerateNextTest_OutputSink(t *testing.T) {
+	project := t.TempDir()
+	sourcePath := filepath.Join(project, "pkg", "math.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(sourcePath), 0755))
+	require.NoError(t, os.WriteFile(sourcePath, []byte("package pkg\n"), 0644))
+
+	ws, err := workspace.New(project)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var patch bytes.Buffer
+	sink := output.NewPatchSink(project, &patch)
+	g := NewTestGenerator(&singleFileFinder{path: sourcePath}, &functionAgent{}, &workspaceLanguage{project: project}, nil, nil, usage.NewTracker(nil, usage.Limits{}))
+	g.SetWorkspace(ws)
+	g.SetOutputSink(sink)
+
+	require.NoError(t, g.GenerateNextTest(context.Background(), project, &rootConfig{root: project}))
+	require.NoError(t, sink.Close())
+
+	assert.NoFileExists(t, filepath.Join(project, "pkg", "math_test.go"))
+	assert.Contains(t, patch.String(), "+++ b/pkg/math_test.go\n@@ -0,0 +1 @@\n+pass Add\n")
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func unique(names []string) []string {
+	var result []string
+	for _, name := range names {
+		if !containsString(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func containsString(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
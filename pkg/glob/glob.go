@@ -0,0 +1,78 @@
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether a slash-separated path matches pattern. Each segment of the
+// pattern is matched with path.Match, and a "**" segment matches any number of segments,
+// including none. A pattern ending in "/" matches everything below that directory.
+func Match(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	name = strings.TrimPrefix(path.Clean(name), "./")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Valid reports whether pattern is well formed
+func Valid(pattern string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Split separates pattern into its leading directory without wildcards and the rest of
+// the pattern, which is empty when the pattern has no wildcards. For example
+// "./types/**/*.go" splits into "./types" and "**/*.go".
+func Split(pattern string) (string, string) {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, `*?[\`) {
+			dir := strings.Join(segments[:i], "/")
+			if dir == "" && i > 0 {
+				dir = "/"
+			} else if dir == "" {
+				dir = "."
+			}
+			return dir, strings.Join(segments[i:], "/")
+		}
+	}
+	return pattern, ""
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse repeated ** and try every possible split
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
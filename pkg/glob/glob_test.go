@@ -0,0 +1,62 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "internal/workers/**", name: "internal/workers/queue.go", expected: true},
+		{pattern: "internal/workers/**", name: "internal/workers/email/send.go", expected: true},
+		{pattern: "internal/workers/**", name: "internal/api/handler.go", expected: false},
+		{pattern: "internal/workers/", name: "internal/workers/queue.go", expected: true},
+		{pattern: "./src/*.ts", name: "src/user.ts", expected: true},
+		{pattern: "src/*.ts", name: "src/models/user.ts", expected: false},
+		{pattern: "**/*_handler.go", name: "handler.go", expected: false},
+		{pattern: "**/*_handler.go", name: "api/user_handler.go", expected: true},
+		{pattern: "**/*_handler.go", name: "user_handler.go", expected: true},
+		{pattern: "src/**/models/*.ts", name: "src/models/user.ts", expected: true},
+		{pattern: "src/**/models/*.ts", name: "src/a/b/models/user.ts", expected: true},
+		{pattern: "src/[", name: "src/a", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Match(tt.pattern, tt.name))
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("internal/**/*.go"))
+	assert.False(t, Valid("internal/[.go"))
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		pattern string
+		dir     string
+		rest    string
+	}{
+		{pattern: "./types/**/*.go", dir: "./types", rest: "**/*.go"},
+		{pattern: "types/user.go", dir: "types/user.go", rest: ""},
+		{pattern: "types/", dir: "types/", rest: ""},
+		{pattern: "*.ts", dir: ".", rest: "*.ts"},
+		{pattern: "/repo/src/[ab].ts", dir: "/repo/src", rest: "[ab].ts"},
+		{pattern: "/*.ts", dir: "/", rest: "*.ts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			dir, rest := Split(tt.pattern)
+			assert.Equal(t, tt.dir, dir)
+			assert.Equal(t, tt.rest, rest)
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package golang
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// maxContextDepth limits how far type declarations are followed from the function, e.g.
+// a struct parameter (1) and the types of its fields (2)
+const maxContextDepth = 2
+
+// ResolveContext collects the declarations of the types, interfaces and constants a
+// function references, from sibling files of its package and from other packages in its
+// module. Declarations in the source file itself are left out, since they are already in
+// the prompt.
+func (g *GoSupport) ResolveContext(ctx context.Context, sourcePath string, function types.Function) ([]types.ContextFile, error) {
+	sourcePath, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, err := g.loadPackage(ctx, sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	funcDecl := findFuncDecl(pkg, sourcePath, function)
+	if funcDecl == nil {
+		return nil, fmt.Errorf("function %s not found in %s", function.Name, sourcePath)
+	}
+
+	r := &contextResolver{
+		pkg:        pkg,
+		sourcePath: sourcePath,
+		seen:       make(map[gotypes.Object]bool),
+		files:      make(map[string]*parsedFile),
+		decls:      make(map[string]bool),
+	}
+	r.collectUses(funcDecl, 1)
+
+	var contextFiles []types.ContextFile
+	for _, file := range r.order {
+		contextFiles = append(contextFiles, types.ContextFile{
+			Path:        file.importPath + "/" + filepath.Base(file.path),
+			Content:     "package " + file.pkgName + "\n\n" + strings.Join(file.declarations, "\n\n"),
+			Description: "Declarations referenced by " + function.Name,
+			Type:        "declarations",
+			Discovered:  true,
+		})
+	}
+	return contextFiles, nil
+}
+
+// loadPackage type-checks the package containing sourcePath. The last package is cached,
+// since every function in a file resolves against the same package.
+func (g *GoSupport) loadPackage(ctx context.Context, sourcePath string) (*packages.Package, error) {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	key := sourcePath + "@" + info.ModTime().String()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.loaded != nil && g.loadedKey == key {
+		return g.loaded, nil
+	}
+
+	// Dependencies are type-checked from source rather than export data, which only
+	// works when the go command and x/tools agree on the export data format
+	cfg := &packages.Config{
+		Context:    ctx,
+		Mode:       packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps | packages.NeedModule,
+		Dir:        filepath.Dir(sourcePath),
+		BuildFlags: g.options.buildFlags(),
+	}
+	pkgs, err := packages.Load(cfg, "file="+sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package for %s: %w", sourcePath, err)
+	}
+	if len(pkgs) == 0 || pkgs[0].TypesInfo == nil {
+		return nil, fmt.Errorf("no package found for %s", sourcePath)
+	}
+
+	g.loaded, g.loadedKey = pkgs[0], key
+	return pkgs[0], nil
+}
+
+func findFuncDecl(pkg *packages.Package, sourcePath string, function types.Function) *ast.FuncDecl {
+	for _, file := range pkg.Syntax {
+		if pkg.Fset.Position(file.Pos()).Filename != sourcePath {
+			continue
+		}
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Name.Name != function.Name {
+				continue
+			}
+			receiver := ""
+			if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+				receiver = receiverName(funcDecl.Recv.List[0].Type)
+			}
+			if receiver == function.Receiver {
+				return funcDecl
+			}
+		}
+	}
+	return nil
+}
+
+type contextResolver struct {
+	pkg        *packages.Package
+	sourcePath string
+	seen       map[gotypes.Object]bool
+	files      map[string]*parsedFile
+	order      []*parsedFile   // Files in the order their first declaration was found
+	decls      map[string]bool // Declarations already added, by file and offset
+}
+
+// parsedFile is a file declaring referenced objects, parsed with comments
+type parsedFile struct {
+	path         string
+	pkgName      string
+	importPath   string
+	fset         *token.FileSet
+	file         *ast.File
+	src          []byte
+	declarations []string
+}
+
+// collectUses adds the declarations of the package-level types and constants used in node
+func (r *contextResolver) collectUses(node ast.Node, depth int) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if obj := r.pkg.TypesInfo.Uses[ident]; obj != nil {
+				r.addObject(obj, depth)
+			}
+		}
+		return true
+	})
+}
+
+func (r *contextResolver) addObject(obj gotypes.Object, depth int) {
+	if r.seen[obj] || !r.inModule(obj) {
+		return
+	}
+	switch obj.(type) {
+	case *gotypes.TypeName, *gotypes.Const:
+	default:
+		return
+	}
+	if obj.Parent() != obj.Pkg().Scope() {
+		return
+	}
+	r.seen[obj] = true
+
+	position := r.pkg.Fset.Position(obj.Pos())
+	if position.Filename != r.sourcePath {
+		r.addDeclaration(obj, position)
+	}
+
+	typeName, ok := obj.(*gotypes.TypeName)
+	if !ok {
+		return
+	}
+
+	// Constants of a named type are its enum values
+	scope := obj.Pkg().Scope()
+	for _, name := range scope.Names() {
+		if c, ok := scope.Lookup(name).(*gotypes.Const); ok && gotypes.Identical(c.Type(), typeName.Type()) {
+			r.addObject(c, depth)
+		}
+	}
+
+	if depth < maxContextDepth {
+		r.addTypes(typeName.Type().Underlying(), depth+1, make(map[gotypes.Type]bool))
+	}
+}
+
+// addTypes adds the named types that make up t, like struct field and method parameter types
+func (r *contextResolver) addTypes(t gotypes.Type, depth int, visited map[gotypes.Type]bool) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+
+	switch t := t.(type) {
+	case *gotypes.Named:
+		r.addObject(t.Obj(), depth)
+	case *gotypes.Alias:
+		r.addObject(t.Obj(), depth)
+	case *gotypes.Pointer:
+		r.addTypes(t.Elem(), depth, visited)
+	case *gotypes.Slice:
+		r.addTypes(t.Elem(), depth, visited)
+	case *gotypes.Array:
+		r.addTypes(t.Elem(), depth, visited)
+	case *gotypes.Chan:
+		r.addTypes(t.Elem(), depth, visited)
+	case *gotypes.Map:
+		r.addTypes(t.Key(), depth, visited)
+		r.addTypes(t.Elem(), depth, visited)
+	case *gotypes.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			r.addTypes(t.Field(i).Type(), depth, visited)
+		}
+	case *gotypes.Interface:
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			r.addTypes(t.ExplicitMethod(i).Type(), depth, visited)
+		}
+	case *gotypes.Signature:
+		for i := 0; i < t.Params().Len(); i++ {
+			r.addTypes(t.Params().At(i).Type(), depth, visited)
+		}
+		for i := 0; i < t.Results().Len(); i++ {
+			r.addTypes(t.Results().At(i).Type(), depth, visited)
+		}
+	}
+}
+
+// inModule reports whether obj is declared in the module of the loaded package, leaving
+// out the standard library and third-party dependencies
+func (r *contextResolver) inModule(obj gotypes.Object) bool {
+	if obj.Pkg() == nil {
+		return false
+	}
+	if r.pkg.Module == nil {
+		return obj.Pkg().Path() == r.pkg.PkgPath
+	}
+	modulePath := r.pkg.Module.Path
+	return obj.Pkg().Path() == modulePath || strings.HasPrefix(obj.Pkg().Path(), modulePath+"/")
+}
+
+// addDeclaration adds the source of the declaration of obj, found by name and line
+func (r *contextResolver) addDeclaration(obj gotypes.Object, position token.Position) {
+	file := r.parse(position.Filename, obj.Pkg())
+	if file == nil {
+		return
+	}
+
+	for _, decl := range file.file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			if !declares(file.fset, spec, obj.Name(), position.Line) {
+				continue
+			}
+
+			// Grouped types are pulled out of their group, while grouped constants are kept
+			// together since their values may depend on iota
+			from, to := genDecl.Pos(), genDecl.End()
+			doc := genDecl.Doc
+			grouped := genDecl.Lparen.IsValid() && genDecl.Tok == token.TYPE
+			if grouped {
+				from, to = spec.Pos(), spec.End()
+				doc = spec.(*ast.TypeSpec).Doc
+			}
+
+			key := fmt.Sprintf("%s:%d", file.path, from)
+			if r.decls[key] {
+				return
+			}
+			r.decls[key] = true
+
+			source := file.text(from, to)
+			if grouped {
+				source = "type " + source
+			}
+			if doc != nil {
+				source = file.text(doc.Pos(), doc.End()) + "\n" + source
+			}
+			if grouped {
+				source = strings.ReplaceAll(source, "\n\t", "\n")
+			}
+
+			if len(file.declarations) == 0 {
+				r.order = append(r.order, file)
+			}
+			file.declarations = append(file.declarations, source)
+			return
+		}
+	}
+}
+
+// declares reports whether spec declares name on the given line
+func declares(fset *token.FileSet, spec ast.Spec, name string, line int) bool {
+	var idents []*ast.Ident
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		idents = []*ast.Ident{spec.Name}
+	case *ast.ValueSpec:
+		idents = spec.Names
+	}
+	for _, ident := range idents {
+		if ident.Name == name && fset.Position(ident.Pos()).Line == line {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *parsedFile) text(from, to token.Pos) string {
+	return string(f.src[f.fset.Position(from).Offset:f.fset.Position(to).Offset])
+}
+
+func (r *contextResolver) parse(filename string, pkg *gotypes.Package) *parsedFile {
+	if file, ok := r.files[filename]; ok {
+		return file
+	}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		r.files[filename] = nil
+		return nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		r.files[filename] = nil
+		return nil
+	}
+
+	parsed := &parsedFile{
+		path:       filename,
+		pkgName:    pkg.Name(),
+		importPath: path.Clean(pkg.Path()),
+		fset:       fset,
+		file:       file,
+		src:        src,
+	}
+	r.files[filename] = parsed
+	return parsed
+}
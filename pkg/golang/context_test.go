@@ -0,0 +1,108 @@
+package golang
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gwkline/artestian/types"
+)
+
+func TestGoSupport_ResolveContext(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"model/user.go": `package model
+
+// User is a customer account
+type User struct {
+	Name    string
+	Role    Role
+	Address Address
+}
+
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleCustomer Role = "customer"
+)
+
+type (
+	// Address is where orders are shipped
+	Address struct {
+		City string
+	}
+
+	Unused struct{}
+)
+`,
+		"orders/notify.go": `package orders
+
+// Notifier sends messages to users
+type Notifier interface {
+	Notify(message string) error
+}
+`,
+		"orders/orders.go": `package orders
+
+import (
+	"strings"
+
+	"example.com/shop/model"
+)
+
+const greeting = "Hello"
+
+type local struct{}
+
+func Greet(n Notifier, u model.User) error {
+	_ = local{}
+	return n.Notify(strings.Join([]string{greeting, u.Name}, " "))
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	g := NewGoSupport()
+	contextFiles, err := g.ResolveContext(context.Background(), filepath.Join(root, "orders", "orders.go"), types.Function{Name: "Greet"})
+	assert.NoError(t, err)
+	assert.Len(t, contextFiles, 2)
+
+	byPath := make(map[string]string)
+	for _, file := range contextFiles {
+		assert.Equal(t, "declarations", file.Type)
+		assert.True(t, file.Discovered)
+		byPath[file.Path] = file.Content
+	}
+
+	assert.Contains(t, byPath["example.com/shop/orders/notify.go"], "type Notifier interface")
+
+	model := byPath["example.com/shop/model/user.go"]
+	assert.Contains(t, model, "package model")
+	assert.Contains(t, model, "// User is a customer account\ntype User struct")
+	assert.Contains(t, model, "type Role string")
+	assert.Contains(t, model, `RoleAdmin    Role = "admin"`)
+	assert.Contains(t, model, "// Address is where orders are shipped\ntype Address struct")
+	assert.NotContains(t, model, "Unused")
+
+	// Declarations in the source file are already part of the prompt
+	for _, content := range byPath {
+		assert.NotContains(t, content, "greeting")
+		assert.NotContains(t, content, "type local")
+	}
+
+	_, err = g.ResolveContext(context.Background(), filepath.Join(root, "orders", "orders.go"), types.Function{Name: "Missing"})
+	assert.Error(t, err)
+}
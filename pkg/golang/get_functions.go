@@ -0,0 +1,170 @@
+package golang
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"sort"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+func (g *GoSupport) GetFunctions(sourceCode string) ([]types.Function, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", sourceCode, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	info := checkFile(fset, file)
+	lines := strings.Split(sourceCode, "\n")
+
+	var functions []types.Function
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		// Get the function source code
+		startPos := fset.Position(funcDecl.Pos())
+		endPos := fset.Position(funcDecl.End())
+		funcSource := strings.Join(lines[startPos.Line-1:endPos.Line], "\n")
+
+		function := types.Function{
+			Name:       funcDecl.Name.Name,
+			SourceCode: funcSource,
+			IsExported: funcDecl.Name.IsExported(),
+			Params:     fieldParams(funcDecl.Type.Params),
+			Results:    fieldParams(funcDecl.Type.Results),
+			Doc:        strings.TrimSpace(funcDecl.Doc.Text()),
+			StartLine:  startPos.Line,
+			EndLine:    endPos.Line,
+			References: references(funcDecl, info),
+		}
+		if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+			function.Receiver = receiverName(funcDecl.Recv.List[0].Type)
+		}
+		functions = append(functions, function)
+	}
+
+	return functions, nil
+}
+
+// checkFile type-checks a single file in isolation. Imports resolve to empty
+// packages and errors are ignored, so the result only describes what can be
+// resolved from the file itself.
+func checkFile(fset *token.FileSet, file *ast.File) *gotypes.Info {
+	info := &gotypes.Info{
+		Defs: make(map[*ast.Ident]gotypes.Object),
+		Uses: make(map[*ast.Ident]gotypes.Object),
+	}
+	conf := gotypes.Config{
+		Importer: emptyImporter{},
+		Error:    func(error) {},
+	}
+	conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+	return info
+}
+
+// emptyImporter satisfies imports with empty packages so type checking can
+// proceed without access to dependencies
+type emptyImporter struct{}
+
+func (emptyImporter) Import(path string) (*gotypes.Package, error) {
+	name := path[strings.LastIndex(path, "/")+1:]
+	pkg := gotypes.NewPackage(path, name)
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// receiverName returns the base type name of a method receiver, without pointer or type parameters
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return gotypes.ExprString(expr)
+		}
+	}
+}
+
+func fieldParams(fields *ast.FieldList) []types.Param {
+	if fields == nil {
+		return nil
+	}
+
+	var params []types.Param
+	for _, field := range fields.List {
+		typ := gotypes.ExprString(field.Type)
+		if len(field.Names) == 0 {
+			params = append(params, types.Param{Type: typ})
+			continue
+		}
+		for _, name := range field.Names {
+			params = append(params, types.Param{Name: name.Name, Type: typ})
+		}
+	}
+	return params
+}
+
+// references collects the package-level identifiers and imported names used by a function.
+// Identifiers that could not be resolved are assumed to be declared in sibling files.
+func references(funcDecl *ast.FuncDecl, info *gotypes.Info) []string {
+	seen := make(map[string]bool)
+	skip := make(map[*ast.Ident]bool)
+
+	ast.Inspect(funcDecl, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			skip[node.Sel] = true
+			if x, ok := node.X.(*ast.Ident); ok {
+				if pkgName, ok := info.Uses[x].(*gotypes.PkgName); ok {
+					seen[pkgName.Name()+"."+node.Sel.Name] = true
+					skip[x] = true
+				}
+			}
+		case *ast.KeyValueExpr:
+			// Struct literal field names
+			if key, ok := node.Key.(*ast.Ident); ok {
+				if _, resolved := info.Uses[key]; !resolved {
+					skip[key] = true
+				}
+			}
+		case *ast.Ident:
+			if skip[node] || node == funcDecl.Name || node.Name == "_" {
+				return true
+			}
+			if _, isDef := info.Defs[node]; isDef {
+				return true
+			}
+			obj, resolved := info.Uses[node]
+			if !resolved {
+				seen[node.Name] = true
+				return true
+			}
+			if obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope() {
+				seen[node.Name] = true
+			}
+		}
+		return true
+	})
+
+	refs := make([]string, 0, len(seen))
+	for name := range seen {
+		refs = append(refs, name)
+	}
+	sort.Strings(refs)
+	return refs
+}
@@ -1,44 +1,121 @@
 package golang
 
 import (
-	"go/ast"
-	"go/parser"
-	"go/token"
-	"os/exec"
+	"context"
+	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"golang.org/x/tools/go/packages"
+
+	"github.com/gwkline/artestian/pkg/command"
+	"github.com/gwkline/artestian/pkg/sandbox"
 	"github.com/gwkline/artestian/types"
 )
 
-type GoTestRunner struct{}
+// Options configures how go vet and go test are invoked
+type Options struct {
+	Tags    []string         // Build tags passed to -tags
+	Race    bool             // Run tests with the race detector
+	Timeout time.Duration    // Passed to go test -timeout, zero uses the go default
+	Sandbox *sandbox.Sandbox // Isolates go test and go vet, nil runs them directly
+}
+
+func (o Options) buildFlags() []string {
+	if len(o.Tags) == 0 {
+		return nil
+	}
+	return []string{"-tags", strings.Join(o.Tags, ",")}
+}
 
-func (r *GoTestRunner) RunTests(rootDir, testFilePath string) (bool, string, error) {
-	dir := filepath.Dir(rootDir)
+type GoTestRunner struct {
+	options Options
+}
 
-	cmd := exec.Command("go", "test", testFilePath)
-	cmd.Dir = dir
+// RunTests runs only the tests declared in testFilePath, as part of the package
+// that contains it so package-internal symbols resolve
+func (r *GoTestRunner) RunTests(ctx context.Context, rootDir, testFilePath string) (types.RunResult, error) {
+	return r.run(ctx, testFilePath, types.RerunOptions{Runs: 1})
+}
 
-	output, err := cmd.CombinedOutput()
+// RerunTests runs the tests in testFilePath options.Runs times in a single go test
+// invocation with -count, so state leaking between runs is caught too
+func (r *GoTestRunner) RerunTests(ctx context.Context, rootDir, testFilePath string, options types.RerunOptions) (types.RunResult, error) {
+	return r.run(ctx, testFilePath, options)
+}
+
+func (r *GoTestRunner) run(ctx context.Context, testFilePath string, options types.RerunOptions) (types.RunResult, error) {
+	pkg, err := resolvePackage(ctx, filepath.Dir(testFilePath), r.options.Tags)
+	if err != nil {
+		return types.RunResult{}, err
+	}
+
+	names, err := testNames(testFilePath)
+	if err != nil {
+		// Syntax errors are reported back so they can be fixed
+		return parseVetOutput(err.Error()), nil
+	}
+	if len(names) == 0 {
+		return types.RunResult{Output: fmt.Sprintf("no test functions found in %s", filepath.Base(testFilePath))}, nil
+	}
+
+	args := []string{"test", "-json", fmt.Sprintf("-count=%d", max(1, options.Runs)), "-run", runPattern(names)}
+	args = append(args, r.options.buildFlags()...)
+	if r.options.Race || options.Race {
+		args = append(args, "-race")
+	}
+	if options.Shuffle {
+		args = append(args, "-shuffle=on")
+	}
+	if r.options.Timeout > 0 {
+		args = append(args, "-timeout", r.options.Timeout.String())
+	}
+	args = append(args, packageTarget(pkg))
+
+	ctx, cancel := r.options.Sandbox.WithTimeout(ctx)
+	defer cancel()
+	cmd, err := r.options.Sandbox.Command(ctx, filepath.Dir(testFilePath), pkg.moduleDir(), "go", args...)
 	if err != nil {
-		// Go test returns non-zero exit code on test failures
-		return false, string(output), nil
+		return types.RunResult{}, err
 	}
-	return true, string(output), nil
+
+	output, err := cmd.CombinedOutput()
+	if err := command.Canceled(ctx); err != nil {
+		return types.RunResult{}, err
+	}
+
+	result := parseTestJSON(r.options.Sandbox.RealPaths(string(output)))
+	// Go test returns non-zero exit code on test failures
+	result.Passed = err == nil
+	result.TimedOut = result.TimedOut || command.TimedOut(ctx)
+	return result, nil
 }
 
 func (r *GoTestRunner) GetName() string {
 	return "go test"
 }
 
-type GoSupport struct{}
+type GoSupport struct {
+	options Options
+
+	mu        sync.Mutex
+	loaded    *packages.Package // Last package loaded by ResolveContext
+	loadedKey string
+	baselines map[string]map[string]bool // go vet findings in each package directory before tests were generated
+}
 
 func NewGoSupport() *GoSupport {
-	return &GoSupport{}
+	return NewGoSupportWithOptions(Options{})
+}
+
+func NewGoSupportWithOptions(options Options) *GoSupport {
+	return &GoSupport{options: options, baselines: make(map[string]map[string]bool)}
 }
 
 func (g *GoSupport) GetTestRunner() types.ITestRunner {
-	return &GoTestRunner{}
+	return &GoTestRunner{options: g.options}
 }
 
 func (g *GoSupport) GetFileExtension() string {
@@ -49,50 +126,107 @@ func (g *GoSupport) GetTestFilePattern() string {
 	return "_test.go"
 }
 
-func (g *GoSupport) CheckTypes(testFilePath string) (bool, string, error) {
-	// Get the directory of the test file
-	dir := filepath.Dir(testFilePath)
+// CheckTypes vets the package containing testFilePath, including its internal
+// and external test files. go vet reports findings for the whole package, so those in
+// other files that were already in the baseline for the package don't count.
+func (g *GoSupport) CheckTypes(ctx context.Context, testFilePath string) (types.RunResult, error) {
+	ctx, cancel := g.options.Sandbox.WithTimeout(ctx)
+	defer cancel()
+
+	result, failed, err := g.vet(ctx, testFilePath)
+	if err != nil {
+		return types.RunResult{}, err
+	}
+	if command.TimedOut(ctx) {
+		result.TimedOut = true
+		return result, nil
+	}
 
-	cmd := exec.Command("go", "vet")
-	cmd.Dir = dir
+	g.mu.Lock()
+	baseline := g.baselines[filepath.Dir(testFilePath)]
+	g.mu.Unlock()
 
-	output, err := cmd.CombinedOutput()
+	reported := len(result.Diagnostics) > 0
+	result.Diagnostics = filterDiagnostics(result.Diagnostics, testFilePath, baseline)
+	// A failure without findings, e.g. go missing, can't be explained by the baseline
+	result.Passed = !failed || reported && len(result.Diagnostics) == 0
+	return result, nil
+}
+
+// CaptureBaseline vets the package of a source file before tests are generated for it
+// and remembers its findings, so problems already in the package aren't blamed on
+// generated tests
+func (g *GoSupport) CaptureBaseline(ctx context.Context, sourcePath string) error {
+	result, _, err := g.vet(ctx, sourcePath)
 	if err != nil {
-		return false, string(output), nil
+		return err
+	}
+
+	baseline := make(map[string]bool, len(result.Diagnostics))
+	for _, d := range result.Diagnostics {
+		baseline[diagnosticKey(d)] = true
 	}
-	return true, string(output), nil
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.baselines[filepath.Dir(sourcePath)] = baseline
+	return nil
 }
 
-func (g *GoSupport) GetName() string {
-	return "go"
+// vet runs go vet on the package containing path. It returns the findings with
+// absolute paths, and whether go vet failed.
+func (g *GoSupport) vet(ctx context.Context, path string) (types.RunResult, bool, error) {
+	pkg, err := resolvePackage(ctx, filepath.Dir(path), g.options.Tags)
+	if err != nil {
+		return types.RunResult{}, false, err
+	}
+
+	args := append([]string{"vet"}, g.options.buildFlags()...)
+	args = append(args, packageTarget(pkg))
+
+	cmd, err := g.options.Sandbox.Command(ctx, filepath.Dir(path), pkg.moduleDir(), "go", args...)
+	if err != nil {
+		return types.RunResult{}, false, err
+	}
+
+	output, runErr := cmd.CombinedOutput()
+	if err := command.Canceled(ctx); err != nil {
+		return types.RunResult{}, false, err
+	}
+
+	result := parseVetOutput(g.options.Sandbox.RealPaths(string(output)))
+	for i, d := range result.Diagnostics {
+		if !filepath.IsAbs(d.File) {
+			result.Diagnostics[i].File = filepath.Join(pkg.moduleDir(), d.File)
+		}
+	}
+	return result, runErr != nil, nil
 }
 
-func (g *GoSupport) GetFunctions(sourceCode string) ([]types.Function, error) {
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "", sourceCode, parser.ParseComments)
+// filterDiagnostics keeps every diagnostic in the test file, and those elsewhere that
+// aren't in the baseline
+func filterDiagnostics(diagnostics []types.Diagnostic, testFilePath string, baseline map[string]bool) []types.Diagnostic {
+	absPath, err := filepath.Abs(testFilePath)
 	if err != nil {
-		return nil, err
-	}
-
-	var functions []types.Function
-	ast.Inspect(file, func(n ast.Node) bool {
-		if funcDecl, ok := n.(*ast.FuncDecl); ok {
-			// Get the function source code
-			startPos := fset.Position(funcDecl.Pos())
-			endPos := fset.Position(funcDecl.End())
-			lines := strings.Split(sourceCode, "\n")
-			funcSource := strings.Join(lines[startPos.Line-1:endPos.Line], "\n")
-
-			// Create Function struct
-			function := types.Function{
-				Name:       funcDecl.Name.Name,
-				SourceCode: funcSource,
-				IsExported: funcDecl.Name.IsExported(),
-			}
-			functions = append(functions, function)
+		absPath = testFilePath
+	}
+
+	var filtered []types.Diagnostic
+	for _, d := range diagnostics {
+		if filepath.Clean(d.File) != absPath && baseline[diagnosticKey(d)] {
+			continue
 		}
-		return true
-	})
+		filtered = append(filtered, d)
+	}
+	return filtered
+}
 
-	return functions, nil
+// diagnosticKey identifies a finding by its file and message, so it still matches when
+// lines above it changed
+func diagnosticKey(d types.Diagnostic) string {
+	return filepath.Clean(d.File) + ": " + d.Message
+}
+
+func (g *GoSupport) GetName() string {
+	return "go"
 }
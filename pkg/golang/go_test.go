@@ -247,3 +247,48 @@ func GetConfig() struct{
 		})
 	}
 }
+
+func TestGoSupport_GetFunctions_Metadata(t *testing.T) {
+	g := NewGoSupport()
+
+	input := `package config
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const maxRetries = 3
+
+type Config struct{ Name string }
+
+// validate checks the config.
+func (c *Config) validate(client *http.Client, n int) (bool, error) {
+	for i := 0; i < maxRetries; i++ {
+		fmt.Println(Other{Field: c.Name}, helper(i), len(c.Name))
+	}
+	return true, nil
+}
+
+func validate() {}
+`
+
+	functions, err := g.GetFunctions(input)
+	assert.NoError(t, err)
+	assert.Len(t, functions, 2)
+
+	method := functions[0]
+	assert.Equal(t, "validate", method.Name)
+	assert.Equal(t, "Config", method.Receiver)
+	assert.Equal(t, "validate checks the config.", method.Doc)
+	assert.Equal(t, []types.Param{{Name: "client", Type: "*http.Client"}, {Name: "n", Type: "int"}}, method.Params)
+	assert.Equal(t, []types.Param{{Type: "bool"}, {Type: "error"}}, method.Results)
+	assert.Equal(t, 13, method.StartLine)
+	assert.Equal(t, 18, method.EndLine)
+	assert.Equal(t, []string{"Config", "Other", "fmt.Println", "helper", "http.Client", "maxRetries"}, method.References)
+
+	free := functions[1]
+	assert.Equal(t, "validate", free.Name)
+	assert.Equal(t, "", free.Receiver)
+	assert.Equal(t, 20, free.StartLine)
+}
@@ -0,0 +1,90 @@
+package golang
+
+import (
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// MergeTests combines test files generated separately into one file, with the first
+// file's package clause and the imports of every file listed once. Everything after the
+// imports is kept as it is, in order.
+func (g *GoSupport) MergeTests(tests []string) (string, error) {
+	var header, pkg string
+	var imports []string
+	seen := make(map[string]bool)
+	var bodies []string
+
+	for i, test := range tests {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "", test, parser.ParseComments|parser.ImportsOnly)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse test %d: %w", i+1, err)
+		}
+
+		if i == 0 {
+			pkg = file.Name.Name
+			// Build constraints and file comments come before the package clause
+			header = test[:fset.Position(file.Package).Offset]
+		} else if file.Name.Name != pkg {
+			return "", fmt.Errorf("tests are in different packages: %s and %s", pkg, file.Name.Name)
+		}
+
+		end := file.Name.End()
+		for _, spec := range file.Imports {
+			var name string
+			if spec.Name != nil {
+				name = spec.Name.Name + " "
+			}
+			if line := name + spec.Path.Value; !seen[line] {
+				seen[line] = true
+				imports = append(imports, line)
+			}
+		}
+		// With ImportsOnly the declarations are exactly the import declarations
+		for _, decl := range file.Decls {
+			end = decl.End()
+		}
+		bodies = append(bodies, strings.TrimSpace(test[fset.Position(end).Offset:]))
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	fmt.Fprintf(&b, "package %s\n", pkg)
+	if len(imports) > 0 {
+		// The standard library first, like goimports
+		var std, other []string
+		for _, line := range imports {
+			path := strings.Trim(line[strings.Index(line, `"`):], `"`)
+			if strings.Contains(strings.Split(path, "/")[0], ".") {
+				other = append(other, line)
+			} else {
+				std = append(std, line)
+			}
+		}
+		b.WriteString("\nimport (\n")
+		for _, line := range std {
+			fmt.Fprintf(&b, "\t%s\n", line)
+		}
+		if len(std) > 0 && len(other) > 0 {
+			b.WriteString("\n")
+		}
+		for _, line := range other {
+			fmt.Fprintf(&b, "\t%s\n", line)
+		}
+		b.WriteString(")\n")
+	}
+	for _, body := range bodies {
+		if body != "" {
+			fmt.Fprintf(&b, "\n%s\n", body)
+		}
+	}
+
+	merged, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format merged tests: %w", err)
+	}
+	return string(merged), nil
+}
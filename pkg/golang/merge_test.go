@@ -0,0 +1,54 @@
+package golang
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeTests(t *testing.T) {
+	tests := []struct {
+		name     string
+		tests    []string
+		expected string
+		err      string
+	}{
+		{
+			name: "imports are listed once",
+			tests: []string{
+				"//go:build integration\n\npackage math\n\nimport (\n\t\"testing\"\n\n\t\"github.com/stretchr/testify/assert\"\n)\n\n// TestAdd checks Add\nfunc TestAdd(t *testing.T) {\n\tassert.Equal(t, 2, Add(1, 1))\n}\n",
+				"package math\n\nimport (\n\t\"strings\"\n\t\"testing\"\n\n\tr \"github.com/stretchr/testify/require\"\n)\n\nfunc TestJoin(t *testing.T) {\n\tr.Equal(t, \"a\", strings.TrimSpace(\" a \"))\n}\n",
+				"package math\n\nimport \"testing\"\n\nfunc TestSub(t *testing.T) {}\n",
+			},
+			expected: "//go:build integration\n\npackage math\n\nimport (\n\t\"strings\"\n\t\"testing\"\n\n\t\"github.com/stretchr/testify/assert\"\n\tr \"github.com/stretchr/testify/require\"\n)\n\n// TestAdd checks Add\nfunc TestAdd(t *testing.T) {\n\tassert.Equal(t, 2, Add(1, 1))\n}\n\nfunc TestJoin(t *testing.T) {\n\tr.Equal(t, \"a\", strings.TrimSpace(\" a \"))\n}\n\nfunc TestSub(t *testing.T) {}\n",
+		},
+		{
+			name:     "no imports",
+			tests:    []string{"package math\n\nfunc TestAdd(t *T) {}\n", "package math\n\nfunc TestSub(t *T) {}"},
+			expected: "package math\n\nfunc TestAdd(t *T) {}\n\nfunc TestSub(t *T) {}\n",
+		},
+		{
+			name:  "different packages",
+			tests: []string{"package math\n", "package math_test\n"},
+			err:   "different packages",
+		},
+		{
+			name:  "doesn't parse",
+			tests: []string{"package math\n", "func TestAdd() {}\n"},
+			err:   "failed to parse test 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := NewGoSupport().MergeTests(tt.tests)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, merged)
+		})
+	}
+}
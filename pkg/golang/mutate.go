@@ -0,0 +1,188 @@
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// swappedOperators maps each operator to the one it's replaced with
+var swappedOperators = map[token.Token]token.Token{
+	token.EQL:  token.NEQ,
+	token.NEQ:  token.EQL,
+	token.LSS:  token.GEQ,
+	token.GEQ:  token.LSS,
+	token.GTR:  token.LEQ,
+	token.LEQ:  token.GTR,
+	token.ADD:  token.SUB,
+	token.SUB:  token.ADD,
+	token.MUL:  token.QUO,
+	token.QUO:  token.MUL,
+	token.LAND: token.LOR,
+	token.LOR:  token.LAND,
+}
+
+// nilTypes are the predeclared types whose zero value is nil
+var nilTypes = map[string]bool{"error": true, "any": true}
+
+// numericTypes are the predeclared types whose zero value is 0
+var numericTypes = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true, "byte": true, "rune": true,
+}
+
+// Mutate returns copies of the source with one change each to the body of function:
+// swapped operators, negated if conditions, changed constants and flipped booleans, and
+// returns replaced by zero values. Changes are made to the source text, so the rest of
+// the file keeps its formatting.
+func (g *GoSupport) Mutate(sourceCode string, function types.Function) ([]types.Mutant, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", sourceCode, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse source: %w", err)
+	}
+
+	var funcDecl *ast.FuncDecl
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != function.Name || fn.Body == nil {
+			continue
+		}
+		receiver := ""
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			receiver = receiverName(fn.Recv.List[0].Type)
+		}
+		if receiver == function.Receiver {
+			funcDecl = fn
+			break
+		}
+	}
+	if funcDecl == nil {
+		return nil, fmt.Errorf("function %s not found", function.Name)
+	}
+
+	m := &mutator{fset: fset, src: sourceCode, seen: make(map[string]bool)}
+	m.mutateBody(funcDecl.Body, funcDecl.Type.Results)
+	return m.mutants, nil
+}
+
+type mutator struct {
+	fset    *token.FileSet
+	src     string
+	mutants []types.Mutant
+	seen    map[string]bool // Mutated sources, since different mutations can make the same change
+}
+
+// mutateBody records mutants for the statements of a function body with the given results
+func (m *mutator) mutateBody(body *ast.BlockStmt, results *ast.FieldList) {
+	zeros := m.zeroValues(results)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Returns in closures return the closure's results
+			m.mutateBody(n.Body, n.Type.Results)
+			return false
+
+		case *ast.BinaryExpr:
+			if op, ok := swappedOperators[n.Op]; ok {
+				m.replace(n.OpPos, n.OpPos+token.Pos(len(n.Op.String())), op.String(), fmt.Sprintf("replaced %s with %s", n.Op, op))
+			}
+
+		case *ast.IfStmt:
+			cond := m.text(n.Cond.Pos(), n.Cond.End())
+			m.replace(n.Cond.Pos(), n.Cond.End(), "!("+cond+")", "negated if condition "+cond)
+
+		case *ast.BasicLit:
+			switch n.Kind {
+			case token.INT:
+				if value, err := strconv.ParseInt(n.Value, 0, 64); err == nil {
+					m.replace(n.Pos(), n.End(), strconv.FormatInt(value+1, 10), fmt.Sprintf("replaced %s with %d", n.Value, value+1))
+				}
+			case token.STRING:
+				if value, err := strconv.Unquote(n.Value); err == nil && value != "" {
+					m.replace(n.Pos(), n.End(), `""`, fmt.Sprintf("replaced %s with an empty string", n.Value))
+				}
+			}
+
+		case *ast.Ident:
+			switch n.Name {
+			case "true":
+				m.replace(n.Pos(), n.End(), "false", "replaced true with false")
+			case "false":
+				m.replace(n.Pos(), n.End(), "true", "replaced false with true")
+			}
+
+		case *ast.ReturnStmt:
+			if len(n.Results) > 0 && len(n.Results) == len(zeros) {
+				m.replace(n.Results[0].Pos(), n.Results[len(n.Results)-1].End(), strings.Join(zeros, ", "), "returned zero values")
+			}
+		}
+		return true
+	})
+}
+
+func (m *mutator) text(from, to token.Pos) string {
+	return m.src[m.fset.Position(from).Offset:m.fset.Position(to).Offset]
+}
+
+// replace records a mutant with the source between from and to replaced
+func (m *mutator) replace(from, to token.Pos, replacement, description string) {
+	start, end := m.fset.Position(from).Offset, m.fset.Position(to).Offset
+	mutated := m.src[:start] + replacement + m.src[end:]
+	if m.src[start:end] == replacement || m.seen[mutated] {
+		return
+	}
+	m.seen[mutated] = true
+	m.mutants = append(m.mutants, types.Mutant{
+		Description: description,
+		Line:        m.fset.Position(from).Line,
+		SourceCode:  mutated,
+	})
+}
+
+// zeroValues returns the zero value of each result, one per name for grouped results
+func (m *mutator) zeroValues(results *ast.FieldList) []string {
+	if results == nil {
+		return nil
+	}
+
+	var zeros []string
+	for _, field := range results.List {
+		zero := zeroValue(field.Type, m.text(field.Type.Pos(), field.Type.End()))
+		for i := 0; i < max(1, len(field.Names)); i++ {
+			zeros = append(zeros, zero)
+		}
+	}
+	return zeros
+}
+
+func zeroValue(typeExpr ast.Expr, typeText string) string {
+	switch t := typeExpr.(type) {
+	case *ast.Ident:
+		switch {
+		case t.Name == "bool":
+			return "false"
+		case t.Name == "string":
+			return `""`
+		case numericTypes[t.Name]:
+			return "0"
+		case nilTypes[t.Name]:
+			return "nil"
+		}
+	case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+		return "nil"
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "nil"
+		}
+	}
+	// Works for any type, including named types and type parameters
+	return "*new(" + typeText + ")"
+}
@@ -0,0 +1,111 @@
+package golang
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gwkline/artestian/types"
+)
+
+const mutateSource = `package shop
+
+type Discount struct{ Percent int }
+
+func Total(items []int, member bool) (int, *Discount, error) {
+	total := 0
+	for _, item := range items {
+		total += item
+	}
+	if member && total > 100 {
+		return total - 10, &Discount{Percent: 10}, nil
+	}
+	return total, nil, nil
+}
+
+func (c *Cart) Label() string {
+	format := func(n int) string {
+		return "items"
+	}
+	return format(len(c.items))
+}
+
+func Other() bool {
+	return true
+}
+`
+
+func TestGoSupport_Mutate(t *testing.T) {
+	tests := []struct {
+		name     string
+		function types.Function
+		expected []types.Mutant
+	}{
+		{
+			name:     "function",
+			function: types.Function{Name: "Total"},
+			expected: []types.Mutant{
+				{Description: "replaced 0 with 1", Line: 6, SourceCode: "total := 1"},
+				{Description: "negated if condition member && total > 100", Line: 10, SourceCode: "if !(member && total > 100) {"},
+				{Description: "replaced && with ||", Line: 10, SourceCode: "if member || total > 100 {"},
+				{Description: "replaced > with <=", Line: 10, SourceCode: "if member && total <= 100 {"},
+				{Description: "replaced 100 with 101", Line: 10, SourceCode: "if member && total > 101 {"},
+				{Description: "returned zero values", Line: 11, SourceCode: "return 0, nil, nil\n\t}"},
+				{Description: "replaced - with +", Line: 11, SourceCode: "return total + 10, &Discount"},
+				{Description: "replaced 10 with 11", Line: 11, SourceCode: "return total - 11, &Discount"},
+				{Description: "replaced 10 with 11", Line: 11, SourceCode: "&Discount{Percent: 11}"},
+				{Description: "returned zero values", Line: 13, SourceCode: "return 0, nil, nil\n}"},
+			},
+		},
+		{
+			name:     "method with closure",
+			function: types.Function{Name: "Label", Receiver: "Cart"},
+			expected: []types.Mutant{
+				{Description: "returned zero values", Line: 18, SourceCode: "return \"\"\n\t}"},
+				{Description: "returned zero values", Line: 20, SourceCode: "return \"\"\n}"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutants, err := NewGoSupport().Mutate(mutateSource, tt.function)
+			require.NoError(t, err)
+			require.Len(t, mutants, len(tt.expected))
+
+			for i, mutant := range mutants {
+				assert.Equal(t, tt.expected[i].Description, mutant.Description)
+				assert.Equal(t, tt.expected[i].Line, mutant.Line)
+				assert.Contains(t, mutant.SourceCode, tt.expected[i].SourceCode)
+				// Only the function is changed, and every mutant is still valid Go
+				assert.True(t, strings.HasSuffix(mutant.SourceCode, "func Other() bool {\n\treturn true\n}\n"))
+				_, err := parser.ParseFile(token.NewFileSet(), "", mutant.SourceCode, 0)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGoSupport_Mutate_NotFound(t *testing.T) {
+	_, err := NewGoSupport().Mutate(mutateSource, types.Function{Name: "Label"})
+	assert.Error(t, err)
+}
+
+func TestZeroValue(t *testing.T) {
+	source := `package p
+
+func f() (int, string, bool, error, []byte, [2]int, map[string]int, *T, T, func(), any, chan int) {}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, 0)
+	require.NoError(t, err)
+	m := &mutator{fset: fset, src: source}
+
+	results := file.Decls[0].(*ast.FuncDecl).Type.Results
+	assert.Equal(t, []string{"0", `""`, "false", "nil", "nil", "*new([2]int)", "nil", "nil", "*new(T)", "nil", "nil", "nil"}, m.zeroValues(results))
+}
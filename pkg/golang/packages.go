@@ -0,0 +1,124 @@
+package golang
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gwkline/artestian/pkg/command"
+)
+
+// goPackage is the subset of `go list -json` output needed to build and test a package
+type goPackage struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	Module     *struct {
+		Path string
+		Dir  string
+	}
+}
+
+// moduleDir returns the root of the module containing the package, falling back
+// to the package directory for GOPATH-style layouts
+func (p *goPackage) moduleDir() string {
+	if p.Module != nil && p.Module.Dir != "" {
+		return p.Module.Dir
+	}
+	return p.Dir
+}
+
+// resolvePackage uses `go list` to find the package in dir and its enclosing module
+func resolvePackage(ctx context.Context, dir string, tags []string) (*goPackage, error) {
+	args := []string{"list", "-e", "-json"}
+	if len(tags) > 0 {
+		args = append(args, "-tags", strings.Join(tags, ","))
+	}
+	args = append(args, ".")
+
+	cmd := command.New(ctx, dir, "go", args...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("go list failed in %s: %s", dir, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("go list failed in %s: %w", dir, err)
+	}
+
+	var pkg goPackage
+	if err := json.Unmarshal(output, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse go list output: %w", err)
+	}
+	if pkg.Dir == "" {
+		pkg.Dir = dir
+	}
+	return &pkg, nil
+}
+
+// testNames returns the names of the top-level Test, Example and Fuzz functions
+// declared in a test file. Both internal and external (_test) packages are supported.
+func testNames(testFilePath string) ([]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, testFilePath, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil {
+			continue
+		}
+		name := funcDecl.Name.Name
+		for _, prefix := range []string{"Test", "Example", "Fuzz"} {
+			if isTestName(name, prefix) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names, nil
+}
+
+// isTestName mirrors the go tool's rule that the character after the prefix must not be lowercase
+func isTestName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	c := name[len(prefix)]
+	return !(c >= 'a' && c <= 'z')
+}
+
+// runPattern builds a -run regexp matching exactly the given test names
+func runPattern(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// packageTarget returns the argument identifying a package to the go tool,
+// preferring a relative directory when the import path is unknown
+func packageTarget(pkg *goPackage) string {
+	if pkg.ImportPath != "" && !strings.HasPrefix(pkg.ImportPath, "_") {
+		return pkg.ImportPath
+	}
+	rel, err := filepath.Rel(pkg.moduleDir(), pkg.Dir)
+	if err != nil {
+		return pkg.Dir
+	}
+	return "./" + filepath.ToSlash(rel)
+}
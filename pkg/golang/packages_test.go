@@ -0,0 +1,204 @@
+package golang
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeModule creates a throwaway module with a package-internal function and returns the package directory
+func writeModule(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "calc")
+	assert.NoError(t, os.MkdirAll(pkgDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/calc\n\ngo 1.21\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(pkgDir, "calc.go"), []byte(`package calc
+
+func add(a, b int) int { return a + b }
+
+func Add(a, b int) int { return add(a, b) }
+`), 0644))
+	return pkgDir
+}
+
+func TestTestNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names_test.go")
+	assert.NoError(t, os.WriteFile(path, []byte(`package calc_test
+
+import "testing"
+
+func TestAdd(t *testing.T) {}
+func Test_helper(t *testing.T) {}
+func Testing(t *testing.T) {}
+func ExampleAdd() {}
+func FuzzAdd(f *testing.F) {}
+func helper() {}
+`), 0644))
+
+	names, err := testNames(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TestAdd", "Test_helper", "ExampleAdd", "FuzzAdd"}, names)
+}
+
+func TestRunPattern(t *testing.T) {
+	assert.Equal(t, "^(TestAdd|TestSub)$", runPattern([]string{"TestAdd", "TestSub"}))
+}
+
+func TestResolvePackage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	pkgDir := writeModule(t)
+
+	pkg, err := resolvePackage(context.Background(), pkgDir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com/calc/calc", pkg.ImportPath)
+	assert.Equal(t, "calc", pkg.Name)
+	assert.Equal(t, filepath.Dir(pkgDir), pkg.moduleDir())
+}
+
+func TestGoSupport_CheckTypes_Baseline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	tests := []struct {
+		name     string
+		testCode string
+		baseline bool
+		passes   bool
+	}{
+		{
+			name:     "finding in another file is in the baseline",
+			testCode: "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\t_ = add(1, 2)\n}\n",
+			baseline: true,
+			passes:   true,
+		},
+		{
+			name:     "no baseline",
+			testCode: "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\t_ = add(1, 2)\n}\n",
+			passes:   false,
+		},
+		{
+			name:     "test file repeats the finding",
+			testCode: "package calc\n\nimport (\n\t\"fmt\"\n\t\"testing\"\n)\n\nfunc TestAdd(t *testing.T) {\n\t_ = fmt.Sprintf(\"%d\", \"s\")\n}\n",
+			baseline: true,
+			passes:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkgDir := writeModule(t)
+			assert.NoError(t, os.WriteFile(filepath.Join(pkgDir, "format.go"), []byte(`package calc
+
+import "fmt"
+
+func format() string { return fmt.Sprintf("%d", "s") }
+`), 0644))
+
+			support := NewGoSupport()
+			if tt.baseline {
+				assert.NoError(t, support.CaptureBaseline(context.Background(), filepath.Join(pkgDir, "calc.go")))
+			}
+			testPath := filepath.Join(pkgDir, "calc_test.go")
+			assert.NoError(t, os.WriteFile(testPath, []byte(tt.testCode), 0644))
+
+			result, err := support.CheckTypes(context.Background(), testPath)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.passes, result.Passed, result.Output)
+			if tt.baseline {
+				for _, d := range result.Diagnostics {
+					assert.Equal(t, testPath, d.File, "findings in format.go are in the baseline")
+				}
+			}
+			if !tt.passes {
+				assert.NotEmpty(t, result.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestGoTestRunner_RunTests(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	tests := []struct {
+		name     string
+		testCode string
+		passes   bool
+	}{
+		{
+			name: "internal package test using unexported symbols",
+			testCode: `package calc
+
+import "testing"
+
+func TestGeneratedAdd(t *testing.T) {
+	if add(2, 3) != 5 {
+		t.Fatal("wrong sum")
+	}
+}
+`,
+			passes: true,
+		},
+		{
+			name: "external test package",
+			testCode: `package calc_test
+
+import (
+	"testing"
+
+	"example.com/calc/calc"
+)
+
+func TestGeneratedAdd(t *testing.T) {
+	if calc.Add(2, 3) != 5 {
+		t.Fatal("wrong sum")
+	}
+}
+`,
+			passes: true,
+		},
+		{
+			name: "failing test",
+			testCode: `package calc
+
+import "testing"
+
+func TestGeneratedAdd(t *testing.T) {
+	if add(2, 3) != 6 {
+		t.Fatal("wrong sum")
+	}
+}
+`,
+			passes: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkgDir := writeModule(t)
+			testPath := filepath.Join(pkgDir, "generated_test.go")
+			assert.NoError(t, os.WriteFile(testPath, []byte(tt.testCode), 0644))
+
+			support := NewGoSupport()
+			result, err := support.CheckTypes(context.Background(), testPath)
+			assert.NoError(t, err)
+			assert.True(t, result.Passed, result.Output)
+
+			result, err = support.GetTestRunner().RunTests(context.Background(), pkgDir, testPath)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.passes, result.Passed, result.Output)
+			assert.Len(t, result.Tests, 1)
+			assert.Equal(t, "TestGeneratedAdd", result.Tests[0].Name)
+		})
+	}
+}
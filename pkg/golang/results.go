@@ -0,0 +1,148 @@
+package golang
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// testEvent is a single event of `go test -json` output
+type testEvent struct {
+	Action string
+	Test   string
+	Output string
+}
+
+var (
+	// diagnosticPattern matches compiler and vet errors, e.g. "vet: ./a_test.go:3:33: undefined: x"
+	diagnosticPattern = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.+)$`)
+	// locationPattern matches file:line references in test output and stack traces
+	locationPattern = regexp.MustCompile(`([\w.\-/]+\.go):(\d+)`)
+)
+
+// frameLines are lines go test prints to delimit tests, which carry no failure information
+var frameLines = []string{"=== RUN", "=== PAUSE", "=== CONT", "=== NAME", "--- PASS", "--- FAIL", "--- SKIP"}
+
+// parseTestJSON converts `go test -json` output into a structured result.
+// Lines that are not JSON events, such as build errors from older go versions,
+// are parsed as compiler diagnostics.
+func parseTestJSON(output string) types.RunResult {
+	result := types.RunResult{Output: output}
+
+	// With -count, a test runs several times and each run gets its own case
+	var tests []*types.TestCase
+	running := make(map[string]*types.TestCase)
+	testCase := func(name string) *types.TestCase {
+		if tc, ok := running[name]; ok && tc.Status == "" {
+			return tc
+		}
+		running[name] = &types.TestCase{Name: name}
+		tests = append(tests, running[name])
+		return running[name]
+	}
+
+	var panicLines []string
+	inPanic := false
+
+	for _, line := range strings.Split(output, "\n") {
+		var event testEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &event) != nil {
+			if diag, ok := parseDiagnostic(line); ok {
+				result.Diagnostics = append(result.Diagnostics, diag)
+			}
+			continue
+		}
+
+		switch event.Action {
+		case "build-output":
+			if diag, ok := parseDiagnostic(strings.TrimSpace(event.Output)); ok {
+				result.Diagnostics = append(result.Diagnostics, diag)
+			}
+		case "output":
+			text := strings.TrimRight(event.Output, "\n")
+			if strings.Contains(text, "test timed out after") {
+				result.TimedOut = true
+			}
+			if strings.HasPrefix(text, "panic: ") {
+				inPanic = true
+			}
+			if inPanic {
+				panicLines = append(panicLines, text)
+				continue
+			}
+			if event.Test == "" || isFrameLine(text) || strings.TrimSpace(text) == "" {
+				continue
+			}
+			tc := testCase(event.Test)
+			tc.Failures = append(tc.Failures, strings.TrimSpace(text))
+		case "pass", "fail", "skip":
+			if event.Test == "" {
+				continue
+			}
+			tc := testCase(event.Test)
+			tc.Status = types.TestStatus(event.Action)
+		}
+	}
+
+	for _, tc := range tests {
+		if tc.Status != types.TestStatusFail {
+			// Output of passing and skipped tests is only noise for repairs
+			tc.Failures = nil
+		}
+		tc.Locations = findLocations(tc.Failures)
+		result.Tests = append(result.Tests, *tc)
+	}
+	result.Panic = strings.TrimSpace(strings.Join(panicLines, "\n"))
+
+	return result
+}
+
+// parseVetOutput converts `go vet` output into a structured result
+func parseVetOutput(output string) types.RunResult {
+	result := types.RunResult{Output: output}
+	for _, line := range strings.Split(output, "\n") {
+		if diag, ok := parseDiagnostic(strings.TrimSpace(line)); ok {
+			result.Diagnostics = append(result.Diagnostics, diag)
+		}
+	}
+	return result
+}
+
+func parseDiagnostic(line string) (types.Diagnostic, bool) {
+	match := diagnosticPattern.FindStringSubmatch(line)
+	if match == nil {
+		return types.Diagnostic{}, false
+	}
+	lineNum, _ := strconv.Atoi(match[2])
+	column, _ := strconv.Atoi(match[3])
+	return types.Diagnostic{
+		Location: types.Location{File: match[1], Line: lineNum, Column: column},
+		Message:  match[4],
+	}, true
+}
+
+func findLocations(lines []string) []types.Location {
+	var locations []types.Location
+	for _, line := range lines {
+		match := locationPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		lineNum, _ := strconv.Atoi(match[2])
+		locations = append(locations, types.Location{File: match[1], Line: lineNum})
+	}
+	return locations
+}
+
+func isFrameLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range frameLines {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return trimmed == "PASS" || trimmed == "FAIL" || strings.HasPrefix(trimmed, "FAIL\t") || strings.HasPrefix(trimmed, "ok  \t")
+}
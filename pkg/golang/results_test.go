@@ -0,0 +1,109 @@
+package golang
+
+import (
+	"testing"
+
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTestJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected types.RunResult
+	}{
+		{
+			name: "failing and skipped tests",
+			output: `{"Action":"start","Package":"bm"}
+{"Action":"run","Package":"bm","Test":"TestA"}
+{"Action":"output","Package":"bm","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"output","Package":"bm","Test":"TestA","Output":"    a_test.go:3: bad 1\n"}
+{"Action":"output","Package":"bm","Test":"TestA","Output":"--- FAIL: TestA (0.00s)\n"}
+{"Action":"fail","Package":"bm","Test":"TestA","Elapsed":0}
+{"Action":"run","Package":"bm","Test":"TestB"}
+{"Action":"output","Package":"bm","Test":"TestB","Output":"    a_test.go:5: not yet\n"}
+{"Action":"skip","Package":"bm","Test":"TestB","Elapsed":0}
+{"Action":"fail","Package":"bm","Elapsed":0.005}`,
+			expected: types.RunResult{
+				Tests: []types.TestCase{
+					{
+						Name:      "TestA",
+						Status:    types.TestStatusFail,
+						Failures:  []string{"a_test.go:3: bad 1"},
+						Locations: []types.Location{{File: "a_test.go", Line: 3}},
+					},
+					{
+						Name:   "TestB",
+						Status: types.TestStatusSkip,
+					},
+				},
+			},
+		},
+		{
+			name: "repeated runs",
+			output: `{"Action":"run","Package":"bm","Test":"TestA"}
+{"Action":"output","Package":"bm","Test":"TestA","Output":"--- PASS: TestA (0.00s)\n"}
+{"Action":"pass","Package":"bm","Test":"TestA","Elapsed":0}
+{"Action":"run","Package":"bm","Test":"TestA"}
+{"Action":"output","Package":"bm","Test":"TestA","Output":"    a_test.go:7: got [b a]\n"}
+{"Action":"output","Package":"bm","Test":"TestA","Output":"--- FAIL: TestA (0.00s)\n"}
+{"Action":"fail","Package":"bm","Test":"TestA","Elapsed":0}
+{"Action":"fail","Package":"bm","Elapsed":0.005}`,
+			expected: types.RunResult{
+				Tests: []types.TestCase{
+					{Name: "TestA", Status: types.TestStatusPass},
+					{
+						Name:      "TestA",
+						Status:    types.TestStatusFail,
+						Failures:  []string{"a_test.go:7: got [b a]"},
+						Locations: []types.Location{{File: "a_test.go", Line: 7}},
+					},
+				},
+			},
+		},
+		{
+			name: "build failure",
+			output: `{"ImportPath":"bm [bm.test]","Action":"build-output","Output":"# bm [bm.test]\n"}
+{"ImportPath":"bm [bm.test]","Action":"build-output","Output":"./a_test.go:3:33: undefined: undefined1\n"}
+{"ImportPath":"bm [bm.test]","Action":"build-fail"}
+{"Action":"fail","Package":"bm","Elapsed":0,"FailedBuild":"bm [bm.test]"}`,
+			expected: types.RunResult{
+				Diagnostics: []types.Diagnostic{
+					{Location: types.Location{File: "./a_test.go", Line: 3, Column: 33}, Message: "undefined: undefined1"},
+				},
+			},
+		},
+		{
+			name: "panic and timeout",
+			output: `{"Action":"run","Package":"bm","Test":"TestA"}
+{"Action":"output","Package":"bm","Test":"TestA","Output":"panic: test timed out after 1s\n"}
+{"Action":"output","Package":"bm","Test":"TestA","Output":"\trunning tests:\n"}
+{"Action":"fail","Package":"bm","Test":"TestA","Elapsed":1}`,
+			expected: types.RunResult{
+				Tests: []types.TestCase{
+					{Name: "TestA", Status: types.TestStatusFail},
+				},
+				Panic:    "panic: test timed out after 1s\n\trunning tests:",
+				TimedOut: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseTestJSON(tt.output)
+			tt.expected.Output = tt.output
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParseVetOutput(t *testing.T) {
+	output := "# bm\n# [bm]\nvet: ./a_test.go:3:33: undefined: undefined1\n"
+
+	result := parseVetOutput(output)
+	assert.Equal(t, []types.Diagnostic{
+		{Location: types.Location{File: "./a_test.go", Line: 3, Column: 33}, Message: "undefined: undefined1"},
+	}, result.Diagnostics)
+}
@@ -0,0 +1,224 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines around each change, as in diff -u
+const contextLines = 3
+
+// edit is one line of an edit script: ' ' kept, '-' removed or '+' added
+type edit struct {
+	op   byte
+	line string
+}
+
+// Diff returns a unified diff from old to new for the file at name, relative to the
+// repository root, in the format git apply reads. A nil old is a new file. Diff returns
+// an empty string when nothing changed.
+func Diff(name string, old, new []byte) string {
+	if old != nil && string(old) == string(new) {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n", name, name)
+	if old == nil {
+		b.WriteString("new file mode 100644\n--- /dev/null\n")
+	} else {
+		fmt.Fprintf(&b, "--- a/%s\n", name)
+	}
+	fmt.Fprintf(&b, "+++ b/%s\n", name)
+
+	edits := editScript(splitLines(string(old)), splitLines(string(new)))
+	for _, h := range hunks(edits) {
+		writeHunk(&b, edits, h)
+	}
+	return b.String()
+}
+
+// splitLines splits text after every newline. The last line has no newline when text
+// doesn't end with one.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript returns the shortest edit script from a to b, found with Myers' algorithm
+// after trimming the common prefix and suffix
+func editScript(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []edit
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits
+}
+
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		edits := make([]edit, 0, n+m)
+		for _, line := range a {
+			edits = append(edits, edit{'-', line})
+		}
+		for _, line := range b {
+			edits = append(edits, edit{'+', line})
+		}
+		return edits
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k, and trace keeps v after
+	// every round so the path can be walked back
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v...))
+				return backtrack(a, b, trace, offset)
+			}
+		}
+		trace = append(trace, append([]int(nil), v...))
+	}
+	return nil
+}
+
+func backtrack(a, b []string, trace [][]int, offset int) []edit {
+	var edits []edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d-1]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{'+', b[y]})
+		} else {
+			x--
+			edits = append(edits, edit{'-', a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, edit{' ', a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// hunk is a range of the edit script, with changes and the context around them
+type hunk struct {
+	start, end int
+}
+
+// hunks groups the changes in edits, merging changes whose context would overlap
+func hunks(edits []edit) []hunk {
+	var result []hunk
+	for i, e := range edits {
+		if e.op == ' ' {
+			continue
+		}
+		start := max(0, i-contextLines)
+		end := min(len(edits), i+1+contextLines)
+		if len(result) > 0 && start <= result[len(result)-1].end {
+			result[len(result)-1].end = end
+		} else {
+			result = append(result, hunk{start, end})
+		}
+	}
+	return result
+}
+
+func writeHunk(b *strings.Builder, edits []edit, h hunk) {
+	// Line numbers before the hunk
+	oldStart, newStart := 0, 0
+	for _, e := range edits[:h.start] {
+		if e.op != '+' {
+			oldStart++
+		}
+		if e.op != '-' {
+			newStart++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, e := range edits[h.start:h.end] {
+		if e.op != '+' {
+			oldCount++
+		}
+		if e.op != '-' {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, e := range edits[h.start:h.end] {
+		b.WriteByte(e.op)
+		b.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the lines after skipped as a hunk header range. An empty range
+// starts at the line before it.
+func hunkRange(skipped, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", skipped)
+	case 1:
+		return fmt.Sprintf("%d", skipped+1)
+	default:
+		return fmt.Sprintf("%d,%d", skipped+1, count)
+	}
+}
@@ -0,0 +1,127 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	numbered := func(from, to int, extra map[int]string) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			if line, ok := extra[i]; ok {
+				b.WriteString(line + "\n")
+				continue
+			}
+			b.WriteString("line " + string(rune('a'+i-1)) + "\n")
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name     string
+		old      []byte
+		new      string
+		expected string
+	}{
+		{
+			name: "new file",
+			new:  "package pkg\n\nfunc TestAdd(t *testing.T) {}\n",
+			expected: "diff --git a/pkg/add_test.go b/pkg/add_test.go\n" +
+				"new file mode 100644\n" +
+				"--- /dev/null\n" +
+				"+++ b/pkg/add_test.go\n" +
+				"@@ -0,0 +1,3 @@\n" +
+				"+package pkg\n" +
+				"+\n" +
+				"+func TestAdd(t *testing.T) {}\n",
+		},
+		{
+			name:     "unchanged",
+			old:      []byte("package pkg\n"),
+			new:      "package pkg\n",
+			expected: "",
+		},
+		{
+			name: "appended",
+			old:  []byte("package pkg\n\nfunc TestAdd(t *testing.T) {}\n"),
+			new:  "package pkg\n\nfunc TestAdd(t *testing.T) {}\n\nfunc TestSub(t *testing.T) {}\n",
+			expected: "diff --git a/pkg/add_test.go b/pkg/add_test.go\n" +
+				"--- a/pkg/add_test.go\n" +
+				"+++ b/pkg/add_test.go\n" +
+				"@@ -1,3 +1,5 @@\n" +
+				" package pkg\n" +
+				" \n" +
+				" func TestAdd(t *testing.T) {}\n" +
+				"+\n" +
+				"+func TestSub(t *testing.T) {}\n",
+		},
+		{
+			name: "separate hunks",
+			old:  []byte(numbered(1, 12, nil)),
+			new:  numbered(1, 12, map[int]string{2: "changed b", 11: "changed k"}),
+			expected: "diff --git a/pkg/add_test.go b/pkg/add_test.go\n" +
+				"--- a/pkg/add_test.go\n" +
+				"+++ b/pkg/add_test.go\n" +
+				"@@ -1,5 +1,5 @@\n" +
+				" line a\n" +
+				"-line b\n" +
+				"+changed b\n" +
+				" line c\n" +
+				" line d\n" +
+				" line e\n" +
+				"@@ -8,5 +8,5 @@\n" +
+				" line h\n" +
+				" line i\n" +
+				" line j\n" +
+				"-line k\n" +
+				"+changed k\n" +
+				" line l\n",
+		},
+		{
+			name: "no newline at end of file",
+			old:  []byte("package pkg"),
+			new:  "package pkg_test\n",
+			expected: "diff --git a/pkg/add_test.go b/pkg/add_test.go\n" +
+				"--- a/pkg/add_test.go\n" +
+				"+++ b/pkg/add_test.go\n" +
+				"@@ -1 +1 @@\n" +
+				"-package pkg\n" +
+				"\\ No newline at end of file\n" +
+				"+package pkg_test\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Diff("pkg/add_test.go", tt.old, []byte(tt.new)))
+		})
+	}
+}
+
+func TestEditScript(t *testing.T) {
+	a := splitLines("a\nb\nc\na\nb\nb\na\n")
+	b := splitLines("c\nb\na\nb\na\nc\n")
+
+	edits := editScript(a, b)
+
+	// Applying the script to a gives b, with the fewest changes
+	var old, new []string
+	changes := 0
+	for _, e := range edits {
+		if e.op != '+' {
+			old = append(old, e.line)
+		}
+		if e.op != '-' {
+			new = append(new, e.line)
+		}
+		if e.op != ' ' {
+			changes++
+		}
+	}
+	assert.Equal(t, a, old)
+	assert.Equal(t, b, new)
+	assert.Equal(t, 5, changes)
+}
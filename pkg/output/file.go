@@ -0,0 +1,69 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// FileSink writes test files to the project
+type FileSink struct{}
+
+func NewFileSink() *FileSink {
+	return &FileSink{}
+}
+
+// WriteFile writes the test file atomically, creating its directory
+func (s *FileSink) WriteFile(file types.TestFile) error {
+	return writeFile(file.Path, file.Content)
+}
+
+func (s *FileSink) Close() error {
+	return nil
+}
+
+// Content joins the tests of a file in the order they were generated, as they are. It's
+// only a whole test file for a single test; several are merged with ILanguage.MergeTests.
+func Content(tests []types.FunctionTest) []byte {
+	var content []byte
+	for _, test := range tests {
+		content = append(content, test.TestCode...)
+		content = append(content, '\n')
+	}
+	return content
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating test directory: %w", err)
+	}
+	if err := writeFileAtomic(path, content); err != nil {
+		return fmt.Errorf("error writing test file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a hidden file in the same directory and renames it
+// into place, so an interrupted run never leaves a half-written test file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,256 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gwkline/artestian/pkg/command"
+	"github.com/gwkline/artestian/types"
+)
+
+// CommitMode is how test files are grouped into commits
+type CommitMode string
+
+const (
+	CommitSingle      CommitMode = "single"       // One commit for the whole run
+	CommitPerFile     CommitMode = "per-file"     // One commit per source file
+	CommitPerFunction CommitMode = "per-function" // One commit per function test
+)
+
+// GitOptions configures the git sink
+type GitOptions struct {
+	Root   string // Project root, inside a git repository
+	Branch string // Branch created from HEAD for the run's commits, artestian/<time> by default
+	Mode   CommitMode
+	Force  bool // Run on a working tree with uncommitted changes
+
+	// Merge combines function tests into one file for the intermediate commits of
+	// CommitPerFunction mode. They're joined as they are when it's nil or fails.
+	Merge func(tests []string) (string, error)
+}
+
+// GitSink writes test files to a new branch and commits them, then switches back to the
+// branch the run started on, leaving the new branch ready for review
+type GitSink struct {
+	options  GitOptions
+	original string // Branch or commit checked out before the run
+
+	mu      sync.Mutex
+	pending []types.TestFile // Written but not committed yet, in CommitSingle mode
+	commits int
+}
+
+// NewGitSink checks that the working tree is clean, unless forced, and creates and
+// checks out the branch
+func NewGitSink(options GitOptions) (*GitSink, error) {
+	switch options.Mode {
+	case CommitSingle, CommitPerFile, CommitPerFunction:
+	case "":
+		options.Mode = CommitPerFile
+	default:
+		return nil, fmt.Errorf("unknown commit mode: %s", options.Mode)
+	}
+	root, err := filepath.Abs(options.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project root: %w", err)
+	}
+	options.Root = root
+	if options.Branch == "" {
+		options.Branch = "artestian/" + time.Now().Format("20060102-150405")
+	}
+	s := &GitSink{options: options}
+
+	if _, err := s.git("rev-parse", "--show-toplevel"); err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", root, err)
+	}
+
+	status, err := s.git("status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	if status != "" {
+		if !options.Force {
+			return nil, fmt.Errorf("working tree has uncommitted changes, commit or stash them first or use -force")
+		}
+		slog.Warn("working tree has uncommitted changes, only generated test files will be committed")
+	}
+
+	// A detached HEAD has no branch name, so the commit is switched back to instead
+	if s.original, err = s.git("symbolic-ref", "--quiet", "--short", "HEAD"); err != nil {
+		if s.original, err = s.git("rev-parse", "HEAD"); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.git("checkout", "-b", options.Branch); err != nil {
+		return nil, fmt.Errorf("failed to create branch %s: %w", options.Branch, err)
+	}
+	slog.Info("created branch for generated tests", "branch", options.Branch, "from", s.original)
+	return s, nil
+}
+
+// WriteFile writes the test file and commits it, one commit per function test in
+// CommitPerFunction mode. In CommitSingle mode it's committed on Close.
+func (s *GitSink) WriteFile(file types.TestFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.options.Mode {
+	case CommitPerFunction:
+		// Each commit adds one function's test to the file, ending with the whole file
+		for i, test := range file.Tests {
+			content := file.Content
+			if i < len(file.Tests)-1 {
+				content = s.partial(file.Tests[:i+1])
+			}
+			if err := writeFile(file.Path, content); err != nil {
+				return err
+			}
+			if err := s.commit(functionMessage(s.rel(file.SourcePath), test), file.Path); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CommitSingle:
+		if err := writeFile(file.Path, file.Content); err != nil {
+			return err
+		}
+		s.pending = append(s.pending, file)
+		return nil
+	default:
+		if err := writeFile(file.Path, file.Content); err != nil {
+			return err
+		}
+		return s.commit(fileMessage(s.rel(file.SourcePath), file.Tests), file.Path)
+	}
+}
+
+// Close makes the CommitSingle commit and switches back to the original branch
+func (s *GitSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) > 0 {
+		paths := make([]string, len(s.pending))
+		for i, file := range s.pending {
+			paths[i] = file.Path
+		}
+		if err := s.commit(s.runMessage(s.pending), paths...); err != nil {
+			return err
+		}
+		s.pending = nil
+	}
+
+	if _, err := s.git("checkout", s.original); err != nil {
+		return fmt.Errorf("failed to switch back to %s, generated tests are on branch %s: %w", s.original, s.options.Branch, err)
+	}
+	if s.commits == 0 {
+		slog.Info("no tests were committed, removing branch", "branch", s.options.Branch)
+		_, err := s.git("branch", "--delete", s.options.Branch)
+		return err
+	}
+	slog.Info("generated tests are ready for review", "branch", s.options.Branch, "commits", s.commits)
+	return nil
+}
+
+// partial returns the test file holding only some of its tests
+func (s *GitSink) partial(tests []types.FunctionTest) []byte {
+	if s.options.Merge == nil {
+		return Content(tests)
+	}
+	codes := make([]string, len(tests))
+	for i, test := range tests {
+		codes[i] = test.TestCode
+	}
+	merged, err := s.options.Merge(codes)
+	if err != nil {
+		slog.Warn("failed to merge tests for commit, joining them instead", "error", err)
+		return Content(tests)
+	}
+	return []byte(merged)
+}
+
+// commit stages and commits only paths, leaving anything else in the index alone
+func (s *GitSink) commit(message string, paths ...string) error {
+	if _, err := s.git(append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if _, err := s.git(append([]string{"commit", "--quiet", "--message", message, "--"}, paths...)...); err != nil {
+		return err
+	}
+	s.commits++
+	return nil
+}
+
+func (s *GitSink) git(args ...string) (string, error) {
+	cmd := command.New(context.Background(), s.options.Root, "git", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// rel returns path relative to the project root for commit messages
+func (s *GitSink) rel(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(s.options.Root, abs)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (s *GitSink) runMessage(files []types.TestFile) string {
+	var b strings.Builder
+	if len(files) == 1 {
+		fmt.Fprintf(&b, "Add generated tests for %s\n", s.rel(files[0].SourcePath))
+	} else {
+		fmt.Fprintf(&b, "Add generated tests for %d files\n", len(files))
+	}
+	for _, file := range files {
+		fmt.Fprintf(&b, "\n%s:\n", s.rel(file.SourcePath))
+		writeTests(&b, file.Tests)
+	}
+	return b.String()
+}
+
+func fileMessage(sourcePath string, tests []types.FunctionTest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Add generated tests for %s\n\n", sourcePath)
+	writeTests(&b, tests)
+	return b.String()
+}
+
+func functionMessage(sourcePath string, test types.FunctionTest) string {
+	return fmt.Sprintf("Add generated test for %s in %s\n\nPassed after %s.\n", functionName(test.Function), sourcePath, attempts(test.Attempts))
+}
+
+// writeTests lists the functions covered, with the attempts each took
+func writeTests(b *strings.Builder, tests []types.FunctionTest) {
+	for _, test := range tests {
+		fmt.Fprintf(b, "- %s (%s)\n", functionName(test.Function), attempts(test.Attempts))
+	}
+}
+
+func functionName(function types.Function) string {
+	if function.Receiver == "" {
+		return function.Name
+	}
+	return function.Receiver + "." + function.Name
+}
+
+func attempts(n int) string {
+	if n == 1 {
+		return "1 attempt"
+	}
+	return fmt.Sprintf("%d attempts", n)
+}
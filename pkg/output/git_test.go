@@ -0,0 +1,184 @@
+package output
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// gitRepo creates a repository with one commit on main, and returns a function that
+// runs git in it
+func gitRepo(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping git test in short mode")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+
+	root := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return strings.TrimSpace(string(output))
+	}
+	git("init", "--quiet", "--initial-branch=main")
+	writeFiles(t, root, map[string]string{"pkg/math.go": "package pkg\n", "pkg/strings.go": "package pkg\n"})
+	git("add", ".")
+	git("commit", "--quiet", "--message", "Initial commit")
+	return root, git
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func gitTestFiles(root string) []types.TestFile {
+	mathTests := []types.FunctionTest{
+		{Function: types.Function{Name: "Add"}, TestCode: "func TestAdd() {}", Attempts: 1},
+		{Function: types.Function{Name: "Sub", Receiver: "Calculator"}, TestCode: "func TestSub() {}", Attempts: 3},
+	}
+	stringsTests := []types.FunctionTest{
+		{Function: types.Function{Name: "Reverse"}, TestCode: "func TestReverse() {}", Attempts: 2},
+	}
+	return []types.TestFile{
+		{SourcePath: filepath.Join(root, "pkg", "math.go"), Path: filepath.Join(root, "pkg", "math_test.go"), Content: Content(mathTests), Tests: mathTests},
+		{SourcePath: filepath.Join(root, "pkg", "strings.go"), Path: filepath.Join(root, "pkg", "strings_test.go"), Content: Content(stringsTests), Tests: stringsTests},
+	}
+}
+
+func TestGitSink(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     CommitMode
+		expected []string // Commit messages on the branch, newest first
+	}{
+		{
+			name: "single",
+			mode: CommitSingle,
+			expected: []string{
+				"Add generated tests for 2 files\n\npkg/math.go:\n- Add (1 attempt)\n- Calculator.Sub (3 attempts)\n\npkg/strings.go:\n- Reverse (2 attempts)",
+			},
+		},
+		{
+			name: "per file",
+			mode: CommitPerFile,
+			expected: []string{
+				"Add generated tests for pkg/strings.go\n\n- Reverse (2 attempts)",
+				"Add generated tests for pkg/math.go\n\n- Add (1 attempt)\n- Calculator.Sub (3 attempts)",
+			},
+		},
+		{
+			name: "per function",
+			mode: CommitPerFunction,
+			expected: []string{
+				"Add generated test for Reverse in pkg/strings.go\n\nPassed after 2 attempts.",
+				"Add generated test for Calculator.Sub in pkg/math.go\n\nPassed after 3 attempts.",
+				"Add generated test for Add in pkg/math.go\n\nPassed after 1 attempt.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, git := gitRepo(t)
+
+			sink, err := NewGitSink(GitOptions{Root: root, Branch: "artestian/tests", Mode: tt.mode})
+			require.NoError(t, err)
+			assert.Equal(t, "artestian/tests", git("branch", "--show-current"))
+			for _, file := range gitTestFiles(root) {
+				require.NoError(t, sink.WriteFile(file))
+			}
+			require.NoError(t, sink.Close())
+
+			// The run ends back on main, with the tests only on the branch
+			assert.Equal(t, "main", git("branch", "--show-current"))
+			assert.NoFileExists(t, filepath.Join(root, "pkg", "math_test.go"))
+			assert.Empty(t, git("status", "--porcelain"))
+
+			log := git("log", "--format=%B%x00", "main..artestian/tests")
+			var messages []string
+			for _, message := range strings.Split(log, "\x00") {
+				if message = strings.TrimSpace(message); message != "" {
+					messages = append(messages, message)
+				}
+			}
+			assert.Equal(t, tt.expected, messages)
+
+			assert.Equal(t, "func TestAdd() {}\nfunc TestSub() {}", git("show", "artestian/tests:pkg/math_test.go"))
+		})
+	}
+}
+
+func TestGitSink_DirtyTree(t *testing.T) {
+	root, git := gitRepo(t)
+	writeFiles(t, root, map[string]string{"pkg/math.go": "package pkg\n\nfunc Add() {}\n"})
+	git("add", "pkg/math.go")
+
+	_, err := NewGitSink(GitOptions{Root: root, Branch: "artestian/tests"})
+	assert.ErrorContains(t, err, "uncommitted changes")
+	assert.Equal(t, "main", git("branch", "--show-current"))
+
+	// When forced, only the test files are committed, and the changes stay in the tree
+	sink, err := NewGitSink(GitOptions{Root: root, Branch: "artestian/tests", Force: true})
+	require.NoError(t, err)
+	require.NoError(t, sink.WriteFile(gitTestFiles(root)[0]))
+	require.NoError(t, sink.Close())
+
+	assert.Equal(t, "pkg/math_test.go", git("diff", "--name-only", "main", "artestian/tests"))
+	assert.Equal(t, "M  pkg/math.go", git("status", "--porcelain"))
+}
+
+func TestGitSink_NothingCommitted(t *testing.T) {
+	root, git := gitRepo(t)
+
+	sink, err := NewGitSink(GitOptions{Root: root, Branch: "artestian/tests"})
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	assert.Equal(t, "main", git("branch", "--format=%(refname:short)"))
+}
+
+func TestGitSink_PerFunctionMerge(t *testing.T) {
+	root, git := gitRepo(t)
+
+	sink, err := NewGitSink(GitOptions{
+		Root:   root,
+		Branch: "artestian/tests",
+		Mode:   CommitPerFunction,
+		Merge: func(tests []string) (string, error) {
+			return "package pkg\n\n" + strings.Join(tests, "\n") + "\n", nil
+		},
+	})
+	require.NoError(t, err)
+	file := gitTestFiles(root)[0]
+	file.Content = []byte("package pkg\n\nfunc TestAdd() {}\n\nfunc TestSub() {}\n")
+	require.NoError(t, sink.WriteFile(file))
+	require.NoError(t, sink.Close())
+
+	// Earlier commits hold the merged tests so far, and the last one the whole file
+	assert.Equal(t, "package pkg\n\nfunc TestAdd() {}", git("show", "artestian/tests~1:pkg/math_test.go"))
+	assert.Equal(t, "package pkg\n\nfunc TestAdd() {}\n\nfunc TestSub() {}", git("show", "artestian/tests:pkg/math_test.go"))
+}
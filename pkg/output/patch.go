@@ -0,0 +1,111 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// PatchSink leaves the project untouched, and writes a patch of every test file it was
+// given to out when it's closed
+type PatchSink struct {
+	root string
+	out  io.Writer
+	file string // Written instead of out when set
+
+	mu    sync.Mutex
+	paths []string // In the order they were first written
+	files map[string]patchFile
+}
+
+type patchFile struct {
+	old, new []byte // old is nil for a new file
+}
+
+// NewPatchSink returns a sink that writes a patch with paths relative to root to out,
+// which git apply can apply from root
+func NewPatchSink(root string, out io.Writer) *PatchSink {
+	return &PatchSink{root: root, out: out, files: make(map[string]patchFile)}
+}
+
+// NewPatchFileSink returns a sink that writes a patch with paths relative to root to the
+// file at path
+func NewPatchFileSink(root, path string) *PatchSink {
+	return &PatchSink{root: root, file: path, files: make(map[string]patchFile)}
+}
+
+// NewStdoutSink returns a sink that prints a patch with paths relative to root
+func NewStdoutSink(root string) *PatchSink {
+	return NewPatchSink(root, os.Stdout)
+}
+
+// WriteFile records the test file's new content
+func (s *PatchSink) WriteFile(file types.TestFile) error {
+	name, err := s.name(file.Path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	patch, ok := s.files[name]
+	if !ok {
+		s.paths = append(s.paths, name)
+		old, err := os.ReadFile(file.Path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("error reading %s: %w", file.Path, err)
+		}
+		patch.old = old
+	}
+	patch.new = append([]byte(nil), file.Content...)
+	s.files[name] = patch
+	return nil
+}
+
+// Close writes the patch
+func (s *PatchSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var patch strings.Builder
+	for _, name := range s.paths {
+		file := s.files[name]
+		patch.WriteString(Diff(name, file.old, file.new))
+	}
+
+	if s.file != "" {
+		if err := os.WriteFile(s.file, []byte(patch.String()), 0644); err != nil {
+			return fmt.Errorf("error writing patch: %w", err)
+		}
+		return nil
+	}
+	if _, err := io.WriteString(s.out, patch.String()); err != nil {
+		return fmt.Errorf("error writing patch: %w", err)
+	}
+	return nil
+}
+
+// name returns path relative to the root, with forward slashes as in git
+func (s *PatchSink) name(path string) (string, error) {
+	root, err := filepath.Abs(s.root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve patch root: %w", err)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside %s", path, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gwkline/artestian/types"
+)
+
+func testFile(path string, content []byte) types.TestFile {
+	return types.TestFile{Path: path, Content: content}
+}
+
+func TestPatchSink(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "pkg", "sub_test.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(existing), 0755))
+	require.NoError(t, os.WriteFile(existing, []byte("package pkg\n"), 0644))
+
+	var out bytes.Buffer
+	sink := NewPatchSink(root, &out)
+	require.NoError(t, sink.WriteFile(testFile(filepath.Join(root, "pkg", "add_test.go"), []byte("package pkg\n"))))
+	require.NoError(t, sink.WriteFile(testFile(existing, []byte("package pkg\n\nfunc TestSub() {}\n"))))
+	// Writing a file again replaces its new content, and keeps its original
+	require.NoError(t, sink.WriteFile(testFile(existing, []byte("package pkg\n\nfunc TestSub(t *testing.T) {}\n"))))
+	assert.ErrorContains(t, sink.WriteFile(testFile(filepath.Join(t.TempDir(), "x_test.go"), nil)), "outside")
+
+	// Nothing is written until the sink is closed, and the project is never changed
+	assert.Empty(t, out.String())
+	require.NoError(t, sink.Close())
+	assert.NoFileExists(t, filepath.Join(root, "pkg", "add_test.go"))
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg\n", string(content))
+
+	assert.Equal(t, "diff --git a/pkg/add_test.go b/pkg/add_test.go\n"+
+		"new file mode 100644\n"+
+		"--- /dev/null\n"+
+		"+++ b/pkg/add_test.go\n"+
+		"@@ -0,0 +1 @@\n"+
+		"+package pkg\n"+
+		"diff --git a/pkg/sub_test.go b/pkg/sub_test.go\n"+
+		"--- a/pkg/sub_test.go\n"+
+		"+++ b/pkg/sub_test.go\n"+
+		"@@ -1 +1,3 @@\n"+
+		" package pkg\n"+
+		"+\n"+
+		"+func TestSub(t *testing.T) {}\n", out.String())
+}
+
+func TestPatchFileSink_GitApply(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping git apply test in short mode")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	root := t.TempDir()
+	existing := filepath.Join(root, "pkg", "sub_test.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(existing), 0755))
+	require.NoError(t, os.WriteFile(existing, []byte("package pkg\n\nfunc TestSub(t *testing.T) {}"), 0644))
+
+	patchPath := filepath.Join(t.TempDir(), "tests.patch")
+	sink := NewPatchFileSink(root, patchPath)
+	files := map[string]string{
+		filepath.Join(root, "pkg", "add_test.go"):    "package pkg\n\nfunc TestAdd(t *testing.T) {}\n",
+		filepath.Join(root, "pkg", "nested", "a.go"): "package nested\n",
+		existing: "package pkg\n\nfunc TestSub(t *testing.T) {}\n\nfunc TestMul(t *testing.T) {}\n",
+	}
+	for path, content := range files {
+		require.NoError(t, sink.WriteFile(testFile(path, []byte(content))))
+	}
+	require.NoError(t, sink.Close())
+
+	cmd := exec.Command("git", "apply", patchPath)
+	cmd.Dir = root
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	for path, expected := range files {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pkg", "add_test.go")
+
+	sink := NewFileSink()
+	require.NoError(t, sink.WriteFile(testFile(path, []byte("package pkg\n"))))
+	require.NoError(t, sink.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg\n", string(content))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/gwkline/artestian/types"
 )
 
 // promptLogger handles saving prompts to files for debugging and analysis
@@ -42,7 +44,7 @@ func Init(enabled bool) (*promptLogger, error) {
 }
 
 // logPrompt saves a prompt and its response to a file as JSON
-func (l *promptLogger) Log(operation string, prompt string, response string) error {
+func (l *promptLogger) Log(operation string, prompt string, response string, usage types.Usage) error {
 	if l.loggingDisabled {
 		return nil
 	}
@@ -58,6 +60,7 @@ func (l *promptLogger) Log(operation string, prompt string, response string) err
 		"timestamp": timestamp,
 		"prompt":    prompt,
 		"response":  response,
+		"usage":     usage,
 	}
 
 	// Convert to JSON
@@ -74,3 +77,25 @@ func (l *promptLogger) Log(operation string, prompt string, response string) err
 	slog.Debug("saved prompt log", "path", fullPath)
 	return nil
 }
+
+// LogSummary saves the usage summary of a run to a file as JSON
+func (l *promptLogger) LogSummary(summary types.UsageSummary) error {
+	if l.loggingDisabled {
+		return nil
+	}
+
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	fullPath := filepath.Join(l.logsDir, fmt.Sprintf("%s_usage_summary.json", timestamp))
+
+	content, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage summary to JSON: %w", err)
+	}
+
+	if err := os.WriteFile(fullPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write usage summary: %w", err)
+	}
+
+	slog.Debug("saved usage summary", "path", fullPath)
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gwkline/artestian/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -68,7 +69,7 @@ func TestPromptLogger(t *testing.T) {
 			assert.NotNil(t, logger)
 
 			// Log prompt
-			err = logger.Log(tt.operation, tt.prompt, tt.response)
+			err = logger.Log(tt.operation, tt.prompt, tt.response, types.Usage{Calls: 1, InputTokens: 10, OutputTokens: 5})
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -95,6 +96,7 @@ func TestPromptLogger(t *testing.T) {
 				assert.Equal(t, tt.operation, logEntry["operation"])
 				assert.Equal(t, tt.prompt, logEntry["prompt"])
 				assert.Equal(t, tt.response, logEntry["response"])
+				assert.Equal(t, float64(10), logEntry["usage"].(map[string]interface{})["input_tokens"])
 
 				// Verify timestamp format
 				timestamp := logEntry["timestamp"].(string)
@@ -108,3 +110,39 @@ func TestPromptLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestPromptLogger_LogSummary(t *testing.T) {
+	tempDir := t.TempDir()
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("failed to change working directory: %v", err)
+	}
+
+	logger, err := Init(true)
+	assert.NoError(t, err)
+
+	err = logger.LogSummary(types.UsageSummary{
+		Total:        types.Usage{Calls: 2, Cost: 0.5},
+		ByOperation:  map[string]types.Usage{"generate_test": {Calls: 2, Cost: 0.5}},
+		PassingTests: 1,
+	})
+	assert.NoError(t, err)
+
+	files, err := os.ReadDir(filepath.Join(tempDir, "logs"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Contains(t, files[0].Name(), "usage_summary")
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "logs", files[0].Name()))
+	assert.NoError(t, err)
+
+	var summary types.UsageSummary
+	assert.NoError(t, json.Unmarshal(content, &summary))
+	assert.Equal(t, 2, summary.ByOperation["generate_test"].Calls)
+	assert.Equal(t, 1, summary.PassingTests)
+}
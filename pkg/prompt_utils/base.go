@@ -3,27 +3,82 @@ package prompt_utils
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 )
 
-// StructToXMLString converts a struct into an XML-like string format with snake_cased tags
+// named is implemented by languages and test runners, which are rendered by name
+type named interface {
+	GetName() string
+}
+
+// fieldOptions are parsed from a `prompt:"name,omitempty,cdata,order=N"` struct tag.
+// `prompt:"-"` or `prompt:"skip"` leaves the field out entirely.
+type fieldOptions struct {
+	name      string
+	omitempty bool
+	cdata     bool
+	skip      bool
+	order     int
+	ordered   bool
+}
+
+type field struct {
+	value   reflect.Value
+	options fieldOptions
+	embed   bool
+}
+
+// StructToXMLString converts a struct into an XML-like string format with snake_cased tags.
+// Field output is controlled by `prompt` struct tags:
+//   - name overrides the snake_cased field name
+//   - omitempty leaves out zero values and empty slices and maps
+//   - cdata wraps the value in a CDATA section instead of escaping it, for code
+//   - order=N renders the field before fields without an order, in ascending N
+//   - skip (or "-") leaves the field out
+//
+// Nested structs, slices of structs and maps are rendered recursively.
 func StructToXMLString(v interface{}) (string, error) {
-	var result strings.Builder
 	val := reflect.ValueOf(v)
-	typ := val.Type()
-
 	if val.Kind() == reflect.Ptr {
 		val = val.Elem()
-		typ = typ.Elem()
 	}
 
 	if val.Kind() != reflect.Struct {
 		return "", fmt.Errorf("input must be a struct, got %v", val.Kind())
 	}
 
+	var result strings.Builder
+	if err := writeFields(&result, val); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+func writeFields(b *strings.Builder, val reflect.Value) error {
+	for _, f := range sortedFields(val) {
+		if f.embed {
+			if err := writeFields(b, f.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := writeElement(b, f.options.name, f.value, f.options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedFields returns the fields of a struct to render, explicitly ordered fields first
+func sortedFields(val reflect.Value) []field {
+	typ := val.Type()
+
+	var fields []field
 	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
 		fieldType := typ.Field(i)
 
 		// Skip unexported fields
@@ -31,62 +86,180 @@ func StructToXMLString(v interface{}) (string, error) {
 			continue
 		}
 
-		// Handle embedded structs
-		if fieldType.Anonymous {
-			if field.Kind() == reflect.Struct {
-				embedded, err := StructToXMLString(field.Interface())
-				if err != nil {
+		options := parseTag(fieldType)
+		if options.skip {
+			continue
+		}
+
+		value := val.Field(i)
+		if options.omitempty && isEmpty(value) {
+			continue
+		}
+
+		// Embedded structs are flattened unless the tag gives them a name
+		tagName, _, _ := strings.Cut(fieldType.Tag.Get("prompt"), ",")
+		embed := fieldType.Anonymous && value.Kind() == reflect.Struct && tagName == ""
+		fields = append(fields, field{value: value, options: options, embed: embed})
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i].options, fields[j].options
+		if a.ordered != b.ordered {
+			return a.ordered
+		}
+		return a.ordered && a.order < b.order
+	})
+	return fields
+}
+
+func parseTag(fieldType reflect.StructField) fieldOptions {
+	options := fieldOptions{name: toSnakeCase(fieldType.Name)}
+
+	tag, ok := fieldType.Tag.Lookup("prompt")
+	if !ok {
+		return options
+	}
+	if tag == "-" {
+		options.skip = true
+		return options
+	}
+
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		options.name = parts[0]
+	}
+	for _, part := range parts[1:] {
+		switch {
+		case part == "omitempty":
+			options.omitempty = true
+		case part == "cdata":
+			options.cdata = true
+		case part == "skip":
+			options.skip = true
+		case strings.HasPrefix(part, "order="):
+			if n, err := strconv.Atoi(strings.TrimPrefix(part, "order=")); err == nil {
+				options.order = n
+				options.ordered = true
+			}
+		}
+	}
+	return options
+}
+
+func writeElement(b *strings.Builder, name string, value reflect.Value, options fieldOptions) error {
+	content, err := formatValue(value, options)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "<%s>\n%s\n</%s>\n\n", name, strings.TrimRight(content, "\n"), name)
+	return nil
+}
+
+// formatValue renders the contents of an element
+func formatValue(value reflect.Value, options fieldOptions) (string, error) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return "", nil
+		}
+		return formatValue(value.Elem(), options)
+
+	case reflect.Interface:
+		if value.IsNil() {
+			return "", nil
+		}
+		if n, ok := value.Interface().(named); ok {
+			return escape(n.GetName(), options), nil
+		}
+		return value.Elem().Type().String(), nil
+
+	case reflect.Struct:
+		var b strings.Builder
+		if err := writeFields(&b, value); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+
+	case reflect.Slice, reflect.Array:
+		var b strings.Builder
+		elemType := value.Type().Elem()
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() == reflect.Struct || elemType.Kind() == reflect.Map || elemType.Kind() == reflect.Slice {
+			itemName := toSnakeCase(elemType.Name())
+			if itemName == "" {
+				itemName = "item"
+			}
+			for j := 0; j < value.Len(); j++ {
+				if err := writeElement(&b, itemName, value.Index(j), fieldOptions{cdata: options.cdata}); err != nil {
 					return "", err
 				}
-				result.WriteString(embedded)
-				continue
 			}
+			return b.String(), nil
 		}
 
-		// Convert field name to snake_case
-		tagName := toSnakeCase(fieldType.Name)
-
-		// Format the field value
-		var fieldStr string
-		switch {
-		case field.Kind() == reflect.Struct:
-			embedded, err := StructToXMLString(field.Interface())
+		items := make([]string, value.Len())
+		for j := 0; j < value.Len(); j++ {
+			item, err := formatValue(value.Index(j), options)
 			if err != nil {
 				return "", err
 			}
-			fieldStr = embedded
-		case field.Kind() == reflect.Interface && !field.IsNil():
-			// Try to call GetName() if it exists
-			if nameMethod := field.MethodByName("GetName"); nameMethod.IsValid() {
-				result := nameMethod.Call(nil)
-				if len(result) > 0 {
-					fieldStr = fmt.Sprintf("%v", result[0])
-				} else {
-					fieldStr = field.Elem().Type().String()
-				}
-			} else {
-				// Fallback to type name
-				fieldStr = field.Elem().Type().String()
-			}
-		case field.Kind() == reflect.Slice || field.Kind() == reflect.Array:
-			items := make([]string, field.Len())
-			for j := 0; j < field.Len(); j++ {
-				items[j] = fmt.Sprintf("%v", field.Index(j))
+			items[j] = item
+		}
+		return strings.Join(items, "\n"), nil
+
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+
+		var b strings.Builder
+		for _, key := range keys {
+			content, err := formatValue(value.MapIndex(key), options)
+			if err != nil {
+				return "", err
 			}
-			fieldStr = strings.Join(items, "\n")
-		default:
-			fieldStr = fmt.Sprintf("%v", field)
+			fmt.Fprintf(&b, "<entry key=\"%s\">\n%s\n</entry>\n", escapeAttribute(fmt.Sprint(key)), strings.TrimRight(content, "\n"))
 		}
+		return b.String(), nil
+
+	case reflect.String:
+		return escape(value.String(), options), nil
 
-		// For embedded struct fields, don't wrap them in tags
-		if field.Kind() == reflect.Struct {
-			result.WriteString(fieldStr)
-		} else {
-			result.WriteString(fmt.Sprintf("<%s>\n%s\n</%s>\n\n", tagName, fieldStr, tagName))
+	default:
+		return fmt.Sprintf("%v", value), nil
+	}
+}
+
+// escape makes text safe to embed in an element, using a CDATA section if requested
+func escape(text string, options fieldOptions) string {
+	if options.cdata {
+		if text == "" {
+			return ""
 		}
+		return "<![CDATA[\n" + strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>") + "\n]]>"
 	}
+	return textEscaper.Replace(text)
+}
 
-	return result.String(), nil
+func escapeAttribute(text string) string {
+	return strings.ReplaceAll(textEscaper.Replace(text), `"`, "&quot;")
+}
+
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// isEmpty reports whether a value should be left out by omitempty
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	default:
+		return value.IsZero()
+	}
 }
 
 // toSnakeCase converts a string from CamelCase to snake_case
@@ -3,6 +3,8 @@ package prompt_utils
 import (
 	"strings"
 	"testing"
+
+	"github.com/gwkline/artestian/types"
 )
 
 func TestToSnakeCase(t *testing.T) {
@@ -163,3 +165,97 @@ func TestStructToXMLString_EmbeddedStruct(t *testing.T) {
 		}
 	}
 }
+
+func TestStructToXMLString_Tags(t *testing.T) {
+	type File struct {
+		Path    string
+		Content string `prompt:"content,cdata"`
+	}
+
+	type TaggedStruct struct {
+		Renamed  string            `prompt:"title"`
+		Empty    string            `prompt:"empty,omitempty"`
+		Skipped  string            `prompt:"-"`
+		Ignored  string            `prompt:"ignored,skip"`
+		Later    string            `prompt:"later,order=2"`
+		First    string            `prompt:"first,order=1"`
+		Files    []File            `prompt:"files"`
+		Labels   map[string]string `prompt:"labels"`
+		Escaped  string
+		Fallback string
+	}
+
+	test := TaggedStruct{
+		Renamed: "renamed",
+		Skipped: "skipped",
+		Ignored: "ignored",
+		Later:   "later",
+		First:   "first",
+		Files: []File{
+			{Path: "a.go", Content: "if a < b && c > d {}"},
+			{Path: "b.go", Content: "x := y[z[0]]>0"},
+		},
+		Labels:   map[string]string{"b": "2", "a": "1"},
+		Escaped:  "a < b && c > d",
+		Fallback: "plain",
+	}
+
+	result, err := StructToXMLString(test)
+	if err != nil {
+		t.Fatalf("StructToXMLString failed: %v", err)
+	}
+
+	expectedParts := []string{
+		"<title>\nrenamed\n</title>",
+		"<files>\n<file>\n<path>\na.go\n</path>\n\n<content>\n<![CDATA[\nif a < b && c > d {}\n]]>\n</content>\n</file>",
+		"<![CDATA[\nx := y[z[0]]]]><![CDATA[>0\n]]>",
+		"<labels>\n<entry key=\"a\">\n1\n</entry>\n<entry key=\"b\">\n2\n</entry>\n</labels>",
+		"<escaped>\na &lt; b &amp;&amp; c &gt; d\n</escaped>",
+	}
+
+	for _, part := range expectedParts {
+		if !strings.Contains(result, part) {
+			t.Errorf("Expected result to contain %q, but it didn't.\nGot: %s", part, result)
+		}
+	}
+
+	for _, unexpected := range []string{"<empty>", "skipped", "ignored"} {
+		if strings.Contains(result, unexpected) {
+			t.Errorf("Result should not contain %q.\nGot: %s", unexpected, result)
+		}
+	}
+
+	if !strings.HasPrefix(result, "<first>") || strings.Index(result, "<later>") > strings.Index(result, "<title>") {
+		t.Errorf("Expected ordered fields to come first.\nGot: %s", result)
+	}
+}
+
+func TestStructToXMLString_GenerateTestParams(t *testing.T) {
+	params := types.GenerateTestParams{
+		Function: types.Function{
+			Name:       "Add",
+			SourceCode: "func Add(a, b int) int { return a + b }",
+			Params:     []types.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+		},
+		SourceCode: "package math\n\nfunc Add(a, b int) int { return a + b }",
+	}
+
+	result, err := StructToXMLString(types.IterateTestParams{
+		GenerateTestParams: params,
+		TestCode:           "func TestAdd(t *testing.T) {}",
+		Errors:             []string{"undefined: x"},
+	})
+	if err != nil {
+		t.Fatalf("StructToXMLString failed: %v", err)
+	}
+
+	if count := strings.Count(result, "return a + b"); count != 1 {
+		t.Errorf("Expected the function source to appear once, got %d.\nGot: %s", count, result)
+	}
+	if !strings.Contains(result, "<param>\n<name>\na\n</name>\n\n<type>\nint\n</type>\n</param>") {
+		t.Errorf("Expected params to be rendered as elements.\nGot: %s", result)
+	}
+	if strings.Index(result, "<test_code>") < strings.Index(result, "<source_code>") || strings.Index(result, "<errors>") < strings.Index(result, "<test_code>") {
+		t.Errorf("Expected source code, then test code, then errors.\nGot: %s", result)
+	}
+}
@@ -0,0 +1,227 @@
+package prompt_utils
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// minSectionTokens is the smallest budget worth truncating a section to. Below it the
+// section is dropped instead.
+const minSectionTokens = 64
+
+// EstimateTokens approximates the number of tokens in s. Source code averages a little
+// under four characters per token, so this errs on the side of overestimating.
+func EstimateTokens(s string) int {
+	return (utf8.RuneCountInString(s)*2 + 6) / 7
+}
+
+// FitParams shrinks the parameters to roughly fit within budget tokens. Content is kept
+// in priority order:
+//  1. the target function, which is never truncated
+//  2. the types it references, reduced to their declarations if the whole source file doesn't fit,
+//     then discovered context files with declarations from other files
+//  3. the most relevant example
+//  4. configured context files, in the order given
+//  5. further examples, most relevant first, which are never truncated
+//
+// Lower priority sections are truncated or dropped first, and every change is logged.
+func FitParams(params types.GenerateTestParams, budget int) types.GenerateTestParams {
+	remaining := budget - EstimateTokens(params.Function.Doc)
+
+	// The function is only rendered as part of the source file, so a summarized file
+	// always ends with it
+	if tokens := EstimateTokens(params.SourceCode); tokens > remaining {
+		functionTokens := EstimateTokens(params.Function.SourceCode)
+		summary := referencedDeclarations(params.SourceCode, params.Function)
+		summary = truncate("source code", summary, remaining-functionTokens, tokens-functionTokens)
+		params.SourceCode = strings.TrimSpace(summary + "\n\n" + params.Function.SourceCode)
+	}
+	remaining -= EstimateTokens(params.SourceCode)
+
+	// Discovered declarations belong with the types the function references, so they're
+	// budgeted before the example. Configured context files come after it.
+	var discovered, configured []types.ContextFile
+	for _, file := range params.ContextFiles {
+		if file.Discovered {
+			discovered = append(discovered, file)
+		} else {
+			configured = append(configured, file)
+		}
+	}
+	contextFiles := fitContextFiles(discovered, &remaining)
+
+	if tokens := EstimateTokens(params.Example.SourceCode); tokens > remaining {
+		params.Example.SourceCode = truncate("example "+params.Example.Name, params.Example.SourceCode, remaining, tokens)
+	}
+	remaining -= EstimateTokens(params.Example.SourceCode)
+
+	params.ContextFiles = append(contextFiles, fitContextFiles(configured, &remaining)...)
+
+	// A partial example is more misleading than helpful, so further examples are kept whole or dropped
+	var otherExamples []types.TestExample
+	for _, example := range params.OtherExamples {
+		if tokens := EstimateTokens(example.SourceCode); tokens > remaining {
+			truncate("example "+example.Name, example.SourceCode, 0, tokens)
+			continue
+		}
+		remaining -= EstimateTokens(example.SourceCode)
+		otherExamples = append(otherExamples, example)
+	}
+	params.OtherExamples = otherExamples
+
+	return params
+}
+
+// fitContextFiles truncates or drops files, in order, to fit in the remaining tokens
+func fitContextFiles(files []types.ContextFile, remaining *int) []types.ContextFile {
+	var kept []types.ContextFile
+	for _, file := range files {
+		tokens := EstimateTokens(file.Content)
+		if tokens > *remaining {
+			file.Content = truncate("context file "+file.Path, file.Content, *remaining, tokens)
+			if file.Content == "" {
+				continue
+			}
+		}
+		*remaining -= EstimateTokens(file.Content)
+		kept = append(kept, file)
+	}
+	return kept
+}
+
+// Truncate cuts content to fit in budget tokens, logging under the given section name
+func Truncate(section, content string, budget int) string {
+	tokens := EstimateTokens(content)
+	if tokens <= budget {
+		return content
+	}
+	return truncate(section, content, budget, tokens)
+}
+
+// truncate cuts content down to whole lines fitting in budget tokens, or drops it entirely
+// if the budget is too small to be useful
+func truncate(section, content string, budget, originalTokens int) string {
+	if EstimateTokens(content) <= budget {
+		if content != "" {
+			slog.Info("summarized prompt section to fit token budget", "section", section, "tokens", originalTokens, "kept", EstimateTokens(content))
+		}
+		return content
+	}
+	if budget < minSectionTokens {
+		slog.Warn("dropped prompt section to fit token budget", "section", section, "tokens", originalTokens)
+		return ""
+	}
+
+	lines := strings.Split(content, "\n")
+	var kept strings.Builder
+	used := 0
+	for i, line := range lines {
+		// Leave room for the truncation marker
+		if used+EstimateTokens(line)+1 > budget-16 {
+			fmt.Fprintf(&kept, "... (%d more lines truncated)", len(lines)-i)
+			break
+		}
+		kept.WriteString(line)
+		kept.WriteByte('\n')
+		used += EstimateTokens(line) + 1
+	}
+
+	result := kept.String()
+	slog.Warn("truncated prompt section to fit token budget", "section", section, "tokens", originalTokens, "kept", EstimateTokens(result))
+	return result
+}
+
+// declarationKeyword matches lines that declare a named type, constant or variable
+var declarationKeyword = regexp.MustCompile(`^(export\s+)?(default\s+)?(declare\s+)?(abstract\s+)?(type|interface|class|enum|const|let|var)\b`)
+
+// referencedDeclarations reduces a source file to its imports and the top-level blocks
+// declaring something the function references. Blocks are separated by a blank line
+// followed by an unindented line, which holds for gofmt'd Go and most TypeScript.
+func referencedDeclarations(source string, function types.Function) string {
+	names := make(map[string]bool)
+	for _, ref := range function.References {
+		if !strings.Contains(ref, ".") {
+			names[ref] = true
+		}
+	}
+
+	var kept []string
+	for _, block := range topLevelBlocks(source) {
+		header := declarationLine(block)
+		switch {
+		case strings.HasPrefix(header, "package "), strings.HasPrefix(header, "import "), strings.HasPrefix(header, "import("):
+			kept = append(kept, block)
+		case declarationKeyword.MatchString(header):
+			for _, word := range declaredNames(block, header) {
+				if names[word] && word != function.Name {
+					kept = append(kept, block)
+					break
+				}
+			}
+		}
+	}
+
+	return strings.Join(kept, "\n\n")
+}
+
+var identifiers = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*`)
+
+// topLevelBlocks splits source into blank-line separated blocks, keeping indented
+// continuation lines with the block they belong to
+func topLevelBlocks(source string) []string {
+	var blocks []string
+	var current []string
+	blank := false
+	for _, line := range strings.Split(source, "\n") {
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t' || line[0] == '}' || line[0] == ')'
+		if blank && !indented && len(current) > 0 {
+			blocks = append(blocks, strings.Join(current, "\n"))
+			current = nil
+		} else if blank && len(current) > 0 {
+			current = append(current, "")
+		}
+		current = append(current, line)
+		blank = false
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
+
+// declaredNames returns the identifiers on a declaration line, or the first identifier of
+// every line for grouped Go declarations like "type (...)"
+func declaredNames(block, header string) []string {
+	if !strings.HasSuffix(header, "(") {
+		return identifiers.FindAllString(header, -1)
+	}
+
+	var names []string
+	for _, line := range strings.Split(block, "\n")[1:] {
+		if name := identifiers.FindString(strings.TrimSpace(line)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// declarationLine returns the first line of a block that isn't a comment or decorator
+func declarationLine(block string) string {
+	for _, line := range strings.Split(block, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "//") || strings.HasPrefix(line, "/*") || strings.HasPrefix(line, "*") || strings.HasPrefix(line, "@") {
+			continue
+		}
+		return line
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package prompt_utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gwkline/artestian/types"
+)
+
+const budgetSource = `package shapes
+
+import "math"
+
+// Circle is a round shape
+type Circle struct {
+	Radius float64
+}
+
+// Square is not referenced by Area
+type Square struct {
+	Side float64
+}
+
+type (
+	Unit  string
+	Color string
+)
+
+func Area(c Circle, u Unit) float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+`
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens(""); got != 0 {
+		t.Errorf("EstimateTokens(\"\") = %d, want 0", got)
+	}
+
+	text := strings.Repeat("abcd", 100)
+	if got := EstimateTokens(text); got < 100 || got > 130 {
+		t.Errorf("EstimateTokens(400 chars) = %d, want between 100 and 130", got)
+	}
+}
+
+func TestReferencedDeclarations(t *testing.T) {
+	function := types.Function{Name: "Area", References: []string{"Circle", "Unit", "math.Pi"}}
+
+	result := referencedDeclarations(budgetSource, function)
+
+	for _, want := range []string{"package shapes", `import "math"`, "type Circle struct", "Unit  string", "// Circle is a round shape"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected result to contain %q.\nGot: %s", want, result)
+		}
+	}
+	for _, unwanted := range []string{"Square", "func Area"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("expected result not to contain %q.\nGot: %s", unwanted, result)
+		}
+	}
+}
+
+func TestFitParams(t *testing.T) {
+	function := types.Function{
+		Name:       "Area",
+		SourceCode: "func Area(c Circle, u Unit) float64 {\n\treturn math.Pi * c.Radius * c.Radius\n}",
+		References: []string{"Circle", "Unit"},
+	}
+	largeFile := strings.Repeat("const filler = \"some long line of context\"\n", 200)
+
+	params := types.GenerateTestParams{
+		Function:   function,
+		SourceCode: budgetSource,
+		Example:    types.TestExample{Name: "example", SourceCode: largeFile},
+		ContextFiles: []types.ContextFile{
+			{Path: "a.go", Content: largeFile},
+			{Path: "b.go", Content: largeFile},
+		},
+	}
+
+	t.Run("everything fits", func(t *testing.T) {
+		result := FitParams(params, 100000)
+		if result.SourceCode != budgetSource || result.Example.SourceCode != largeFile || len(result.ContextFiles) != 2 {
+			t.Errorf("expected params to be unchanged")
+		}
+	})
+
+	t.Run("lower priority content is dropped first", func(t *testing.T) {
+		budget := EstimateTokens(budgetSource) + EstimateTokens(largeFile) + 500
+		result := FitParams(params, budget)
+
+		if result.SourceCode != budgetSource {
+			t.Errorf("expected source code to be kept in full")
+		}
+		if result.Example.SourceCode != largeFile {
+			t.Errorf("expected example to be kept in full")
+		}
+		if len(result.ContextFiles) != 1 || result.ContextFiles[0].Path != "a.go" {
+			t.Fatalf("expected only the first context file to be kept, got %d", len(result.ContextFiles))
+		}
+		if !strings.Contains(result.ContextFiles[0].Content, "more lines truncated") {
+			t.Errorf("expected first context file to be truncated")
+		}
+	})
+
+	t.Run("source code is reduced to referenced declarations", func(t *testing.T) {
+		budget := EstimateTokens(budgetSource) - 10
+		result := FitParams(params, budget)
+
+		if strings.Contains(result.SourceCode, "Square") || !strings.Contains(result.SourceCode, "type Circle struct") {
+			t.Errorf("expected source code to be summarized.\nGot: %s", result.SourceCode)
+		}
+		if !strings.HasSuffix(result.SourceCode, function.SourceCode) {
+			t.Errorf("expected summarized source code to end with the function.\nGot: %s", result.SourceCode)
+		}
+		if result.Example.SourceCode != "" || len(result.ContextFiles) != 0 {
+			t.Errorf("expected example and context files to be dropped")
+		}
+	})
+	t.Run("further examples come after context files", func(t *testing.T) {
+		withExamples := params
+		withExamples.ContextFiles = params.ContextFiles[:1]
+		withExamples.OtherExamples = []types.TestExample{
+			{Name: "second", SourceCode: largeFile},
+			{Name: "third", SourceCode: largeFile},
+		}
+
+		budget := EstimateTokens(budgetSource) + 3*EstimateTokens(largeFile) + 100
+		result := FitParams(withExamples, budget)
+
+		if len(result.ContextFiles) != 1 || result.ContextFiles[0].Content != largeFile {
+			t.Errorf("expected context file to be kept in full")
+		}
+		if len(result.OtherExamples) != 1 || result.OtherExamples[0].Name != "second" {
+			t.Fatalf("expected only the most relevant further example to be kept, got %d", len(result.OtherExamples))
+		}
+		if result.OtherExamples[0].SourceCode != largeFile {
+			t.Errorf("expected further example to be kept in full")
+		}
+	})
+}
+
+func TestFitParams_DiscoveredContext(t *testing.T) {
+	function := types.Function{Name: "Area", SourceCode: "func Area(c Circle) float64 { return 0 }"}
+	largeFile := strings.Repeat("const filler = \"some long line of context\"\n", 200)
+	declarations := "package shapes\n\ntype Circle struct {\n\tRadius float64\n}"
+
+	params := types.GenerateTestParams{
+		Function:   function,
+		SourceCode: function.SourceCode,
+		Example:    types.TestExample{Name: "example", SourceCode: largeFile},
+		ContextFiles: []types.ContextFile{
+			{Path: "shapes/circle.go", Content: declarations, Discovered: true},
+			{Path: "configured.go", Content: largeFile},
+		},
+	}
+
+	// Only room for the source code and the declarations, with the example truncated
+	budget := EstimateTokens(function.SourceCode) + EstimateTokens(declarations) + 200
+	result := FitParams(params, budget)
+
+	if len(result.ContextFiles) != 1 || result.ContextFiles[0].Content != declarations {
+		t.Fatalf("expected discovered declarations to be kept in full, got %+v", result.ContextFiles)
+	}
+	if !strings.Contains(result.Example.SourceCode, "more lines truncated") {
+		t.Errorf("expected example to be truncated")
+	}
+}
@@ -0,0 +1,190 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/gwkline/artestian/pkg/prompt_utils"
+	"github.com/gwkline/artestian/types"
+)
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+type Operation string
+
+const (
+	OperationGenerateTest    Operation = "generate_test"
+	OperationFixTypeErrors   Operation = "fix_type_errors"
+	OperationFixTestFailures Operation = "fix_test_failures"
+	OperationPickExample     Operation = "pick_example"
+)
+
+// Operations lists every prompt that can be rendered or overridden
+var Operations = []Operation{
+	OperationGenerateTest,
+	OperationFixTypeErrors,
+	OperationFixTestFailures,
+	OperationPickExample,
+}
+
+// IsValidOperation reports whether name is one of Operations
+func IsValidOperation(name string) bool {
+	for _, op := range Operations {
+		if string(op) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Data is the value templates are executed with. All GenerateTestParams fields are
+// available directly, e.g. {{.TestPath}} or {{.Function.Name}}.
+type Data struct {
+	types.IterateTestParams
+	Params   string              // The operation's parameters rendered as XML
+	Examples []types.TestExample // Candidate examples, only set for pick_example
+}
+
+// MaxPromptTokens is the token budget for a rendered prompt, leaving room in the
+// context window for the response
+var MaxPromptTokens = 150000
+
+// promptOverhead is reserved for the template text, XML tags and function metadata
+const promptOverhead = 2000
+
+var funcs = template.FuncMap{
+	"xml":  prompt_utils.StructToXMLString,
+	"join": strings.Join,
+}
+
+// Renderer renders prompts from the built-in templates, preferring project and
+// example type overrides when configured
+type Renderer struct {
+	templates     map[Operation]*template.Template
+	byExampleType map[types.TestType]map[Operation]*template.Template
+}
+
+// NewRenderer parses the built-in templates and any overrides
+func NewRenderer(overrides types.PromptTemplates) (*Renderer, error) {
+	r := &Renderer{
+		templates:     make(map[Operation]*template.Template),
+		byExampleType: make(map[types.TestType]map[Operation]*template.Template),
+	}
+
+	for _, op := range Operations {
+		content, err := builtin.ReadFile("templates/" + string(op) + ".tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in %s template: %w", op, err)
+		}
+		tmpl, err := parse(string(op), string(content))
+		if err != nil {
+			return nil, err
+		}
+		r.templates[op] = tmpl
+	}
+
+	for name, content := range overrides.Templates {
+		tmpl, err := parse(name, content)
+		if err != nil {
+			return nil, err
+		}
+		r.templates[Operation(name)] = tmpl
+	}
+
+	for exampleType, templates := range overrides.ByExampleType {
+		r.byExampleType[exampleType] = make(map[Operation]*template.Template)
+		for name, content := range templates {
+			tmpl, err := parse(string(exampleType)+"/"+name, content)
+			if err != nil {
+				return nil, err
+			}
+			r.byExampleType[exampleType][Operation(name)] = tmpl
+		}
+	}
+
+	return r, nil
+}
+
+func parse(name, content string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s prompt template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Render executes the template for an operation, using the example type's
+// override if there is one
+func (r *Renderer) Render(op Operation, exampleType types.TestType, data Data) (string, error) {
+	tmpl, ok := r.byExampleType[exampleType][op]
+	if !ok {
+		tmpl, ok = r.templates[op]
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown prompt operation: %s", op)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", op, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// GenerateTest renders the prompt for generating a test
+func (r *Renderer) GenerateTest(params types.GenerateTestParams) (string, error) {
+	params = prompt_utils.FitParams(params, MaxPromptTokens-promptOverhead)
+
+	xmlParams, err := prompt_utils.StructToXMLString(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to format params: %w", err)
+	}
+
+	return r.Render(OperationGenerateTest, params.Example.Type, Data{
+		IterateTestParams: types.IterateTestParams{GenerateTestParams: params},
+		Params:            xmlParams,
+	})
+}
+
+// FixTypeErrors renders the prompt for fixing type errors in a generated test
+func (r *Renderer) FixTypeErrors(params types.IterateTestParams) (string, error) {
+	return r.iterate(OperationFixTypeErrors, params)
+}
+
+// FixTestFailures renders the prompt for fixing failures in a generated test
+func (r *Renderer) FixTestFailures(params types.IterateTestParams) (string, error) {
+	return r.iterate(OperationFixTestFailures, params)
+}
+
+func (r *Renderer) iterate(op Operation, params types.IterateTestParams) (string, error) {
+	budget := MaxPromptTokens - promptOverhead - prompt_utils.EstimateTokens(params.TestCode) - prompt_utils.EstimateTokens(strings.Join(params.Errors, "\n"))
+	params.GenerateTestParams = prompt_utils.FitParams(params.GenerateTestParams, budget)
+
+	xmlParams, err := prompt_utils.StructToXMLString(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to format params: %w", err)
+	}
+
+	return r.Render(op, params.Example.Type, Data{
+		IterateTestParams: params,
+		Params:            xmlParams,
+	})
+}
+
+// PickExample renders the prompt for choosing the best example for a source file.
+// The example type is not known yet, so only project overrides apply.
+func (r *Renderer) PickExample(sourceCode string, examples []types.TestExample) (string, error) {
+	sourceCode = prompt_utils.Truncate("source code", sourceCode, MaxPromptTokens-promptOverhead)
+
+	return r.Render(OperationPickExample, "", Data{
+		IterateTestParams: types.IterateTestParams{
+			GenerateTestParams: types.GenerateTestParams{SourceCode: sourceCode},
+		},
+		Examples: examples,
+	})
+}
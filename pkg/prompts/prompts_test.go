@@ -0,0 +1,107 @@
+package prompts
+
+import (
+	"testing"
+
+	"github.com/gwkline/artestian/pkg/golang"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderer_GenerateTest(t *testing.T) {
+	params := types.GenerateTestParams{
+		Language:   golang.NewGoSupport(),
+		TestRunner: &golang.GoTestRunner{},
+		TestPath:   "/project/pkg/math/math_test.go",
+		Function:   types.Function{Name: "Multiply", Receiver: "Calculator"},
+		SourceCode: "func (c Calculator) Multiply(a, b int) int { return a * b }",
+		Example:    types.TestExample{Name: "table test", Type: types.TestTypeUnit},
+	}
+
+	tests := []struct {
+		name      string
+		overrides types.PromptTemplates
+		contains  []string
+		excludes  []string
+	}{
+		{
+			name: "built-in template",
+			contains: []string{
+				"Generate a test for the function provided.",
+				"the current working directory is /project/pkg/math/math_test.go",
+				"<source_code>",
+			},
+		},
+		{
+			name: "project override",
+			overrides: types.PromptTemplates{
+				Templates: map[string]string{
+					"generate_test": "Test {{.Function.Receiver}}.{{.Function.Name}} with {{.Language.GetName}}, mocks are fine",
+				},
+			},
+			contains: []string{"Test Calculator.Multiply with go, mocks are fine"},
+			excludes: []string{"basically never use mocks"},
+		},
+		{
+			name: "example type override wins over project override",
+			overrides: types.PromptTemplates{
+				Templates: map[string]string{"generate_test": "project"},
+				ByExampleType: map[types.TestType]map[string]string{
+					types.TestTypeUnit: {"generate_test": "unit {{.Example.Name}}"},
+				},
+			},
+			contains: []string{"unit table test"},
+			excludes: []string{"project"},
+		},
+		{
+			name: "override for another example type is ignored",
+			overrides: types.PromptTemplates{
+				ByExampleType: map[types.TestType]map[string]string{
+					types.TestTypeIntegration: {"generate_test": "integration"},
+				},
+			},
+			contains: []string{"Generate a test for the function provided."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderer, err := NewRenderer(tt.overrides)
+			assert.NoError(t, err)
+
+			prompt, err := renderer.GenerateTest(params)
+			assert.NoError(t, err)
+
+			for _, s := range tt.contains {
+				assert.Contains(t, prompt, s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, prompt, s)
+			}
+		})
+	}
+}
+
+func TestRenderer_PickExample(t *testing.T) {
+	renderer, err := NewRenderer(types.PromptTemplates{})
+	assert.NoError(t, err)
+
+	prompt, err := renderer.PickExample("func Add(a, b int) int", []types.TestExample{
+		{Name: "table", Type: types.TestTypeUnit, Description: "table driven", SourceCode: "secret"},
+		{Name: "db", Type: types.TestTypeIntegration, Description: "uses a database"},
+	})
+	assert.NoError(t, err)
+
+	assert.Contains(t, prompt, "func Add(a, b int) int")
+	assert.Contains(t, prompt, "0. table (unit): table driven")
+	assert.Contains(t, prompt, "1. db (integration): uses a database")
+	assert.NotContains(t, prompt, "secret")
+}
+
+func TestNewRenderer_InvalidTemplate(t *testing.T) {
+	_, err := NewRenderer(types.PromptTemplates{
+		Templates: map[string]string{"fix_type_errors": "{{.Errors"},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "fix_type_errors")
+}
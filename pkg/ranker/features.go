@@ -0,0 +1,127 @@
+package ranker
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// profile holds the structural features of a piece of code that examples are compared on
+type profile struct {
+	imports   map[string]bool // Import paths, plus their last segment
+	database  bool
+	http      bool
+	async     bool
+	functions int
+	terms     map[string]int // Identifier parts and how often they occur
+}
+
+var (
+	goImportBlock  = regexp.MustCompile(`(?s)import\s*\((.*?)\)`)
+	goImportLine   = regexp.MustCompile(`(?m)^\s*import\s+(?:[\w.]+\s+)?"([^"]+)"`)
+	quotedPath     = regexp.MustCompile(`"([^"]+)"`)
+	tsImport       = regexp.MustCompile(`(?:from\s+|import\s+|require\(\s*)['"]([^'"]+)['"]`)
+	identifier     = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+	functionDecl   = regexp.MustCompile(`(?m)\bfunc\b|\bfunction\b|=>`)
+	databaseUsage  = regexp.MustCompile(`\b(?:database/sql|sqlx|gorm|pgx|pgxpool|mongo|mongoose|prisma|typeorm|knex|sequelize|redis|sqlmock|QueryRow|QueryContext|ExecContext|BeginTx)\b|\bdb\.|\.(?:Query|Exec)\(`)
+	httpUsage      = regexp.MustCompile(`\b(?:net/http|httptest|http\.Client|http\.Handler|http\.Request|ResponseWriter|axios|supertest|express)\b|\bfetch\(`)
+	asyncUsage     = regexp.MustCompile(`\bgo\s+(?:func\b|[\w.]+\()|\bchan\b|\bsync\.|\bselect\s*\{|\basync\b|\bawait\b|\bPromise\b|\berrgroup\b`)
+	stringLiterals = regexp.MustCompile("(?s)`[^`]*`|\"(?:[^\"\\\\\\n]|\\\\.)*\"|'(?:[^'\\\\\\n]|\\\\.)*'")
+	lineComments   = regexp.MustCompile(`(?m)//.*$`)
+)
+
+// stopWords are keywords and common words that say nothing about what code does
+var stopWords = map[string]bool{
+	"func": true, "function": true, "return": true, "package": true, "import": true, "from": true,
+	"type": true, "struct": true, "interface": true, "const": true, "var": true, "let": true,
+	"for": true, "range": true, "if": true, "else": true, "switch": true, "case": true,
+	"default": true, "break": true, "continue": true, "nil": true, "null": true, "undefined": true,
+	"true": true, "false": true, "new": true, "this": true, "string": true, "int": true,
+	"bool": true, "error": true, "err": true, "export": true, "class": true, "async": true,
+	"await": true, "the": true, "and": true, "test": true, "tests": true, "expect": true,
+	"assert": true, "describe": true, "it": true, "to": true, "be": true, "equal": true,
+}
+
+func newProfile(code string) profile {
+	p := profile{
+		imports:   importPaths(code),
+		database:  databaseUsage.MatchString(code),
+		http:      httpUsage.MatchString(code),
+		async:     asyncUsage.MatchString(code),
+		functions: len(functionDecl.FindAllString(code, -1)),
+		terms:     make(map[string]int),
+	}
+
+	// Identifiers come from code only, not from strings or comments
+	stripped := lineComments.ReplaceAllString(stringLiterals.ReplaceAllString(code, " "), " ")
+	for _, ident := range identifier.FindAllString(stripped, -1) {
+		for _, part := range splitIdentifier(ident) {
+			if len(part) < 3 || stopWords[part] {
+				continue
+			}
+			p.terms[part]++
+		}
+	}
+
+	return p
+}
+
+// importPaths returns the Go and TypeScript imports of code, each with its last path segment
+func importPaths(code string) map[string]bool {
+	var paths []string
+	for _, block := range goImportBlock.FindAllStringSubmatch(code, -1) {
+		for _, match := range quotedPath.FindAllStringSubmatch(block[1], -1) {
+			paths = append(paths, match[1])
+		}
+	}
+	for _, match := range goImportLine.FindAllStringSubmatch(code, -1) {
+		paths = append(paths, match[1])
+	}
+	for _, match := range tsImport.FindAllStringSubmatch(code, -1) {
+		paths = append(paths, match[1])
+	}
+
+	imports := make(map[string]bool, len(paths)*2)
+	for _, p := range paths {
+		imports[p] = true
+		if base := path.Base(strings.TrimSuffix(p, "/")); base != "." && base != "/" {
+			imports[base] = true
+		}
+	}
+	return imports
+}
+
+// splitIdentifier splits camelCase, PascalCase and snake_case identifiers into lowercase words
+func splitIdentifier(ident string) []string {
+	var words []string
+	var current []rune
+
+	runes := []rune(ident)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(current) > 0:
+			// Split before an upper case letter that starts a word, keeping acronyms like HTTP together
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				flush()
+			}
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+
+	return words
+}
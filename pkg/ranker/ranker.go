@@ -0,0 +1,141 @@
+package ranker
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// Weights of each feature in an example's score, summing to 1
+const (
+	weightTerms     = 0.40
+	weightImports   = 0.20
+	weightDatabase  = 0.15
+	weightHTTP      = 0.10
+	weightAsync     = 0.05
+	weightFunctions = 0.10
+)
+
+// Score is how well an example matches the source, between 0 and 1
+type Score struct {
+	Example types.TestExample
+	Index   int // Position of the example in the configured list
+	Score   float64
+}
+
+// Rank scores every example against the source code without calling a model, best first.
+// Scores compare TF-IDF weighted identifiers, imports, database, HTTP and async usage, and
+// the number of functions. Ties keep the configured order, so the ranking is deterministic.
+func Rank(sourceCode string, examples []types.TestExample) []Score {
+	source := newProfile(sourceCode)
+	profiles := make([]profile, len(examples))
+	for i, example := range examples {
+		profiles[i] = newProfile(example.SourceCode)
+	}
+
+	idf := inverseDocumentFrequency(append([]profile{source}, profiles...))
+	sourceVector := tfidf(source.terms, idf)
+
+	scores := make([]Score, len(examples))
+	for i, example := range examples {
+		p := profiles[i]
+		score := weightTerms*cosine(sourceVector, tfidf(p.terms, idf)) +
+			weightImports*jaccard(source.imports, p.imports) +
+			weightDatabase*same(source.database, p.database) +
+			weightHTTP*same(source.http, p.http) +
+			weightAsync*same(source.async, p.async) +
+			weightFunctions*ratio(source.functions, p.functions)
+
+		scores[i] = Score{Example: example, Index: i, Score: score}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+	return scores
+}
+
+// Best returns the highest scoring example, or false if there are none
+func Best(sourceCode string, examples []types.TestExample) (types.TestExample, bool) {
+	if len(examples) == 0 {
+		return types.TestExample{}, false
+	}
+	return Rank(sourceCode, examples)[0].Example, true
+}
+
+func inverseDocumentFrequency(profiles []profile) map[string]float64 {
+	documents := make(map[string]int)
+	for _, p := range profiles {
+		for term := range p.terms {
+			documents[term]++
+		}
+	}
+
+	idf := make(map[string]float64, len(documents))
+	for term, count := range documents {
+		// Smoothed, so terms found in every document still count a little
+		idf[term] = math.Log(float64(1+len(profiles))/float64(1+count)) + 1
+	}
+	return idf
+}
+
+func tfidf(terms map[string]int, idf map[string]float64) map[string]float64 {
+	vector := make(map[string]float64, len(terms))
+	for term, count := range terms {
+		vector[term] = (1 + math.Log(float64(count))) * idf[term]
+	}
+	return vector
+}
+
+// cosine sums in term order, so equal inputs always give bit-for-bit equal scores
+func cosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for _, term := range sortedTerms(a) {
+		dot += a[term] * b[term]
+		normA += a[term] * a[term]
+	}
+	for _, term := range sortedTerms(b) {
+		normB += b[term] * b[term]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+func sortedTerms(vector map[string]float64) []string {
+	terms := make([]string, 0, len(vector))
+	for term := range vector {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for key := range a {
+		if b[key] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func same(a, b bool) float64 {
+	if a == b {
+		return 1
+	}
+	return 0
+}
+
+func ratio(a, b int) float64 {
+	if a == 0 && b == 0 {
+		return 1
+	}
+	return float64(min(a, b)) / float64(max(a, b))
+}
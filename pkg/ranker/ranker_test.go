@@ -0,0 +1,181 @@
+package ranker
+
+import (
+	"testing"
+
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var examples = []types.TestExample{
+	{
+		Name: "pure function",
+		Type: types.TestTypeUnit,
+		SourceCode: `package mathutil
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	tests := []struct{ a, b, expected int }{{1, 2, 3}}
+	for _, tt := range tests {
+		if got := Add(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Add() = %d", got)
+		}
+	}
+}`,
+	},
+	{
+		Name: "repository",
+		Type: types.TestTypeIntegration,
+		SourceCode: `package store
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUserRepository_FindUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	repo := NewUserRepository(db)
+	repo.FindUser(1)
+}`,
+	},
+	{
+		Name: "handler",
+		Type: types.TestTypeIntegration,
+		SourceCode: `package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	HealthHandler(rec, req)
+}`,
+	},
+	{
+		Name: "worker",
+		Type: types.TestTypeWorker,
+		SourceCode: `import { processQueue } from '../worker';
+
+describe('processQueue', () => {
+  it('drains the queue', async () => {
+    await processQueue(queue);
+  });
+});`,
+	},
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourceCode string
+		expected   string
+	}{
+		{
+			name: "database code picks the repository example",
+			sourceCode: `package store
+
+import "database/sql"
+
+type UserRepository struct{ db *sql.DB }
+
+func (r *UserRepository) FindUser(id int) (User, error) {
+	row := r.db.QueryRow("SELECT name FROM users WHERE id = $1", id)
+	var user User
+	return user, row.Scan(&user.Name)
+}`,
+			expected: "repository",
+		},
+		{
+			name: "http code picks the handler example",
+			sourceCode: `package api
+
+import "net/http"
+
+func StatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}`,
+			expected: "handler",
+		},
+		{
+			name: "async code picks the worker example",
+			sourceCode: `export async function processQueue(queue: Queue): Promise<void> {
+  while (queue.length) {
+    await queue.pop();
+  }
+}`,
+			expected: "worker",
+		},
+		{
+			name: "plain code picks the pure function example",
+			sourceCode: `package mathutil
+
+func Add(a, b int) int {
+	return a + b
+}`,
+			expected: "pure function",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := Rank(tt.sourceCode, examples)
+
+			assert.Len(t, scores, len(examples))
+			assert.Equal(t, tt.expected, scores[0].Example.Name)
+			for i := 1; i < len(scores); i++ {
+				assert.GreaterOrEqual(t, scores[i-1].Score, scores[i].Score)
+			}
+
+			// The same input always ranks the same way
+			assert.Equal(t, scores, Rank(tt.sourceCode, examples))
+		})
+	}
+}
+
+func TestRank_TiesKeepConfiguredOrder(t *testing.T) {
+	identical := []types.TestExample{
+		{Name: "first", SourceCode: "func TestA() {}"},
+		{Name: "second", SourceCode: "func TestA() {}"},
+	}
+
+	scores := Rank("func A() {}", identical)
+	assert.Equal(t, "first", scores[0].Example.Name)
+	assert.Equal(t, 0, scores[0].Index)
+	assert.Equal(t, scores[0].Score, scores[1].Score)
+}
+
+func TestBest(t *testing.T) {
+	_, ok := Best("func A() {}", nil)
+	assert.False(t, ok)
+
+	example, ok := Best("func A() {}", examples[:1])
+	assert.True(t, ok)
+	assert.Equal(t, "pure function", example.Name)
+}
+
+func TestSplitIdentifier(t *testing.T) {
+	tests := []struct {
+		ident    string
+		expected []string
+	}{
+		{ident: "findUser", expected: []string{"find", "user"}},
+		{ident: "HTTPServer", expected: []string{"http", "server"}},
+		{ident: "user_id2name", expected: []string{"user", "id", "name"}},
+		{ident: "ID", expected: []string{"id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ident, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitIdentifier(tt.ident))
+		})
+	}
+}
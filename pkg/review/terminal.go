@@ -0,0 +1,215 @@
+package review
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// Terminal reviews tests with plain line-based prompts, so it works in any terminal,
+// including over SSH
+type Terminal struct {
+	in     *bufio.Reader
+	out    io.Writer
+	editor string // Shell command the test file's path is appended to
+	count  int
+}
+
+// NewTerminal returns a reviewer that reads choices from in and writes to out. Tests are
+// edited with $VISUAL or $EDITOR, falling back to vi.
+func NewTerminal(in io.Reader, out io.Writer) *Terminal {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	return &Terminal{in: bufio.NewReader(in), out: out, editor: editor}
+}
+
+// Review shows the test and asks what to do with it. Closing the input quits.
+func (t *Terminal) Review(ctx context.Context, item types.ReviewItem) (types.ReviewDecision, error) {
+	t.count++
+	t.show(item)
+
+	for {
+		choice, err := t.ask(ctx, "[a]ccept, [r]eject, re[g]enerate, [e]dit, [q]uit: ")
+		if err == io.EOF {
+			return types.ReviewDecision{Action: types.ReviewQuit}, nil
+		}
+		if err != nil {
+			return types.ReviewDecision{}, err
+		}
+
+		switch strings.ToLower(choice) {
+		case "a", "accept":
+			return types.ReviewDecision{Action: types.ReviewAccept}, nil
+		case "r", "reject":
+			return types.ReviewDecision{Action: types.ReviewReject}, nil
+		case "q", "quit":
+			return types.ReviewDecision{Action: types.ReviewQuit}, nil
+		case "g", "regenerate":
+			instruction, err := t.ask(ctx, "Instruction for the model: ")
+			if err != nil && err != io.EOF {
+				return types.ReviewDecision{}, err
+			}
+			if instruction == "" {
+				fmt.Fprintln(t.out, "An instruction is required to regenerate the test.")
+				continue
+			}
+			return types.ReviewDecision{Action: types.ReviewRegenerate, Instruction: instruction}, nil
+		case "e", "edit":
+			testCode, err := t.edit(item)
+			if err != nil {
+				fmt.Fprintf(t.out, "Editing failed: %v\n", err)
+				continue
+			}
+			return types.ReviewDecision{Action: types.ReviewEdit, TestCode: testCode}, nil
+		default:
+			fmt.Fprintf(t.out, "Unknown choice %q.\n", choice)
+		}
+	}
+}
+
+// show prints the function, the result and the test code with line numbers
+func (t *Terminal) show(item types.ReviewItem) {
+	fmt.Fprintf(t.out, "\n=== Review %d: %s in %s ===\n", t.count, functionName(item.Function), item.SourcePath)
+	fmt.Fprintf(t.out, "Result: %s\n", summary(item.Result))
+	if item.MutationScore != nil {
+		fmt.Fprintf(t.out, "Mutation score: %.0f%%\n", *item.MutationScore*100)
+	}
+	for _, problem := range problems(item.Result) {
+		fmt.Fprintf(t.out, "  %s\n", problem)
+	}
+
+	fmt.Fprintln(t.out, strings.Repeat("-", 72))
+	lines := strings.Split(strings.TrimRight(item.TestCode, "\n"), "\n")
+	width := len(fmt.Sprint(len(lines)))
+	for i, line := range lines {
+		fmt.Fprintf(t.out, "%*d | %s\n", width, i+1, line)
+	}
+	fmt.Fprintln(t.out, strings.Repeat("-", 72))
+}
+
+// ask prints prompt and reads a line, giving up when ctx is done
+func (t *Terminal) ask(ctx context.Context, prompt string) (string, error) {
+	fmt.Fprint(t.out, prompt)
+
+	type line struct {
+		text string
+		err  error
+	}
+	lines := make(chan line, 1)
+	go func() {
+		text, err := t.in.ReadString('\n')
+		lines <- line{text, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(t.out)
+		return "", ctx.Err()
+	case l := <-lines:
+		text := strings.TrimSpace(l.text)
+		if l.err == io.EOF && text != "" {
+			return text, nil
+		}
+		return text, l.err
+	}
+}
+
+// edit opens the test in the editor, attached to the terminal, and returns the result
+func (t *Terminal) edit(item types.ReviewItem) (string, error) {
+	dir, err := os.MkdirTemp("", "artestian-review-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	// Keep the test's file name, so the editor recognizes the language
+	path := filepath.Join(dir, filepath.Base(item.TestPath))
+	if err := os.WriteFile(path, []byte(item.TestCode), 0644); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("sh", "-c", t.editor+` "$1"`, "sh", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w", t.editor, err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// summary describes a result in a few words
+func summary(result types.RunResult) string {
+	failed := 0
+	for _, test := range result.Tests {
+		if test.Status == types.TestStatusFail {
+			failed++
+		}
+	}
+
+	switch {
+	case result.TimedOut:
+		return "timed out"
+	case result.Passed && len(result.Tests) > 0:
+		return "passed, " + plural(len(result.Tests), "test")
+	case result.Passed:
+		return "passed"
+	case len(result.Diagnostics) > 0:
+		return "failed with " + plural(len(result.Diagnostics), "error")
+	case result.Panic != "":
+		return "panicked"
+	default:
+		return fmt.Sprintf("failed, %d of %s", failed, plural(len(result.Tests), "test"))
+	}
+}
+
+// problems lists the errors and failures in a result, one line each
+func problems(result types.RunResult) []string {
+	var lines []string
+	for _, d := range result.Diagnostics {
+		lines = append(lines, fmt.Sprintf("%s:%d: %s", filepath.Base(d.File), d.Line, d.Message))
+	}
+	for _, test := range result.Tests {
+		if test.Status != types.TestStatusFail {
+			continue
+		}
+		failure := ""
+		if len(test.Failures) > 0 {
+			failure = ": " + strings.TrimSpace(strings.SplitN(test.Failures[0], "\n", 2)[0])
+		}
+		lines = append(lines, test.Name+" failed"+failure)
+	}
+	if result.Panic != "" {
+		lines = append(lines, "panic: "+strings.SplitN(result.Panic, "\n", 2)[0])
+	}
+	return lines
+}
+
+func functionName(function types.Function) string {
+	if function.Receiver == "" {
+		return function.Name
+	}
+	return function.Receiver + "." + function.Name
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,119 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gwkline/artestian/types"
+)
+
+func TestTerminal_Review(t *testing.T) {
+	score := 0.75
+	item := types.ReviewItem{
+		SourcePath:    "pkg/math.go",
+		TestPath:      "/tmp/project/pkg/Add123_test.go",
+		Function:      types.Function{Name: "Add", Receiver: "Calculator"},
+		TestCode:      "package pkg\n\nfunc TestAdd(t *testing.T) {}\n",
+		Result:        types.RunResult{Passed: true, Tests: []types.TestCase{{Name: "TestAdd", Status: types.TestStatusPass}}},
+		MutationScore: &score,
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		editor   string
+		expected types.ReviewDecision
+		output   string // Expected in the output after the test is shown
+	}{
+		{
+			name:     "accept",
+			input:    "a\n",
+			expected: types.ReviewDecision{Action: types.ReviewAccept},
+		},
+		{
+			name:     "reject after an unknown choice",
+			input:    "x\nreject\n",
+			expected: types.ReviewDecision{Action: types.ReviewReject},
+			output:   `Unknown choice "x".`,
+		},
+		{
+			name:     "regenerate",
+			input:    "g\n\ng\nuse table tests\n",
+			expected: types.ReviewDecision{Action: types.ReviewRegenerate, Instruction: "use table tests"},
+			output:   "An instruction is required",
+		},
+		{
+			name:     "edit",
+			input:    "e\n",
+			editor:   "sed -i 's/TestAdd/TestCalculatorAdd/'",
+			expected: types.ReviewDecision{Action: types.ReviewEdit, TestCode: "package pkg\n\nfunc TestCalculatorAdd(t *testing.T) {}\n"},
+		},
+		{
+			name:     "editor fails",
+			input:    "e\nq\n",
+			editor:   "false",
+			expected: types.ReviewDecision{Action: types.ReviewQuit},
+			output:   "Editing failed",
+		},
+		{
+			name:     "closed input quits",
+			input:    "",
+			expected: types.ReviewDecision{Action: types.ReviewQuit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			terminal := NewTerminal(strings.NewReader(tt.input), &out)
+			if tt.editor != "" {
+				terminal.editor = tt.editor
+			}
+
+			decision, err := terminal.Review(context.Background(), item)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, decision)
+
+			assert.Contains(t, out.String(), "=== Review 1: Calculator.Add in pkg/math.go ===\n"+
+				"Result: passed, 1 test\n"+
+				"Mutation score: 75%\n")
+			assert.Contains(t, out.String(), "1 | package pkg\n2 | \n3 | func TestAdd(t *testing.T) {}\n")
+			assert.Contains(t, out.String(), tt.output)
+		})
+	}
+}
+
+func TestTerminal_Review_Cancelled(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewTerminal(reader, io.Discard).Review(ctx, types.ReviewItem{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestProblems(t *testing.T) {
+	result := types.RunResult{
+		Diagnostics: []types.Diagnostic{{Location: types.Location{File: "/tmp/pkg/add_test.go", Line: 3}, Message: "undefined: Sub"}},
+		Tests: []types.TestCase{
+			{Name: "TestAdd", Status: types.TestStatusPass},
+			{Name: "TestSub", Status: types.TestStatusFail, Failures: []string{"expected 1, got 2\nmore detail"}},
+		},
+		Panic: "runtime error: index out of range\ngoroutine 1",
+	}
+
+	assert.Equal(t, "failed with 1 error", summary(result))
+	assert.Equal(t, []string{
+		"add_test.go:3: undefined: Sub",
+		"TestSub failed: expected 1, got 2",
+		"panic: runtime error: index out of range",
+	}, problems(result))
+}
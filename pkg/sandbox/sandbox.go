@@ -0,0 +1,313 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gwkline/artestian/pkg/command"
+	"github.com/gwkline/artestian/pkg/workspace"
+)
+
+// Mode is how commands are isolated
+type Mode string
+
+const (
+	ModeAuto       Mode = "auto"       // The strongest mode available
+	ModeBwrap      Mode = "bwrap"      // bubblewrap: read-only filesystem, no network, own pid namespace
+	ModeNamespaces Mode = "namespaces" // unshare: read-only project, no network, own pid namespace
+	ModeCopy       Mode = "copy"       // A scratch copy of the project, with no other isolation
+)
+
+// Options configures how commands are isolated
+type Options struct {
+	Mode         Mode
+	Root         string        // Project root, which commands may only read
+	Writable     []string      // Directories outside the project commands may write to, e.g. build caches
+	CPUTime      time.Duration // CPU time limit per process, zero for none
+	MemoryMB     int           // Address space limit per process in MiB, zero for none
+	MaxProcesses int           // Process limit, zero for none
+	Timeout      time.Duration // Wall-clock limit per command, zero for none
+
+	// Workspace is the scratch copy Root is in, if any. ModeCopy then runs commands in it
+	// directly, instead of copying it again.
+	Workspace *workspace.Workspace
+}
+
+// Sandbox runs test and type check commands isolated from the project and the network.
+// A nil *Sandbox runs commands directly.
+type Sandbox struct {
+	options Options
+	mode    Mode
+
+	mu      sync.Mutex
+	scratch *workspace.Workspace // Copy of Root in ModeCopy, created by the first command
+}
+
+// New returns a sandbox using options.Mode, or the strongest mode available for ModeAuto
+func New(options Options) (*Sandbox, error) {
+	root, err := filepath.Abs(options.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox root: %w", err)
+	}
+	options.Root = root
+
+	mode := options.Mode
+	switch mode {
+	case "", ModeAuto:
+		switch {
+		case available(ModeBwrap):
+			mode = ModeBwrap
+		case available(ModeNamespaces):
+			mode = ModeNamespaces
+		default:
+			mode = ModeCopy
+			slog.Warn("neither bubblewrap nor user namespaces are available, running tests in a scratch copy of the project without network isolation")
+		}
+	case ModeBwrap, ModeNamespaces:
+		if !available(mode) {
+			return nil, fmt.Errorf("sandbox mode %s is not available on this system", mode)
+		}
+	case ModeCopy:
+	default:
+		return nil, fmt.Errorf("unknown sandbox mode: %s", mode)
+	}
+
+	if hasLimits(options) {
+		if _, err := exec.LookPath("prlimit"); err != nil {
+			slog.Warn("prlimit not found, running sandboxed commands without CPU, memory and process limits")
+			options.CPUTime, options.MemoryMB, options.MaxProcesses = 0, 0, 0
+		}
+	}
+
+	slog.Debug("sandbox ready", "mode", mode, "root", root)
+	return &Sandbox{options: options, mode: mode}, nil
+}
+
+// Mode returns the mode commands are isolated with
+func (s *Sandbox) Mode() Mode {
+	if s == nil {
+		return ""
+	}
+	return s.mode
+}
+
+// WithTimeout limits ctx to the sandbox's wall-clock timeout, so a command that runs
+// too long is killed and reported as timed out
+func (s *Sandbox) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s == nil || s.options.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.options.Timeout)
+}
+
+// Command returns a command like command.New, run inside the sandbox. testDir is the only
+// directory in the project the command may write to.
+func (s *Sandbox) Command(ctx context.Context, testDir, dir, name string, args ...string) (*exec.Cmd, error) {
+	if s == nil {
+		return command.New(ctx, dir, name, args...), nil
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve command directory: %w", err)
+	}
+	if !within(s.options.Root, dir) {
+		return nil, fmt.Errorf("sandboxed command directory %s is outside the project root %s", dir, s.options.Root)
+	}
+	if testDir, err = filepath.Abs(testDir); err != nil {
+		return nil, fmt.Errorf("failed to resolve test directory: %w", err)
+	}
+
+	argv := append([]string{name}, args...)
+	switch s.mode {
+	case ModeBwrap:
+		argv = append(s.bwrapArgs(testDir, dir), argv...)
+	case ModeNamespaces:
+		argv = append(s.unshareArgs(testDir, dir), argv...)
+	case ModeCopy:
+		if s.options.Workspace != nil {
+			break
+		}
+		scratch, err := s.sync()
+		if err != nil {
+			return nil, err
+		}
+		if dir, err = scratch.Path(dir); err != nil {
+			return nil, err
+		}
+		for i, arg := range argv {
+			if filepath.IsAbs(arg) && within(s.options.Root, arg) {
+				if argv[i], err = scratch.Path(arg); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	argv = append(s.limitArgs(), argv...)
+
+	return command.New(ctx, dir, argv[0], argv[1:]...), nil
+}
+
+// RealPaths rewrites paths in a command's output from the scratch copy back to the
+// project, so results point at the files the generator knows about
+func (s *Sandbox) RealPaths(output string) string {
+	if s == nil || s.mode != ModeCopy {
+		return output
+	}
+	s.mu.Lock()
+	scratch := s.scratch
+	s.mu.Unlock()
+	if scratch == nil {
+		return output
+	}
+	return scratch.RealPaths(output)
+}
+
+// Close removes the scratch copy, if any
+func (s *Sandbox) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scratch == nil {
+		return nil
+	}
+	err := s.scratch.Close()
+	s.scratch = nil
+	return err
+}
+
+// sync brings the scratch copy up to date with the project, creating it on first use.
+// Files a previous command changed or created in the copy are reset, so every command
+// starts from the project as it is.
+func (s *Sandbox) sync() (*workspace.Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scratch == nil {
+		scratch, err := workspace.New(s.options.Root)
+		if err != nil {
+			return nil, err
+		}
+		s.scratch = scratch
+		return scratch, nil
+	}
+	return s.scratch, s.scratch.Sync()
+}
+
+// bwrapArgs mounts the whole filesystem read-only with a private /tmp, and makes the
+// test directory and the writable directories writable again
+func (s *Sandbox) bwrapArgs(testDir, dir string) []string {
+	args := []string{
+		"bwrap",
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--unshare-net", "--unshare-pid", "--unshare-ipc",
+		"--die-with-parent", "--new-session",
+	}
+	for _, writable := range s.writable() {
+		args = append(args, "--bind", writable, writable)
+	}
+	// The project is read-only even when it sits in a writable directory
+	args = append(args, "--ro-bind", s.options.Root, s.options.Root)
+	if within(s.options.Root, testDir) {
+		args = append(args, "--bind", testDir, testDir)
+	}
+	return append(args, "--chdir", dir, "--")
+}
+
+// unshareArgs runs the command in new user, mount, network and pid namespaces, where a
+// shell remounts the project read-only before running it
+func (s *Sandbox) unshareArgs(testDir, dir string) []string {
+	root := quote(s.options.Root)
+	script := []string{"set -e", "mount --bind " + root + " " + root}
+	if within(s.options.Root, testDir) {
+		script = append(script, "mount --bind "+quote(testDir)+" "+quote(testDir))
+	}
+	script = append(script,
+		"mount -o remount,bind,ro "+root,
+		// The working directory still points at the writable mount until it's entered again
+		"cd "+quote(dir),
+		`exec "$@"`,
+	)
+	return []string{
+		"unshare", "--user", "--map-root-user", "--mount", "--net", "--pid", "--fork", "--kill-child", "--mount-proc",
+		"--", "sh", "-c", strings.Join(script, "\n"), "sh",
+	}
+}
+
+// limitArgs applies the CPU, memory and process limits with prlimit
+func (s *Sandbox) limitArgs() []string {
+	if !hasLimits(s.options) {
+		return nil
+	}
+	args := []string{"prlimit"}
+	if s.options.CPUTime > 0 {
+		args = append(args, "--cpu="+strconv.Itoa(int(max(1, s.options.CPUTime.Seconds()))))
+	}
+	if s.options.MemoryMB > 0 {
+		args = append(args, "--as="+strconv.FormatInt(int64(s.options.MemoryMB)<<20, 10))
+	}
+	if s.options.MaxProcesses > 0 {
+		args = append(args, "--nproc="+strconv.Itoa(s.options.MaxProcesses))
+	}
+	return append(args, "--")
+}
+
+// writable returns the existing writable directories, including the temp directory when
+// it isn't /tmp
+func (s *Sandbox) writable() []string {
+	dirs := s.options.Writable
+	if tmp := os.TempDir(); tmp != "/tmp" {
+		dirs = append(dirs, tmp)
+	}
+
+	var existing []string
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			existing = append(existing, dir)
+		}
+	}
+	return existing
+}
+
+func hasLimits(options Options) bool {
+	return options.CPUTime > 0 || options.MemoryMB > 0 || options.MaxProcesses > 0
+}
+
+// available reports whether mode works here, by running true inside it
+func available(mode Mode) bool {
+	var args []string
+	switch mode {
+	case ModeBwrap:
+		args = []string{"bwrap", "--ro-bind", "/", "/", "--unshare-net", "--unshare-pid", "true"}
+	case ModeNamespaces:
+		args = []string{"unshare", "--user", "--map-root-user", "--mount", "--net", "--pid", "--fork", "true"}
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return false
+	}
+	return exec.Command(args[0], args[1:]...).Run() == nil
+}
+
+// within reports whether path is dir or inside it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// quote quotes s for sh
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,219 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gwkline/artestian/pkg/workspace"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestSandbox_Command(t *testing.T) {
+	root := t.TempDir()
+	cache := t.TempDir()
+	testDir := filepath.Join(root, "pkg")
+
+	tests := []struct {
+		name     string
+		options  Options
+		mode     Mode
+		expected []string
+	}{
+		{
+			name:    "bwrap",
+			options: Options{Root: root, Writable: []string{cache, filepath.Join(cache, "missing")}},
+			mode:    ModeBwrap,
+			expected: []string{
+				"bwrap", "--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp",
+				"--unshare-net", "--unshare-pid", "--unshare-ipc", "--die-with-parent", "--new-session",
+				"--bind", cache, cache,
+				"--ro-bind", root, root,
+				"--bind", testDir, testDir,
+				"--chdir", root, "--",
+				"go", "test", "./pkg",
+			},
+		},
+		{
+			name:    "namespaces with limits",
+			options: Options{Root: root, CPUTime: 90 * time.Second, MemoryMB: 512, MaxProcesses: 64},
+			mode:    ModeNamespaces,
+			expected: []string{
+				"prlimit", "--cpu=90", "--as=536870912", "--nproc=64", "--",
+				"unshare", "--user", "--map-root-user", "--mount", "--net", "--pid", "--fork", "--kill-child", "--mount-proc",
+				"--", "sh", "-c", strings.Join([]string{
+					"set -e",
+					"mount --bind '" + root + "' '" + root + "'",
+					"mount --bind '" + testDir + "' '" + testDir + "'",
+					"mount -o remount,bind,ro '" + root + "'",
+					"cd '" + root + "'",
+					`exec "$@"`,
+				}, "\n"), "sh",
+				"go", "test", "./pkg",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMPDIR", "/tmp")
+			s := &Sandbox{options: tt.options, mode: tt.mode}
+
+			cmd, err := s.Command(context.Background(), testDir, root, "go", "test", "./pkg")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cmd.Args)
+			assert.Equal(t, root, cmd.Dir)
+		})
+	}
+}
+
+func TestSandbox_Command_OutsideRoot(t *testing.T) {
+	s := &Sandbox{options: Options{Root: t.TempDir()}, mode: ModeBwrap}
+	_, err := s.Command(context.Background(), t.TempDir(), t.TempDir(), "go", "test")
+	assert.ErrorContains(t, err, "outside the project root")
+}
+
+func TestSandbox_Nil(t *testing.T) {
+	var s *Sandbox
+	dir := t.TempDir()
+
+	cmd, err := s.Command(context.Background(), dir, dir, "go", "test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "test"}, cmd.Args)
+	assert.Equal(t, "output", s.RealPaths("output"))
+	assert.NoError(t, s.Close())
+}
+
+func TestSandbox_Copy(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":                "module example.com/app\n",
+		"pkg/add.go":            "package pkg\n",
+		".git/HEAD":             "ref: refs/heads/main\n",
+		"node_modules/x/add.js": "module.exports = 1\n",
+	})
+
+	s, err := New(Options{Mode: ModeCopy, Root: root})
+	require.NoError(t, err)
+	defer s.Close()
+
+	run := func(script string) string {
+		t.Helper()
+		cmd, err := s.Command(context.Background(), filepath.Join(root, "pkg"), filepath.Join(root, "pkg"), "sh", "-c", script, "sh", filepath.Join(root, "pkg", "add.go"))
+		require.NoError(t, err)
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return s.RealPaths(string(output))
+	}
+
+	// Commands run in the copy and can't change the project
+	output := run(`pwd; cat "$1"; ls -a ..; echo changed > "$1"; touch created.go`)
+	assert.Contains(t, output, filepath.Join(root, "pkg")+"\n")
+	assert.Contains(t, output, "package pkg\n")
+	assert.NotContains(t, output, ".git")
+	assert.Contains(t, output, "node_modules")
+	content, err := os.ReadFile(filepath.Join(root, "pkg", "add.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg\n", string(content))
+	assert.NoFileExists(t, filepath.Join(root, "pkg", "created.go"))
+
+	// The next command sees the project's changes, and not the previous command's
+	writeFiles(t, root, map[string]string{"pkg/add_test.go": "package pkg\n"})
+	output = run(`cat "$1"; ls`)
+	assert.Equal(t, "package pkg\nadd.go\nadd_test.go\n", output)
+
+	require.NoError(t, os.Remove(filepath.Join(root, "pkg", "add_test.go")))
+	assert.Equal(t, "add.go\n", run("ls"))
+
+	scratch := s.scratch.Dir()
+	require.NoError(t, s.Close())
+	assert.NoDirExists(t, scratch)
+}
+
+func TestSandbox_Copy_Workspace(t *testing.T) {
+	project := t.TempDir()
+	writeFiles(t, project, map[string]string{"pkg/add.go": "package pkg\n"})
+	ws, err := workspace.New(project)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	s, err := New(Options{Mode: ModeCopy, Root: ws.Dir(), Workspace: ws})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Commands run in the workspace itself, without copying it again
+	testDir := filepath.Join(ws.Dir(), "pkg")
+	cmd, err := s.Command(context.Background(), testDir, testDir, "sh", "-c", "pwd; touch add_test.go")
+	require.NoError(t, err)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	assert.Equal(t, testDir+"\n", s.RealPaths(string(output)))
+	assert.FileExists(t, filepath.Join(testDir, "add_test.go"))
+	assert.NoFileExists(t, filepath.Join(project, "pkg", "add_test.go"))
+	assert.Nil(t, s.scratch)
+}
+
+func TestSandbox_Isolation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping sandbox isolation test in short mode")
+	}
+
+	for _, mode := range []Mode{ModeBwrap, ModeNamespaces} {
+		t.Run(string(mode), func(t *testing.T) {
+			if !available(mode) {
+				t.Skipf("%s is not available", mode)
+			}
+
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{"pkg/add.go": "package pkg\n"})
+			testDir := filepath.Join(root, "pkg")
+
+			s, err := New(Options{Mode: mode, Root: root, MaxProcesses: 1024})
+			require.NoError(t, err)
+
+			cmd, err := s.Command(context.Background(), testDir, testDir, "sh", "-c", `
+				touch ../escaped 2>/dev/null && echo project writable
+				touch add_test.go && echo test dir writable
+				echo pid $$
+				cat /proc/net/dev | grep -v lo: | tail -n +3 | wc -l`)
+			require.NoError(t, err)
+			output, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(output))
+
+			assert.NotContains(t, string(output), "project writable")
+			assert.Contains(t, string(output), "test dir writable")
+			assert.Contains(t, string(output), "pid 1\n")
+			assert.True(t, strings.HasSuffix(string(output), "0\n"), "only the loopback interface is available: %s", output)
+			assert.NoFileExists(t, filepath.Join(root, "escaped"))
+			assert.FileExists(t, filepath.Join(testDir, "add_test.go"))
+		})
+	}
+}
+
+func TestSandbox_WithTimeout(t *testing.T) {
+	s := &Sandbox{options: Options{Timeout: 50 * time.Millisecond}}
+	ctx, cancel := s.WithTimeout(context.Background())
+	defer cancel()
+
+	cmd, err := (*Sandbox)(nil).Command(ctx, t.TempDir(), t.TempDir(), "sleep", "10")
+	require.NoError(t, err)
+	start := time.Now()
+	assert.Error(t, cmd.Run())
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,230 @@
+package typescript
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gwkline/artestian/pkg/command"
+	"github.com/gwkline/artestian/types"
+)
+
+// CaptureBaseline type-checks a source file before tests are generated for it and
+// remembers its diagnostics, so errors that already exist in the project are not
+// blamed on generated tests in the same directory
+func (ts *TypeScriptSupport) CaptureBaseline(ctx context.Context, sourcePath string) error {
+	diagnostics, output, failed, err := ts.checkFile(ctx, sourcePath)
+	if err != nil {
+		return err
+	}
+	if failed && len(diagnostics) == 0 {
+		return fmt.Errorf("tsc failed without diagnostics: %s", strings.TrimSpace(output))
+	}
+
+	baseline := make(map[string]bool, len(diagnostics))
+	for _, d := range diagnostics {
+		baseline[diagnosticKey(d)] = true
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.baselines[filepath.Dir(sourcePath)] = baseline
+	return nil
+}
+
+// CheckTypes type-checks only the given test file and the files it imports, using
+// the nearest tsconfig. Diagnostics in other files that were already present in the
+// baseline for its directory don't count. A tsc failure without any diagnostics, e.g.
+// when tsc is missing, fails the check.
+func (ts *TypeScriptSupport) CheckTypes(ctx context.Context, testFilePath string) (types.RunResult, error) {
+	ctx, cancel := ts.options.Sandbox.WithTimeout(ctx)
+	defer cancel()
+
+	diagnostics, output, failed, err := ts.checkFile(ctx, testFilePath)
+	if err != nil {
+		return types.RunResult{}, err
+	}
+	if command.TimedOut(ctx) {
+		return types.RunResult{Output: output, TimedOut: true}, nil
+	}
+
+	ts.mu.Lock()
+	baseline := ts.baselines[filepath.Dir(testFilePath)]
+	ts.mu.Unlock()
+
+	result := types.RunResult{
+		Output:      output,
+		Diagnostics: filterDiagnostics(diagnostics, testFilePath, baseline),
+	}
+	result.Passed = len(result.Diagnostics) == 0 && (!failed || len(diagnostics) > 0)
+	return result, nil
+}
+
+// checkFile runs tsc with filePath as the only root file and returns its
+// diagnostics with absolute file paths, its output and whether it exited non-zero
+func (ts *TypeScriptSupport) checkFile(ctx context.Context, filePath string) ([]types.Diagnostic, string, bool, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	dir := filepath.Dir(absPath)
+	// Without a project config tsc falls back to its defaults
+	args := []string{"tsc", "--noEmit", "--pretty", "false", absPath}
+	if tsconfig := findTsconfig(dir); tsconfig != "" {
+		scopedConfig, err := writeScopedConfig(tsconfig, absPath)
+		if err != nil {
+			return nil, "", false, err
+		}
+		defer os.Remove(scopedConfig)
+
+		dir = filepath.Dir(tsconfig)
+		args = []string{"tsc", "-p", scopedConfig, "--pretty", "false"}
+	}
+
+	cmd, err := ts.options.Sandbox.Command(ctx, filepath.Dir(absPath), dir, "npx", args...)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	// tsc exits non-zero when there are diagnostics, which are parsed from the output
+	combined, runErr := cmd.CombinedOutput()
+	if err := command.Canceled(ctx); err != nil {
+		return nil, "", false, err
+	}
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, "", false, fmt.Errorf("failed to run tsc: %w", runErr)
+	}
+	output := ts.options.Sandbox.RealPaths(string(combined))
+
+	diagnostics := parseDiagnostics(output)
+	for i, d := range diagnostics {
+		if d.File != "" && !filepath.IsAbs(d.File) {
+			diagnostics[i].File = filepath.Join(dir, d.File)
+		}
+	}
+	return diagnostics, output, runErr != nil, nil
+}
+
+// findTsconfig returns the nearest tsconfig.json in dir or its parents
+func findTsconfig(dir string) string {
+	for {
+		candidate := filepath.Join(dir, "tsconfig.json")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// scopedConfig is a tsconfig that extends the project config with a single root file
+type scopedConfig struct {
+	Extends         string         `json:"extends"`
+	CompilerOptions map[string]any `json:"compilerOptions"`
+	Files           []string       `json:"files"`
+	Include         []string       `json:"include"`
+}
+
+// writeScopedConfig writes a temporary tsconfig next to rootFile, extending tsconfig with
+// rootFile as its only root. It sits in the test file's directory, which is writable and
+// visible in the sandbox, and refers to both files relatively, so it still points at the
+// right files in the sandbox's copy of the project. Incremental build info is kept in the
+// user cache directory so repeated checks against the same project reuse work.
+func writeScopedConfig(tsconfig, rootFile string) (string, error) {
+	dir := filepath.Dir(rootFile)
+	extends, err := relativePath(dir, tsconfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve tsconfig: %w", err)
+	}
+	config := scopedConfig{
+		Extends: extends,
+		CompilerOptions: map[string]any{
+			"noEmit":          true,
+			"composite":       false,
+			"incremental":     true,
+			"tsBuildInfoFile": buildInfoPath(tsconfig),
+		},
+		Files:   []string{"./" + filepath.Base(rootFile)},
+		Include: []string{},
+	}
+
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal scoped tsconfig: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, ".artestian-tsconfig-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create scoped tsconfig: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(content); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write scoped tsconfig: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// relativePath returns path relative to dir in the form tsconfig expects, starting with
+// ./ or ../ so "extends" isn't looked up as a package
+func relativePath(dir, path string) (string, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel, nil
+}
+
+// buildInfoPath returns a per-project location for tsc's incremental build info
+func buildInfoPath(tsconfig string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	dir := filepath.Join(cacheDir, "artestian")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		dir = os.TempDir()
+	}
+	hash := sha256.Sum256([]byte(tsconfig))
+	return filepath.Join(dir, hex.EncodeToString(hash[:8])+".tsbuildinfo")
+}
+
+// filterDiagnostics keeps every diagnostic reported in filePath or without a file, like a
+// config error, and those in other files that are not in the baseline
+func filterDiagnostics(diagnostics []types.Diagnostic, filePath string, baseline map[string]bool) []types.Diagnostic {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		absPath = filePath
+	}
+
+	var filtered []types.Diagnostic
+	for _, d := range diagnostics {
+		if d.File != "" && filepath.Clean(d.File) != absPath && baseline[diagnosticKey(d)] {
+			continue
+		}
+		filtered = append(filtered, d)
+	}
+	return filtered
+}
+
+// diagnosticKey identifies a diagnostic by its file and message, independently of its line
+func diagnosticKey(d types.Diagnostic) string {
+	return d.File + ": " + d.Code + ": " + d.Message
+}
@@ -0,0 +1,228 @@
+package typescript
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gwkline/artestian/pkg/sandbox"
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindTsconfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "packages", "api", "src")
+	assert.NoError(t, os.MkdirAll(nested, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "tsconfig.json"), []byte("{}"), 0644))
+
+	assert.Equal(t, filepath.Join(root, "tsconfig.json"), findTsconfig(nested))
+
+	pkgConfig := filepath.Join(root, "packages", "api", "tsconfig.json")
+	assert.NoError(t, os.WriteFile(pkgConfig, []byte("{}"), 0644))
+
+	assert.Equal(t, pkgConfig, findTsconfig(nested))
+}
+
+func TestWriteScopedConfig(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	assert.NoError(t, os.MkdirAll(src, 0755))
+
+	path, err := writeScopedConfig(filepath.Join(root, "tsconfig.json"), filepath.Join(src, "sum.test.ts"))
+	assert.NoError(t, err)
+	defer os.Remove(path)
+
+	// The config sits next to the test file, and refers to both files relatively
+	assert.Equal(t, src, filepath.Dir(path))
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	var config scopedConfig
+	assert.NoError(t, json.Unmarshal(content, &config))
+	assert.Equal(t, "../tsconfig.json", config.Extends)
+	assert.Equal(t, []string{"./sum.test.ts"}, config.Files)
+	assert.Empty(t, config.Include)
+	assert.Equal(t, true, config.CompilerOptions["noEmit"])
+	assert.Equal(t, true, config.CompilerOptions["incremental"])
+	assert.NotEmpty(t, config.CompilerOptions["tsBuildInfoFile"])
+}
+
+func TestRelativePath(t *testing.T) {
+	tests := []struct {
+		dir, path, expected string
+	}{
+		{"/repo/src", "/repo/tsconfig.json", "../tsconfig.json"},
+		{"/repo", "/repo/tsconfig.json", "./tsconfig.json"},
+		{"/repo/src", "/repo/src/tsconfig.json", "./tsconfig.json"},
+	}
+	for _, tt := range tests {
+		rel, err := relativePath(tt.dir, tt.path)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, rel)
+	}
+}
+
+// fakeNpx is run as "npx tsc -p <config> --pretty false", and prints the config and the
+// config it extends
+const fakeNpx = `#!/bin/sh
+cd "$(dirname "$3")"
+cat "$3"
+cat "$(sed -n 's/.*"extends": "\(.*\)".*/\1/p' "$3")"
+`
+
+// TestCheckTypes_Sandbox checks that tsc can read the scoped config and the project
+// config it extends inside each sandbox mode, using a fake npx that prints them
+func TestCheckTypes_Sandbox(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping sandbox test in short mode")
+	}
+
+	for _, mode := range []sandbox.Mode{sandbox.ModeBwrap, sandbox.ModeNamespaces, sandbox.ModeCopy} {
+		t.Run(string(mode), func(t *testing.T) {
+			root := t.TempDir()
+			files := map[string]string{
+				"tsconfig.json":   `{"compilerOptions": {"strict": true}}`,
+				"src/sum.test.ts": "test('sum', () => {})\n",
+				"bin/npx":         fakeNpx,
+			}
+			for name, content := range files {
+				path := filepath.Join(root, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0755))
+			}
+			t.Setenv("PATH", filepath.Join(root, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			sb, err := sandbox.New(sandbox.Options{Mode: mode, Root: root})
+			if err != nil {
+				t.Skipf("%s is not available: %v", mode, err)
+			}
+			defer sb.Close()
+
+			ts := NewTypeScriptSupportWithOptions(Options{Sandbox: sb})
+			result, err := ts.CheckTypes(context.Background(), filepath.Join(root, "src", "sum.test.ts"))
+			require.NoError(t, err)
+
+			assert.Contains(t, result.Output, `"./sum.test.ts"`)
+			assert.Contains(t, result.Output, `"strict": true`)
+			// Nothing refers to the project by its absolute path, so the copy mode
+			// reads its own copy
+			assert.NotContains(t, result.Output, root)
+
+			matches, err := filepath.Glob(filepath.Join(root, "src", ".artestian-tsconfig-*"))
+			require.NoError(t, err)
+			assert.Empty(t, matches)
+		})
+	}
+}
+
+func TestFilterDiagnostics(t *testing.T) {
+	testFile := "/repo/src/sum.test.ts"
+	legacy := types.Diagnostic{
+		Location: types.Location{File: "/repo/src/legacy.ts", Line: 3, Column: 1},
+		Code:     "TS2322",
+		Message:  "Type 'string' is not assignable to type 'number'.",
+	}
+	// The test file repeats a message that's in the baseline for another file
+	repeated := types.Diagnostic{
+		Location: types.Location{File: testFile, Line: 7, Column: 5},
+		Code:     "TS2322",
+		Message:  "Type 'string' is not assignable to type 'number'.",
+	}
+	newInOtherFile := types.Diagnostic{
+		Location: types.Location{File: "/repo/src/helpers.ts", Line: 2, Column: 1},
+		Code:     "TS2451",
+		Message:  "Cannot redeclare block-scoped variable 'total'.",
+	}
+	global := types.Diagnostic{Code: "TS5083", Message: "Cannot read file '/repo/tsconfig.base.json'."}
+
+	baseline := map[string]bool{diagnosticKey(legacy): true, diagnosticKey(global): true}
+
+	filtered := filterDiagnostics([]types.Diagnostic{legacy, repeated, newInOtherFile, global}, testFile, baseline)
+	assert.Equal(t, []types.Diagnostic{repeated, newInOtherFile, global}, filtered)
+}
+
+func TestCheckTypes_Failures(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	tests := []struct {
+		name        string
+		npx         string // Script run as npx, none leaves npx off the PATH
+		baseline    string // Output of npx when the baseline is captured
+		passed      bool
+		diagnostics int
+		err         bool
+	}{
+		{
+			name:   "no errors",
+			npx:    "exit 0",
+			passed: true,
+		},
+		{
+			name:   "fails without diagnostics",
+			npx:    "echo 'This is not the tsc command you are looking for'; exit 1",
+			passed: false,
+		},
+		{
+			name:        "global error",
+			npx:         "echo \"error TS5083: Cannot read file 'tsconfig.base.json'.\"; exit 2",
+			passed:      false,
+			diagnostics: 1,
+		},
+		{
+			name:     "error in another file in the baseline",
+			npx:      "echo \"src/legacy.ts(3,1): error TS2322: Type 'string' is not assignable to type 'number'.\"; exit 2",
+			baseline: "src/legacy.ts(3,1): error TS2322: Type 'string' is not assignable to type 'number'.",
+			passed:   true,
+		},
+		{
+			name:        "test file repeats an error in the baseline",
+			npx:         "echo \"src/sum.test.ts(4,7): error TS2322: Type 'string' is not assignable to type 'number'.\"; exit 2",
+			baseline:    "src/sum.ts(3,1): error TS2322: Type 'string' is not assignable to type 'number'.",
+			passed:      false,
+			diagnostics: 1,
+		},
+		{
+			name: "npx is missing",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			bin := filepath.Join(root, "bin")
+			require.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0755))
+			require.NoError(t, os.MkdirAll(bin, 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(root, "tsconfig.json"), []byte(`{}`), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(root, "src", "sum.test.ts"), []byte("const total: number = 'one'\n"), 0644))
+			t.Setenv("PATH", bin)
+
+			ts := NewTypeScriptSupport()
+			writeNpx := func(script string) {
+				require.NoError(t, os.WriteFile(filepath.Join(bin, "npx"), []byte("#!/bin/sh\n"+script+"\n"), 0755))
+			}
+			if tt.baseline != "" {
+				writeNpx("echo \"" + tt.baseline + "\"; exit 2")
+				require.NoError(t, ts.CaptureBaseline(context.Background(), filepath.Join(root, "src", "sum.ts")))
+			}
+			if tt.npx != "" {
+				writeNpx(tt.npx)
+			}
+
+			result, err := ts.CheckTypes(context.Background(), filepath.Join(root, "src", "sum.test.ts"))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.passed, result.Passed, result.Output)
+			assert.Len(t, result.Diagnostics, tt.diagnostics)
+		})
+	}
+}
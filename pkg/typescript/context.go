@@ -0,0 +1,582 @@
+package typescript
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// maxReexportDepth limits how many `export ... from` hops are followed to find a declaration
+const maxReexportDepth = 3
+
+// importBinding is a local name introduced by an import statement
+type importBinding struct {
+	specifier string
+	name      string // Imported name, "default" for default imports and "*" for namespace imports
+}
+
+// tsDeclaration is a top-level declaration with function and method bodies left out
+type tsDeclaration struct {
+	kind   string // type, interface, enum, const, class or function
+	source string
+	refs   []string // Identifiers used in the declaration
+}
+
+// tsModule holds the top-level declarations and exports of a TypeScript file
+type tsModule struct {
+	path      string
+	decls     map[string][]tsDeclaration // Function overloads share a name
+	imports   map[string]importBinding
+	exports   map[string]string // Exported name to local name, from export lists and export default
+	reexports []reexport
+}
+
+// reexport is an `export { a } from "./a"` or `export * from "./a"` statement
+type reexport struct {
+	specifier string
+	names     map[string]string // Exported name to name in the other module, nil for export *
+}
+
+// ResolveContext collects the declarations of the imported types, interfaces, enums,
+// constants, classes and functions a function references. Relative imports and tsconfig
+// paths aliases are followed, including re-exports through index files. Function and
+// method bodies are left out.
+func (ts *TypeScriptSupport) ResolveContext(ctx context.Context, sourcePath string, function types.Function) ([]types.ContextFile, error) {
+	sourcePath, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	source, err := parseModule(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &contextResolver{
+		config:  loadPathConfig(filepath.Dir(sourcePath)),
+		modules: make(map[string]*tsModule),
+		added:   make(map[string]bool),
+		files:   make(map[string][]string),
+	}
+	for _, ref := range function.References {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		binding, ok := source.imports[ref]
+		if !ok {
+			continue
+		}
+		file := resolveModule(r.config, filepath.Dir(sourcePath), binding.specifier)
+		if file == "" {
+			continue
+		}
+
+		if binding.name != "*" {
+			r.resolve(file, binding.name, 0)
+			continue
+		}
+		for _, member := range namespaceMembers(function.SourceCode, ref) {
+			r.resolve(file, member, 0)
+		}
+	}
+
+	var contextFiles []types.ContextFile
+	for _, file := range r.order {
+		path, err := filepath.Rel(filepath.Dir(sourcePath), file)
+		if err != nil {
+			path = file
+		}
+		contextFiles = append(contextFiles, types.ContextFile{
+			Path:        filepath.ToSlash(path),
+			Content:     strings.Join(r.files[file], "\n\n"),
+			Description: "Declarations referenced by " + function.Name,
+			Type:        "declarations",
+			Discovered:  true,
+		})
+	}
+	return contextFiles, nil
+}
+
+type contextResolver struct {
+	config  *pathConfig
+	modules map[string]*tsModule // Parsed files, nil if they couldn't be read
+	added   map[string]bool      // Declarations already added, by file and name
+	files   map[string][]string  // Declarations by file
+	order   []string             // Files in the order their first declaration was found
+}
+
+// resolve adds the declaration exported as name from file, returning false if it wasn't found
+func (r *contextResolver) resolve(file, name string, depth int) bool {
+	m := r.module(file)
+	if m == nil {
+		return false
+	}
+
+	local := name
+	if exported, ok := m.exports[name]; ok {
+		local = exported
+	}
+
+	if decls, ok := m.decls[local]; ok {
+		r.add(m, local)
+		// Types used by the declaration are usually needed to make sense of it
+		for _, decl := range decls {
+			for _, ref := range decl.refs {
+				if ref != local && isTypeDeclaration(m.decls[ref]) {
+					r.add(m, ref)
+				}
+			}
+		}
+		return true
+	}
+
+	if depth >= maxReexportDepth {
+		return false
+	}
+
+	// Imported and then exported again
+	if binding, ok := m.imports[local]; ok {
+		if target := resolveModule(r.config, filepath.Dir(m.path), binding.specifier); target != "" && binding.name != "*" {
+			return r.resolve(target, binding.name, depth+1)
+		}
+		return false
+	}
+
+	for _, re := range m.reexports {
+		target := name
+		if re.names != nil {
+			var ok bool
+			if target, ok = re.names[name]; !ok {
+				continue
+			}
+		} else if name == "default" {
+			// export * never re-exports the default export
+			continue
+		}
+		if file := resolveModule(r.config, filepath.Dir(m.path), re.specifier); file != "" && r.resolve(file, target, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *contextResolver) add(m *tsModule, name string) {
+	key := m.path + "#" + name
+	if r.added[key] {
+		return
+	}
+	r.added[key] = true
+
+	if _, ok := r.files[m.path]; !ok {
+		r.order = append(r.order, m.path)
+	}
+	for _, decl := range m.decls[name] {
+		r.files[m.path] = append(r.files[m.path], decl.source)
+	}
+}
+
+func (r *contextResolver) module(file string) *tsModule {
+	if m, ok := r.modules[file]; ok {
+		return m
+	}
+	m, err := parseModule(file)
+	if err != nil {
+		m = nil
+	}
+	r.modules[file] = m
+	return m
+}
+
+func isTypeDeclaration(decls []tsDeclaration) bool {
+	for _, decl := range decls {
+		switch decl.kind {
+		case "type", "interface", "enum":
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceMembers returns the members accessed through a namespace import, like User in ns.User
+func namespaceMembers(sourceCode, namespace string) []string {
+	tokens, err := tokenize(sourceCode)
+	if err != nil {
+		return nil
+	}
+	p := &parser{src: sourceCode, toks: tokens}
+
+	seen := make(map[string]bool)
+	var members []string
+	for k := range p.toks {
+		if p.is(k, namespace) && !p.is(k-1, ".") && p.is(k+1, ".") && p.isIdent(k+2) {
+			if member := p.tok(k + 2).text; !seen[member] {
+				seen[member] = true
+				members = append(members, member)
+			}
+		}
+	}
+	return members
+}
+
+func parseModule(path string) (*tsModule, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := tokenize(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{src: string(src), toks: tokens}
+	m := &tsModule{
+		path:    path,
+		decls:   make(map[string][]tsDeclaration),
+		imports: p.importBindings(),
+		exports: make(map[string]string),
+	}
+	for i := 0; i < len(p.toks); {
+		next := p.scanStatement(i, m)
+		if next <= i {
+			next = i + 1
+		}
+		i = next
+	}
+	return m, nil
+}
+
+// importBindings maps the local names of every import statement to what they import
+func (p *parser) importBindings() map[string]importBinding {
+	bindings := make(map[string]importBinding)
+	for i := 0; i < len(p.toks); i++ {
+		// Skip dynamic imports and import.meta
+		if !p.is(i, "import") || p.is(i-1, ".") || p.is(i+1, "(") || p.is(i+1, ".") {
+			continue
+		}
+
+		j := i + 1
+		if p.is(j, "type") && !p.is(j+1, "from") && !p.is(j+1, ",") {
+			j++
+		}
+
+		locals := make(map[string]string) // Local name to imported name
+		for j < len(p.toks) && !p.is(j, "from") && !p.is(j, ";") && p.tok(j).kind != tokString {
+			switch {
+			case p.is(j, "{"):
+				end := p.skipBalanced(j)
+				for exported, local := range p.bindingList(j, end) {
+					locals[local] = exported
+				}
+				j = end
+			case p.is(j, "*") && p.is(j+1, "as") && p.isIdent(j+2):
+				locals[p.tok(j+2).text] = "*"
+				j += 3
+			case p.isIdent(j):
+				locals[p.tok(j).text] = "default"
+				j++
+			default:
+				j++
+			}
+		}
+
+		if p.is(j, "from") {
+			j++
+		}
+		if p.tok(j).kind != tokString {
+			continue
+		}
+		for local, name := range locals {
+			bindings[local] = importBinding{specifier: unquote(p.tok(j).text), name: name}
+		}
+		i = j
+	}
+	return bindings
+}
+
+// bindingList parses the braces of an import or export list between tokens open and end,
+// mapping names on the left of "as" to names on the right
+func (p *parser) bindingList(open, end int) map[string]string {
+	names := make(map[string]string)
+	for k := open + 1; k < end-1; k++ {
+		if !p.isIdent(k) || !(p.is(k-1, "{") || p.is(k-1, ",") || p.is(k-1, "type")) {
+			continue
+		}
+		// Type modifier, as in { type User }
+		if p.is(k, "type") && p.isIdent(k+1) && !p.is(k+1, "as") {
+			continue
+		}
+		name, alias := p.tok(k).text, p.tok(k).text
+		if p.is(k+1, "as") && p.isIdent(k+2) {
+			alias = p.tok(k + 2).text
+			k += 2
+		}
+		names[name] = alias
+	}
+	return names
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, "\"'`")
+}
+
+// scanStatement records the declaration or export statement at token i and returns the
+// index after it
+func (p *parser) scanStatement(i int, m *tsModule) int {
+	start, j := i, i
+	isDefault := false
+
+	if p.is(j, "export") {
+		j++
+		switch {
+		case p.is(j, "default"):
+			j++
+			// export default name;
+			if p.isIdent(j) && (p.is(j+1, ";") || j+1 >= len(p.toks) || p.tok(j+1).newlineBefore) {
+				m.exports["default"] = p.tok(j).text
+				return j + 1
+			}
+			isDefault = true
+
+		case p.is(j, "*"):
+			// export * as ns from "./a" re-exports a namespace, which is not followed
+			if p.is(j+1, "from") && p.tok(j+2).kind == tokString {
+				m.reexports = append(m.reexports, reexport{specifier: unquote(p.tok(j + 2).text)})
+				return j + 3
+			}
+			return j + 1
+
+		case p.is(j, "{"), p.is(j, "type") && p.is(j+1, "{"):
+			if p.is(j, "type") {
+				j++
+			}
+			end := p.skipBalanced(j)
+			names := p.bindingList(j, end)
+			if p.is(end, "from") && p.tok(end+1).kind == tokString {
+				reexported := make(map[string]string, len(names))
+				for name, alias := range names {
+					reexported[alias] = name
+				}
+				m.reexports = append(m.reexports, reexport{specifier: unquote(p.tok(end + 1).text), names: reexported})
+				return end + 2
+			}
+			for name, alias := range names {
+				m.exports[alias] = name
+			}
+			return end
+		}
+	}
+	if p.is(j, "declare") {
+		j++
+	}
+
+	name, kind, end := p.scanDeclaration(j)
+	if kind == "" {
+		if p.is(i, "(") || p.is(i, "[") || p.is(i, "{") {
+			return p.skipBalanced(i)
+		}
+		return i + 1
+	}
+	if p.is(end, ";") {
+		end++
+	}
+	if name == "" {
+		return end
+	}
+
+	decl := tsDeclaration{kind: kind, source: p.declarationSource(start, j, end, kind), refs: p.identifiers(j, end, name)}
+	m.decls[name] = append(m.decls[name], decl)
+	if isDefault {
+		m.exports["default"] = name
+	}
+	return end
+}
+
+// scanDeclaration returns the name and kind of the declaration starting at token j and
+// the index after it, or an empty kind if there is no declaration there
+func (p *parser) scanDeclaration(j int) (string, string, int) {
+	switch {
+	case p.is(j, "type") && p.isIdent(j+1):
+		k := j + 2
+		if p.is(k, "<") {
+			k = p.skipAngles(k)
+		}
+		if !p.is(k, "=") {
+			return "", "", j + 1
+		}
+		return p.tok(j + 1).text, "type", p.skipType(k+1, false)
+
+	case p.is(j, "interface") && p.isIdent(j+1), p.is(j, "enum") && p.isIdent(j+1), p.is(j, "const") && p.is(j+1, "enum"):
+		if p.is(j, "const") {
+			j++
+		}
+		return p.tok(j + 1).text, p.tok(j).text, p.skipToBody(j + 2)
+
+	case p.is(j, "const"), p.is(j, "let"), p.is(j, "var"):
+		name := ""
+		if p.isIdent(j + 1) {
+			name = p.tok(j + 1).text
+		}
+		k := j + 1
+		for {
+			k = p.skipExpression(k)
+			if !p.is(k, ",") {
+				return name, "const", k
+			}
+			k++
+		}
+
+	case p.is(j, "function"), p.is(j, "async") && p.is(j+1, "function"):
+		k := j + 1
+		if p.is(j, "async") {
+			k++
+		}
+		if p.is(k, "*") {
+			k++
+		}
+		if !p.isIdent(k) {
+			return "", "function", p.skipToBody(k)
+		}
+		name := p.tok(k).text
+		k++
+		if p.is(k, "<") {
+			k = p.skipAngles(k)
+		}
+		if p.is(k, "(") {
+			k = p.skipBalanced(k)
+		}
+		if p.is(k, ":") {
+			k = p.skipType(k+1, false)
+		}
+		if p.is(k, "{") {
+			k = p.skipBalanced(k)
+		}
+		return name, "function", k
+
+	case p.is(j, "class"), p.is(j, "abstract") && p.is(j+1, "class"):
+		k := j + 1
+		if p.is(j, "abstract") {
+			k++
+		}
+		name := ""
+		if p.isIdent(k) && !p.is(k, "extends") && !p.is(k, "implements") {
+			name = p.tok(k).text
+		}
+		return name, "class", p.skipToBody(k)
+	}
+	return "", "", j
+}
+
+// skipToBody returns the index after the braces of the body following token k, skipping
+// type parameters and heritage clauses
+func (p *parser) skipToBody(k int) int {
+	for k < len(p.toks) && !p.is(k, "{") {
+		switch {
+		case p.is(k, "<"):
+			k = p.skipAngles(k)
+		case p.is(k, "("), p.is(k, "["):
+			k = p.skipBalanced(k)
+		case p.is(k, ";"):
+			return k
+		default:
+			k++
+		}
+	}
+	return p.skipBalanced(k)
+}
+
+// declarationSource returns the source of the declaration spanning tokens [start, end),
+// with its doc comment and without function and method bodies
+func (p *parser) declarationSource(start, decl, end int, kind string) string {
+	var source string
+	switch kind {
+	case "class":
+		open := decl
+		for open < end && !p.is(open, "{") {
+			if p.is(open, "<") {
+				open = p.skipAngles(open) - 1
+			}
+			open++
+		}
+		source = p.stripBodies(start, end, open)
+	case "function":
+		source = p.stripBodies(start, end, -1)
+	case "const":
+		source = p.src[p.tok(start).start:p.tok(end-1).end]
+		// Function-valued constants are reduced to their signature like functions
+		for k := decl + 1; k < end; k++ {
+			if p.is(k, "=") {
+				if _, ok := p.functionExpression(k + 1); ok {
+					source = p.stripBodies(start, end, -1)
+				}
+				break
+			}
+		}
+	default:
+		source = p.src[p.tok(start).start:p.tok(end-1).end]
+	}
+
+	if comments := p.tok(start).comments; len(comments) > 0 {
+		source = comments[len(comments)-1] + "\n" + source
+	}
+	return source
+}
+
+// notBeforeBody lists tokens after which a '{' opens an object literal or type rather than
+// a function body
+var notBeforeBody = map[string]bool{
+	":": true, "=": true, ",": true, "(": true, "[": true, "<": true, "|": true, "&": true,
+	"?": true, "||": true, "&&": true, "??": true, "...": true, "!": true,
+	"extends": true, "keyof": true, "typeof": true, "return": true,
+}
+
+// stripBodies returns the source of tokens [from, to) with every function and method body
+// at the top level replaced by a semicolon. The braces at token body, like a class body,
+// are entered rather than treated as a function body.
+func (p *parser) stripBodies(from, to, body int) string {
+	if from >= to {
+		return ""
+	}
+
+	var out strings.Builder
+	last := p.tok(from).start
+	for k := from; k < to; {
+		switch {
+		case p.is(k, "("), p.is(k, "["):
+			k = p.skipBalanced(k)
+		case p.is(k, "<"):
+			k = p.skipAngles(k)
+		case k == body:
+			k++
+		case p.is(k, "{") && k > from && !notBeforeBody[p.tok(k-1).text]:
+			out.WriteString(strings.TrimRight(p.src[last:p.tok(k).start], " \t"))
+			out.WriteString(";")
+			k = p.skipBalanced(k)
+			last = p.tok(k - 1).end
+			if p.is(k, ";") && k < to {
+				last = p.tok(k).end
+				k++
+			}
+		case p.is(k, "{"):
+			k = p.skipBalanced(k)
+		default:
+			k++
+		}
+	}
+	out.WriteString(p.src[last:p.tok(to-1).end])
+	return out.String()
+}
+
+// identifiers returns the distinct identifiers in tokens [from, to), other than self
+func (p *parser) identifiers(from, to int, self string) []string {
+	seen := map[string]bool{self: true}
+	var idents []string
+	for k := from; k < to; k++ {
+		if t := p.tok(k); t.kind == tokIdent && !seen[t.text] && !p.is(k-1, ".") {
+			seen[t.text] = true
+			idents = append(idents, t.text)
+		}
+	}
+	return idents
+}
@@ -0,0 +1,190 @@
+package typescript
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gwkline/artestian/types"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestTypeScriptSupport_ResolveContext(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"tsconfig.json": `{
+	// Comments and trailing commas are allowed
+	"compilerOptions": {
+		"baseUrl": ".",
+		"paths": { "@/*": ["src/*"], },
+	},
+}`,
+		"src/models/user.ts": `import { z } from "zod";
+
+/** A customer account */
+export interface User {
+	id: string;
+	role: Role;
+}
+
+export enum Role {
+	Admin = "admin",
+	Customer = "customer",
+}
+
+interface Unused {
+	value: number;
+}
+`,
+		"src/models/index.ts": `export * from "./user";
+export { formatPrice as price } from "./money";
+`,
+		"src/models/money.ts": `export function formatPrice(cents: number): string {
+	return (cents / 100).toFixed(2);
+}
+`,
+		"src/services/repo.ts": `export default class UserRepo {
+	private cache = new Map<string, string>();
+
+	async find(id: string): Promise<string | undefined> {
+		return this.cache.get(id);
+	}
+}
+
+export const MAX_USERS = 100;
+
+export const loadUser = async (id: string): Promise<string> => {
+	return id;
+};
+`,
+		"src/services/greet.ts": `import { User, price } from "@/models";
+import UserRepo from "./repo.js";
+import * as repo from "./repo";
+import { readFile } from "fs";
+
+export function greet(user: User, repoInstance: UserRepo): string {
+	return user.id + price(repo.MAX_USERS) + repo.loadUser(user.id);
+}
+`,
+	})
+
+	ts := NewTypeScriptSupport()
+	sourcePath := filepath.Join(root, "src", "services", "greet.ts")
+	source, err := os.ReadFile(sourcePath)
+	assert.NoError(t, err)
+	functions, err := ts.GetFunctions(string(source))
+	assert.NoError(t, err)
+	assert.Len(t, functions, 1)
+
+	contextFiles, err := ts.ResolveContext(context.Background(), sourcePath, functions[0])
+	assert.NoError(t, err)
+
+	byPath := make(map[string]string)
+	for _, file := range contextFiles {
+		assert.Equal(t, "declarations", file.Type)
+		assert.True(t, file.Discovered)
+		byPath[file.Path] = file.Content
+	}
+	assert.Len(t, byPath, 3)
+
+	user := byPath["../models/user.ts"]
+	assert.Contains(t, user, "/** A customer account */\nexport interface User {")
+	assert.Contains(t, user, "export enum Role {")
+	assert.NotContains(t, user, "Unused")
+	assert.NotContains(t, user, "zod")
+
+	assert.Equal(t, "export function formatPrice(cents: number): string;", byPath["../models/money.ts"])
+
+	repo := byPath["repo.ts"]
+	assert.Contains(t, repo, "export default class UserRepo {")
+	assert.Contains(t, repo, "private cache = new Map<string, string>();")
+	assert.Contains(t, repo, "async find(id: string): Promise<string | undefined>;")
+	assert.NotContains(t, repo, "this.cache.get")
+	assert.Contains(t, repo, "export const MAX_USERS = 100;")
+	assert.Contains(t, repo, "export const loadUser = async (id: string): Promise<string> =>;")
+	assert.NotContains(t, repo, "return id")
+}
+
+func TestResolveModule(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"src/a.ts":           "",
+		"src/b.tsx":          "",
+		"src/lib/index.ts":   "",
+		"src/types.d.ts":     "",
+		"shared/util/log.ts": "",
+	})
+	config := &pathConfig{
+		dir:  root,
+		base: root,
+		paths: map[string][]string{
+			"@/*":      {"src/*"},
+			"@util/*":  {"shared/util/*"},
+			"@exact":   {"src/a.ts"},
+			"@/lib/*":  {"missing/*", "src/lib/*"},
+			"unmapped": {"nowhere"},
+		},
+	}
+	src := filepath.Join(root, "src")
+
+	tests := []struct {
+		name      string
+		specifier string
+		want      string
+	}{
+		{"relative", "./a", filepath.Join(src, "a.ts")},
+		{"js extension", "./a.js", filepath.Join(src, "a.ts")},
+		{"tsx", "./b", filepath.Join(src, "b.tsx")},
+		{"index file", "./lib", filepath.Join(src, "lib", "index.ts")},
+		{"declaration file", "./types", filepath.Join(src, "types.d.ts")},
+		{"parent directory", "../shared/util/log", filepath.Join(root, "shared", "util", "log.ts")},
+		{"paths wildcard", "@/a", filepath.Join(src, "a.ts")},
+		{"paths exact", "@exact", filepath.Join(src, "a.ts")},
+		{"longest prefix with fallback target", "@/lib/index", filepath.Join(src, "lib", "index.ts")},
+		{"other alias", "@util/log", filepath.Join(root, "shared", "util", "log.ts")},
+		{"base url", "src/a", filepath.Join(src, "a.ts")},
+		{"package", "react", ""},
+		{"missing", "./missing", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolveModule(config, src, tt.specifier))
+		})
+	}
+}
+
+func TestParsePathConfig(t *testing.T) {
+	config := parsePathConfig("/repo", []byte(`{
+		/* block comment */
+		"compilerOptions": {
+			"baseUrl": "./src", // trailing comment
+			"paths": { "@/*": ["*"], "//not-a-comment": ["x"], },
+		},
+	}`))
+
+	assert.Equal(t, "/repo/src", config.base)
+	assert.Equal(t, map[string][]string{"@/*": {"*"}, "//not-a-comment": {"x"}}, config.paths)
+}
+
+func TestResolveContext_NoImports(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"sum.ts": "export function sum(a: number, b: number) { return a + b; }\n",
+	})
+
+	contextFiles, err := NewTypeScriptSupport().ResolveContext(context.Background(), filepath.Join(root, "sum.ts"), types.Function{Name: "sum"})
+	assert.NoError(t, err)
+	assert.Empty(t, contextFiles)
+}
@@ -1,112 +1,1006 @@
 package typescript
 
 import (
-	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/gwkline/artestian/types"
 )
 
+// GetFunctions parses TypeScript source and returns every top-level function,
+// function-valued const, class method, accessor and object-literal method it declares,
+// including those inside namespaces. A getter and setter pair is one function named
+// after the property. Members with computed names, like [Symbol.iterator], are skipped.
 func (ts *TypeScriptSupport) GetFunctions(sourceCode string) ([]types.Function, error) {
-	var functions []types.Function
-
-	// Build regex pattern from components
-	patterns := []string{
-		`(?m)`,                                 // Multiline mode
-		`(?:^|\n)\s*`,                          // Start of line or newline, followed by whitespace
-		`(?:(export)\s+)?`,                     // Optional export keyword (captured in group 1)
-		`(?:async\s+)?`,                        // Optional async keyword
-		`(?:`,                                  // Start of main function pattern group
-		`function\s+(\w+)`,                     // Named function declaration (captured in group 2)
-		`|`,                                    // OR
-		`const\s+(\w+)`,                        // Const declaration (captured in group 3)
-		`\s*(?::\s*(?:[^=;]|=>|\{[^}]*\})*?)?`, // Optional type annotation
-		`\s*=\s*`,                              // Assignment
-		`(?:async\s+)?`,                        // Optional async keyword for arrow function
-		`(?:`,                                  // Start of function implementation group
-		`(?:<[^>]+>\s*)?`,                      // Optional generic type parameters
-		`(?:function|\([^)]*\)\s*(?::\s*[^{]*?)?\s*=>)`, // Function keyword or arrow function with return type
-		`)`,
-		`)`,
-		`\s*[^{]*{`, // Any non-brace chars until opening brace
-	}
-
-	functionPattern := strings.Join(patterns, "")
-	re := regexp.MustCompile(functionPattern)
-
-	// First find all potential function starts
-	matches := re.FindAllStringIndex(sourceCode, -1)
-
-	for _, match := range matches {
-		startPos := match[0]
-
-		// Look for export keyword before the function
-		preContext := sourceCode[max(0, startPos-50):startPos]
-		isExported := strings.Contains(preContext, "export") ||
-			re.FindStringSubmatch(sourceCode[startPos:match[1]])[1] != ""
-
-		// Find matching closing brace
-		braceCount := 1
-		endPos := match[1]
-
-		for i := endPos; i < len(sourceCode); i++ {
-			if sourceCode[i] == '{' {
-				braceCount++
-			} else if sourceCode[i] == '}' {
-				braceCount--
-				if braceCount == 0 {
-					endPos = i + 1
-					break
+	tokens, err := tokenize(sourceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{
+		src:        sourceCode,
+		toks:       tokens,
+		overloads:  make(map[string]declaration),
+		reexported: make(map[string]bool),
+		declared:   make(map[string]bool),
+		accessors:  make(map[string]int),
+	}
+	for i, c := range sourceCode {
+		if c == '\n' {
+			p.newlines = append(p.newlines, i)
+		}
+	}
+
+	for i := 0; i < len(p.toks); {
+		next := p.parseStatement(i)
+		if next <= i {
+			next = i + 1
+		}
+		i = next
+	}
+
+	for i, fn := range p.functions {
+		// Functions exported through `export { name }` or `export default name`
+		if fn.Receiver == "" && p.reexported[fn.Name] {
+			p.functions[i].IsExported = true
+		}
+		p.functions[i].References = p.references(p.ranges[i], fn.Name)
+	}
+
+	return p.functions, nil
+}
+
+// exportContext describes the export modifiers preceding a declaration
+type exportContext struct {
+	exported  bool
+	isDefault bool
+	tok       int // index of the export keyword
+}
+
+// declaration identifies a function-like declaration before its body is parsed
+type declaration struct {
+	name     string
+	receiver string
+	first    int    // index of the first token of the declaration, after any export keyword
+	doc      int    // index of the token whose leading comments form the doc comment
+	accessor string // "get" or "set" for accessors
+}
+
+// signature is a parsed function signature and body
+type signature struct {
+	params  []types.Param
+	results []types.Param
+	end     int // index just past the last token of the function
+}
+
+type parser struct {
+	src        string
+	toks       []token
+	newlines   []int
+	functions  []types.Function
+	ranges     [][][2]int             // token ranges of each function, parallel to functions; two for accessor pairs
+	overloads  map[string]declaration // pending overload signatures, keyed by receiver.name
+	reexported map[string]bool        // local names exported through export lists
+	declared   map[string]bool        // top-level declarations and import bindings
+	accessors  map[string]int         // index of the function for each accessor, keyed by receiver.name
+
+	namespace         string // enclosing namespace, e.g. "A.B", empty at the top level
+	namespaceExported bool   // whether the enclosing namespaces are all exported
+}
+
+// modifiers that may precede a class member name
+var memberModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "static": true, "readonly": true,
+	"abstract": true, "override": true, "declare": true, "async": true, "accessor": true,
+	"get": true, "set": true,
+}
+
+// identifiers that cannot end an expression, so a newline after them does not end a statement
+var expressionKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "throw": true, "await": true, "yield": true, "as": true,
+	"satisfies": true, "keyof": true,
+}
+
+// identifiers that continue a type when they follow a complete type
+var typeOperators = map[string]bool{
+	"extends": true, "is": true, "keyof": true, "typeof": true, "infer": true,
+	"readonly": true, "unique": true, "asserts": true, "new": true,
+}
+
+func (p *parser) tok(i int) token {
+	if i < 0 || i >= len(p.toks) {
+		return token{kind: tokPunct}
+	}
+	return p.toks[i]
+}
+
+// is reports whether token i is the punctuator or identifier text
+func (p *parser) is(i int, text string) bool {
+	t := p.tok(i)
+	return (t.kind == tokPunct || t.kind == tokIdent) && t.text == text
+}
+
+func (p *parser) isIdent(i int) bool {
+	return p.tok(i).kind == tokIdent
+}
+
+// lineAt returns the 1-based line number of a byte offset
+func (p *parser) lineAt(offset int) int {
+	return sort.SearchInts(p.newlines, offset) + 1
+}
+
+// text returns the source covered by tokens [from, to) with whitespace collapsed
+func (p *parser) text(from, to int) string {
+	if from >= to || from >= len(p.toks) {
+		return ""
+	}
+	return strings.Join(strings.Fields(p.src[p.tok(from).start:p.tok(to-1).end]), " ")
+}
+
+func (p *parser) parseStatement(i int) int {
+	t := p.tok(i)
+	if t.kind == tokPunct {
+		if t.text == "(" || t.text == "[" || t.text == "{" {
+			return p.skipBalanced(i)
+		}
+		return i + 1
+	}
+	if t.kind != tokIdent {
+		return i + 1
+	}
+
+	switch t.text {
+	case "export":
+		return p.parseExport(i)
+	case "import":
+		return p.parseImport(i)
+	default:
+		return p.parseDeclaration(i, exportContext{tok: -1})
+	}
+}
+
+// parseDeclaration parses a function, variable or class declaration at token i
+func (p *parser) parseDeclaration(i int, ctx exportContext) int {
+	switch {
+	case p.is(i, "function"), p.is(i, "async") && p.is(i+1, "function"):
+		return p.parseFunctionDeclaration(i, ctx)
+	case p.is(i, "const"), p.is(i, "let"), p.is(i, "var"):
+		return p.parseVariable(i, ctx)
+	case p.is(i, "class"), p.is(i, "abstract") && p.is(i+1, "class"):
+		return p.parseClass(i, ctx)
+	case (p.is(i, "namespace") || p.is(i, "module")) && p.isIdent(i+1) && (p.is(i+2, "{") || p.is(i+2, ".")):
+		return p.parseNamespace(i, ctx)
+	case p.is(i, "type"), p.is(i, "interface"), p.is(i, "enum"):
+		if p.isIdent(i + 1) {
+			p.declared[p.tok(i+1).text] = true
+			return i + 2
+		}
+	}
+	return i + 1
+}
+
+// parseNamespace records the functions declared in a namespace body, with the namespace
+// as their receiver. They're exported when they and every enclosing namespace are.
+func (p *parser) parseNamespace(i int, ctx exportContext) int {
+	j := i + 1
+	name := p.tok(j).text
+	p.declared[name] = true
+	j++
+	for p.is(j, ".") && p.isIdent(j+1) {
+		name += "." + p.tok(j+1).text
+		j += 2
+	}
+	if !p.is(j, "{") {
+		return j
+	}
+	end := p.skipBalanced(j)
+
+	outer, outerExported := p.namespace, p.namespaceExported
+	p.namespace = p.qualify(name)
+	p.namespaceExported = ctx.exported && (outer == "" || outerExported)
+	for k := j + 1; k < end-1; {
+		next := p.parseStatement(k)
+		if next <= k {
+			next = k + 1
+		}
+		k = next
+	}
+	p.namespace, p.namespaceExported = outer, outerExported
+	return end
+}
+
+// qualify prefixes name with the enclosing namespace
+func (p *parser) qualify(name string) string {
+	if p.namespace == "" {
+		return name
+	}
+	return p.namespace + "." + name
+}
+
+// parseImport records the local bindings introduced by an import statement
+func (p *parser) parseImport(i int) int {
+	j := i + 1
+	if p.is(j, "type") && !p.is(j+1, "from") {
+		j++
+	}
+
+	for j < len(p.toks) && !p.is(j, "from") && !p.is(j, ";") && p.tok(j).kind != tokString {
+		switch {
+		case p.is(j, "{"):
+			end := p.skipBalanced(j)
+			for k := j + 1; k < end-1; k++ {
+				if p.isIdent(k) && (p.is(k+1, ",") || p.is(k+1, "}")) {
+					p.declared[p.tok(k).text] = true
 				}
 			}
+			j = end
+		case p.isIdent(j) && !p.is(j, "as"):
+			p.declared[p.tok(j).text] = true
+			j++
+		default:
+			j++
 		}
+	}
+	return j
+}
 
-		// Get the full function text including any preceding export
-		fullMatch := sourceCode[startPos:endPos]
-		if isExported && !strings.HasPrefix(strings.TrimSpace(fullMatch), "export") {
-			if idx := strings.LastIndex(preContext, "export"); idx != -1 {
-				fullMatch = "export " + strings.TrimSpace(sourceCode[startPos:endPos])
+func (p *parser) parseExport(i int) int {
+	ctx := exportContext{exported: true, tok: i}
+	j := i + 1
+
+	if p.is(j, "default") {
+		ctx.isDefault = true
+		j++
+		// export default name;
+		if p.isIdent(j) && !p.is(j, "function") && !p.is(j, "async") && !p.is(j, "class") && !p.is(j, "abstract") &&
+			(p.is(j+1, ";") || p.tok(j+1).newlineBefore || j+1 >= len(p.toks)) {
+			p.reexported[p.tok(j).text] = true
+			return j + 1
+		}
+		// export default () => {}
+		if sig, ok := p.functionExpression(j); ok && !p.is(j, "function") && !p.is(j+1, "function") {
+			p.record(declaration{name: "default", first: j, doc: i}, sig, ctx, true)
+			return sig.end
+		}
+	}
+
+	if p.is(j, "type") && p.is(j+1, "{") {
+		j++
+	}
+
+	switch {
+	case p.is(j, "{"):
+		return p.parseExportList(j)
+	case p.is(j, "*"), p.is(j, "="), p.is(j, "declare"):
+		return j + 1
+	}
+
+	return p.parseDeclaration(j, ctx)
+}
+
+// parseExportList handles `export { a, b as c }`, marking local names as exported.
+// Re-exports from other modules (`export { a } from "./a"`) are ignored.
+func (p *parser) parseExportList(open int) int {
+	end := p.skipBalanced(open)
+
+	var names []string
+	for k := open + 1; k < end-1; k++ {
+		if !p.isIdent(k) || p.is(k, "type") && p.isIdent(k+1) {
+			continue
+		}
+		names = append(names, p.tok(k).text)
+		if p.is(k+1, "as") {
+			k += 2
+		}
+	}
+
+	if p.is(end, "from") {
+		return end + 2
+	}
+	for _, name := range names {
+		p.reexported[name] = true
+	}
+	return end
+}
+
+func (p *parser) parseFunctionDeclaration(i int, ctx exportContext) int {
+	j := i
+	if p.is(j, "async") {
+		j++
+	}
+	j++ // function
+	if p.is(j, "*") {
+		j++
+	}
+
+	name := "default"
+	if p.isIdent(j) {
+		name = p.tok(j).text
+		p.declared[name] = true
+		j++
+	} else if !ctx.isDefault {
+		return j
+	}
+
+	decl := declaration{name: name, receiver: p.namespace, first: i, doc: i}
+	if ctx.exported {
+		decl.doc = ctx.tok
+	}
+
+	sig, ok := p.method(j)
+	if !ok {
+		// Overload signature or ambient declaration without a body
+		p.addOverload(decl)
+		return j
+	}
+
+	p.record(decl, sig, ctx, ctx.exported)
+	return sig.end
+}
+
+func (p *parser) parseVariable(i int, ctx exportContext) int {
+	j := i + 1
+	decl := declaration{receiver: p.namespace, first: i, doc: i}
+	if ctx.exported {
+		decl.doc = ctx.tok
+	}
+
+	for {
+		if !p.isIdent(j) {
+			// Destructuring patterns never declare named functions
+			if p.is(j, "{") || p.is(j, "[") {
+				j = p.skipBalanced(j)
+			}
+			return p.skipExpression(j)
+		}
+		decl.name = p.tok(j).text
+		p.declared[decl.name] = true
+		j++
+		if p.is(j, "!") {
+			j++
+		}
+		if p.is(j, ":") {
+			j = p.skipType(j+1, false)
+		}
+
+		if p.is(j, "=") {
+			j++
+			if sig, ok := p.functionExpression(j); ok {
+				p.record(decl, sig, ctx, ctx.exported)
+				j = sig.end
+			} else if p.is(j, "{") {
+				p.parseObjectLiteral(j, p.qualify(decl.name), ctx)
 			}
+			j = p.skipExpression(j)
+		}
+
+		if !p.is(j, ",") {
+			return j
+		}
+		j++
+		decl.first, decl.doc = j, j
+	}
+}
+
+// functionExpression parses a function expression or arrow function starting at token i
+func (p *parser) functionExpression(i int) (signature, bool) {
+	j := i
+	if p.is(j, "async") && !p.is(j+1, "=>") {
+		j++
+	}
+
+	if p.is(j, "function") {
+		j++
+		if p.is(j, "*") {
+			j++
+		}
+		if p.isIdent(j) {
+			j++
+		}
+		return p.method(j)
+	}
+
+	var sig signature
+	if p.is(j, "<") {
+		j = p.skipAngles(j)
+	}
+	switch {
+	case p.is(j, "("):
+		sig.params = p.params(j)
+		j = p.skipBalanced(j)
+		if p.is(j, ":") {
+			end := p.skipType(j+1, true)
+			sig.results = p.result(j+1, end)
+			j = end
 		}
+	case p.isIdent(j) && p.is(j+1, "=>"):
+		sig.params = []types.Param{{Name: p.tok(j).text}}
+		j++
+	default:
+		return sig, false
+	}
+
+	if !p.is(j, "=>") {
+		return sig, false
+	}
+	j++
+	if p.is(j, "{") {
+		sig.end = p.skipBalanced(j)
+		return sig, true
+	}
+	sig.end = p.skipExpression(j)
+	return sig, sig.end > j
+}
 
-		// Extract function name
-		nameMatch := re.FindStringSubmatch(fullMatch)
+// method parses a function's type parameters, parameters, return type and body
+// starting at token k
+func (p *parser) method(k int) (signature, bool) {
+	var sig signature
+	if p.is(k, "<") {
+		k = p.skipAngles(k)
+	}
+	if !p.is(k, "(") {
+		return sig, false
+	}
+	sig.params = p.params(k)
+	k = p.skipBalanced(k)
+	if p.is(k, ":") {
+		end := p.skipType(k+1, false)
+		sig.results = p.result(k+1, end)
+		k = end
+	}
+	if !p.is(k, "{") {
+		return sig, false
+	}
+	sig.end = p.skipBalanced(k)
+	return sig, true
+}
+
+// params parses the parameter list opened by the '(' at token open
+func (p *parser) params(open int) []types.Param {
+	close := p.skipBalanced(open) - 1
 
-		if len(nameMatch) < 4 {
+	var params []types.Param
+	start := open + 1
+	depth := 0
+	for k := open + 1; k <= close; k++ {
+		switch {
+		case p.is(k, "("), p.is(k, "["), p.is(k, "{"), p.is(k, "<"):
+			depth++
+		case p.is(k, ")") && k < close, p.is(k, "]"), p.is(k, "}"), p.is(k, ">"):
+			depth--
+		case depth == 0 && (p.is(k, ",") || k == close):
+			if start < k {
+				params = append(params, p.param(start, k))
+			}
+			start = k + 1
+		}
+	}
+	return params
+}
+
+// param parses a single parameter spanning tokens [from, to)
+func (p *parser) param(from, to int) types.Param {
+	for from < to && p.isIdent(from) && memberModifiers[p.tok(from).text] && p.isIdent(from+1) {
+		from++
+	}
+
+	nameEnd, typeStart, typeEnd := to, to, to
+	depth := 0
+	for k := from; k < to; k++ {
+		switch {
+		case p.is(k, "("), p.is(k, "["), p.is(k, "{"), p.is(k, "<"):
+			depth++
+		case p.is(k, ")"), p.is(k, "]"), p.is(k, "}"), p.is(k, ">"):
+			depth--
+		case depth == 0 && p.is(k, ":") && typeStart == to:
+			nameEnd, typeStart = k, k+1
+		case depth == 0 && p.is(k, "="):
+			if typeStart == to {
+				nameEnd = k
+			}
+			typeEnd = k
+			k = to
+		}
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(p.text(from, nameEnd), "..."), "?")
+	return types.Param{Name: name, Type: p.text(typeStart, typeEnd)}
+}
+
+// result returns the return type annotation spanning tokens [from, to)
+func (p *parser) result(from, to int) []types.Param {
+	if from >= to {
+		return nil
+	}
+	return []types.Param{{Type: p.text(from, to)}}
+}
+
+// parseObjectLiteral records the methods and function-valued properties of an
+// object literal assigned to a top-level variable
+func (p *parser) parseObjectLiteral(open int, objName string, ctx exportContext) {
+	close := p.skipBalanced(open) - 1
+
+	for k := open + 1; k < close; {
+		memberStart := k
+		if p.is(k, "...") {
+			k = p.skipExpression(k + 1)
+		} else {
+			var accessor string
+			for (p.is(k, "async") || p.is(k, "get") || p.is(k, "set")) && !p.isMemberEnd(k+1) {
+				if !p.is(k, "async") {
+					accessor = p.tok(k).text
+				}
+				k++
+			}
+			if p.is(k, "*") {
+				k++
+			}
+			name, next := p.memberName(k)
+			k = next
+			decl := declaration{name: name, receiver: objName, first: memberStart, doc: memberStart, accessor: accessor}
+
+			switch {
+			case name != "" && (p.is(k, "(") || p.is(k, "<")):
+				if sig, ok := p.method(k); ok {
+					p.record(decl, sig, exportContext{tok: -1}, ctx.exported)
+					k = sig.end
+				}
+			case name != "" && p.is(k, ":"):
+				if sig, ok := p.functionExpression(k + 1); ok {
+					p.record(decl, sig, exportContext{tok: -1}, ctx.exported)
+					k = sig.end
+				}
+			}
+			k = p.skipExpression(k)
+		}
+
+		if p.is(k, ",") {
+			k++
+		}
+		if k <= memberStart {
+			k = memberStart + 1
+		}
+	}
+}
+
+func (p *parser) parseClass(i int, ctx exportContext) int {
+	j := i
+	if p.is(j, "abstract") {
+		j++
+	}
+	j++ // class
+
+	className := "default"
+	if p.isIdent(j) && !p.is(j, "extends") && !p.is(j, "implements") {
+		className = p.tok(j).text
+		p.declared[className] = true
+		className = p.qualify(className)
+		j++
+	} else if !ctx.isDefault {
+		return j
+	}
+
+	// Skip type parameters and heritage clauses up to the class body
+	for j < len(p.toks) && !p.is(j, "{") {
+		switch {
+		case p.is(j, "<"):
+			j = p.skipAngles(j)
+		case p.is(j, "("), p.is(j, "["):
+			j = p.skipBalanced(j)
+		case p.is(j, ";"):
+			return j
+		default:
+			j++
+		}
+	}
+	if j >= len(p.toks) {
+		return j
+	}
+
+	end := p.skipBalanced(j)
+	p.parseClassBody(j, end-1, className, ctx.exported)
+	return end
+}
+
+func (p *parser) parseClassBody(open, close int, className string, classExported bool) {
+	docTok := -1
+	for k := open + 1; k < close; {
+		if p.is(k, ";") {
+			k++
+			continue
+		}
+
+		// Decorators
+		if p.is(k, "@") {
+			if docTok == -1 {
+				docTok = k
+			}
+			k++
+			for p.isIdent(k) || p.is(k, ".") {
+				k++
+			}
+			if p.is(k, "(") {
+				k = p.skipBalanced(k)
+			}
+			continue
+		}
+
+		memberStart := k
+		decl := declaration{receiver: className, first: memberStart, doc: memberStart}
+		if docTok != -1 {
+			decl.doc = docTok
+			docTok = -1
+		}
+
+		private, accessor := false, ""
+		for p.isIdent(k) && memberModifiers[p.tok(k).text] && !p.isMemberEnd(k+1) {
+			switch p.tok(k).text {
+			case "private":
+				private = true
+			case "get", "set":
+				accessor = p.tok(k).text
+			}
+			k++
+		}
+		if p.is(k, "*") {
+			k++
+		}
+
+		// Static initialization block
+		if p.is(k, "{") {
+			k = p.skipBalanced(k)
+			continue
+		}
+
+		name, next := p.memberName(k)
+		if next == k {
+			k++
+			continue
+		}
+		if strings.HasPrefix(name, "#") {
+			private = true
+		}
+		decl.name, decl.accessor = name, accessor
+		k = next
+		if p.is(k, "?") || p.is(k, "!") {
+			k++
+		}
+
+		exported := classExported && !private
+		if p.is(k, "(") || p.is(k, "<") {
+			if sig, ok := p.method(k); ok {
+				if name != "" && name != "constructor" {
+					p.record(decl, sig, exportContext{tok: -1}, exported)
+				}
+				k = sig.end
+				continue
+			}
+			// Overload or abstract signature without a body
+			if name != "" && accessor == "" {
+				p.addOverload(decl)
+			}
+			k = p.skipToMemberEnd(k)
 			continue
 		}
 
-		name := nameMatch[2]
-		if name == "" {
-			name = nameMatch[3] // Use const name if it's an arrow function
+		// Property, possibly initialised with an arrow function
+		if p.is(k, ":") {
+			k = p.skipType(k+1, false)
 		}
+		if p.is(k, "=") {
+			if sig, ok := p.functionExpression(k + 1); ok && name != "" {
+				p.record(decl, sig, exportContext{tok: -1}, exported)
+				k = sig.end
+			}
+		}
+		k = p.skipToMemberEnd(k)
+	}
+}
+
+// memberName reads a class or object member name at token k, returning the name
+// and the index of the following token. It returns an empty name if none is present,
+// or if the name is computed, like [Symbol.iterator], since it can't be called by name.
+func (p *parser) memberName(k int) (string, int) {
+	t := p.tok(k)
+	switch {
+	case t.kind == tokIdent, t.kind == tokNumber:
+		return t.text, k + 1
+	case t.kind == tokString:
+		return strings.Trim(t.text, `"'`), k + 1
+	case p.is(k, "["):
+		return "", p.skipBalanced(k)
+	}
+	return "", k
+}
+
+// isMemberEnd reports whether token k ends a member name, meaning the previous
+// identifier was the name itself rather than a modifier
+func (p *parser) isMemberEnd(k int) bool {
+	t := p.tok(k)
+	if k >= len(p.toks) {
+		return true
+	}
+	if t.kind != tokPunct {
+		return false
+	}
+	switch t.text {
+	case "(", ":", "=", ";", "?", "!", "<", ",", "}":
+		return true
+	}
+	return false
+}
+
+// skipToMemberEnd skips the remainder of a class member, including any initializer
+func (p *parser) skipToMemberEnd(k int) int {
+	k = p.skipExpression(k)
+	if p.is(k, ";") || p.is(k, ",") {
+		k++
+	}
+	return k
+}
+
+func (p *parser) addOverload(decl declaration) {
+	key := decl.receiver + "." + decl.name
+	if _, ok := p.overloads[key]; !ok {
+		p.overloads[key] = decl
+	}
+}
+
+// record adds a function for decl whose body ends at sig.end. Any pending
+// overload signatures for the same name are folded into its source, and the second
+// accessor of a pair is folded into the first.
+func (p *parser) record(decl declaration, sig signature, ctx exportContext, exported bool) {
+	exported = exported && (p.namespace == "" || p.namespaceExported)
+	key := decl.receiver + "." + decl.name
+	if overload, ok := p.overloads[key]; ok {
+		decl.first, decl.doc = overload.first, overload.doc
+		delete(p.overloads, key)
+	}
+
+	start, end := p.tok(decl.first).start, p.tok(sig.end-1).end
+	source := p.src[start:end]
+	startLine := p.lineAt(start)
+	if ctx.exported {
+		prefix := "export "
+		if ctx.isDefault {
+			prefix = "export default "
+		}
+		if !strings.HasPrefix(source, "export") {
+			source = prefix + source
+		}
+		startLine = p.lineAt(p.tok(ctx.tok).start)
+	}
+
+	if decl.accessor != "" {
+		if i, ok := p.accessors[key]; ok {
+			p.mergeAccessor(i, decl, sig, strings.TrimSpace(source), startLine, exported)
+			return
+		}
+		p.accessors[key] = len(p.functions)
+	}
+
+	p.functions = append(p.functions, types.Function{
+		Name:       decl.name,
+		SourceCode: strings.TrimSpace(source),
+		IsExported: exported,
+		Receiver:   decl.receiver,
+		Params:     sig.params,
+		Results:    sig.results,
+		Doc:        commentText(p.tok(decl.doc).comments),
+		StartLine:  startLine,
+		EndLine:    p.lineAt(end - 1),
+	})
+	p.ranges = append(p.ranges, [][2]int{{decl.first, sig.end}})
+}
+
+// mergeAccessor folds an accessor into the function recorded for the other accessor of
+// its property. The getter provides the results and the setter the parameters.
+func (p *parser) mergeAccessor(i int, decl declaration, sig signature, source string, startLine int, exported bool) {
+	fn := &p.functions[i]
+	fn.SourceCode += "\n\n" + source
+	fn.IsExported = fn.IsExported || exported
+	fn.StartLine = min(fn.StartLine, startLine)
+	fn.EndLine = max(fn.EndLine, p.lineAt(p.tok(sig.end-1).end-1))
+	if fn.Doc == "" {
+		fn.Doc = commentText(p.tok(decl.doc).comments)
+	}
+	if decl.accessor == "set" {
+		fn.Params = sig.params
+	} else {
+		fn.Results = sig.results
+	}
+	p.ranges[i] = append(p.ranges[i], [2]int{decl.first, sig.end})
+}
 
-		if name == "" {
+// references returns the top-level declarations and imports used within token ranges
+func (p *parser) references(ranges [][2]int, self string) []string {
+	seen := make(map[string]bool)
+	for _, r := range ranges {
+		for k := r[0]; k < r[1]; k++ {
+			t := p.toks[k]
+			if t.kind != tokIdent || t.text == self || !p.declared[t.text] {
+				continue
+			}
+			// Property accesses and object keys are not references
+			if p.is(k-1, ".") || p.is(k-1, "?.") || (p.is(k+1, ":") && (p.is(k-1, "{") || p.is(k-1, ","))) {
+				continue
+			}
+			seen[t.text] = true
+		}
+	}
+
+	refs := make([]string, 0, len(seen))
+	for name := range seen {
+		refs = append(refs, name)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// skipBalanced returns the index just past the bracket matching the opener at i
+func (p *parser) skipBalanced(i int) int {
+	depth := 0
+	for k := i; k < len(p.toks); k++ {
+		t := p.toks[k]
+		if t.kind != tokPunct {
+			continue
+		}
+		switch t.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return k + 1
+			}
+		}
+	}
+	return len(p.toks)
+}
+
+// skipAngles returns the index just past the '>' matching the '<' at i
+func (p *parser) skipAngles(i int) int {
+	depth := 0
+	for k := i; k < len(p.toks); k++ {
+		switch {
+		case p.is(k, "("), p.is(k, "["), p.is(k, "{"):
+			k = p.skipBalanced(k) - 1
+		case p.is(k, "<"):
+			depth++
+		case p.is(k, ">"):
+			depth--
+			if depth == 0 {
+				return k + 1
+			}
+		case p.is(k, ";"), p.is(k, ")"), p.is(k, "}"):
+			return k
+		}
+	}
+	return len(p.toks)
+}
+
+// skipType returns the index of the first token after a type annotation starting at i.
+// When stopAtArrow is set, a '=>' ends the type instead of forming a function type,
+// which is needed for arrow function return types.
+func (p *parser) skipType(i int, stopAtArrow bool) int {
+	expectOperand := true
+	conditionals := 0
+
+	for k := i; k < len(p.toks); {
+		t := p.toks[k]
+		if expectOperand {
+			switch {
+			case p.is(k, "{"), p.is(k, "["):
+				k = p.skipBalanced(k)
+				expectOperand = false
+			case p.is(k, "("):
+				k = p.skipBalanced(k)
+				expectOperand = false
+			case p.is(k, "<"):
+				k = p.skipAngles(k)
+			case p.is(k, "|"), p.is(k, "&"), p.is(k, "-"):
+				k++
+			case t.kind == tokIdent && typeOperators[t.text]:
+				k++
+			case t.kind != tokPunct:
+				k++
+				expectOperand = false
+			default:
+				return k
+			}
 			continue
 		}
 
-		functions = append(functions, types.Function{
-			Name:       name,
-			SourceCode: strings.TrimSpace(fullMatch),
-			IsExported: isExported,
-		})
+		switch {
+		case p.is(k, "[") || p.is(k, "<"):
+			if p.is(k, "[") {
+				k = p.skipBalanced(k)
+			} else {
+				k = p.skipAngles(k)
+			}
+		case p.is(k, "|"), p.is(k, "&"), p.is(k, "."):
+			k++
+			expectOperand = true
+		case p.is(k, "=>") && !stopAtArrow:
+			k++
+			expectOperand = true
+		case p.is(k, "?") && !t.newlineBefore:
+			conditionals++
+			k++
+			expectOperand = true
+		case p.is(k, ":") && conditionals > 0:
+			conditionals--
+			k++
+			expectOperand = true
+		case t.kind == tokIdent && typeOperators[t.text] && !t.newlineBefore:
+			k++
+			expectOperand = true
+		default:
+			return k
+		}
 	}
+	return len(p.toks)
+}
 
-	return functions, nil
+// skipExpression returns the index of the token ending the expression starting at i:
+// a ',' or ';' or closing bracket at depth zero, or a line break that ends the statement
+func (p *parser) skipExpression(i int) int {
+	for k := i; k < len(p.toks); {
+		t := p.toks[k]
+		if t.newlineBefore && p.endsExpression(k-1) && !p.continuesExpression(k) {
+			return k
+		}
+		if t.kind == tokPunct {
+			switch t.text {
+			case "(", "[", "{":
+				k = p.skipBalanced(k)
+				continue
+			case ")", "]", "}", ";", ",":
+				return k
+			}
+		}
+		k++
+	}
+	return len(p.toks)
 }
 
-// Helper function for logging
-func min(x, y int) int {
-	if x < y {
-		return x
+// endsExpression reports whether token k can be the last token of an expression
+func (p *parser) endsExpression(k int) bool {
+	t := p.tok(k)
+	switch t.kind {
+	case tokIdent:
+		return !expressionKeywords[t.text]
+	case tokPunct:
+		switch t.text {
+		case ")", "]", "}", "++", "--", ">":
+			return true
+		}
+		return false
+	default:
+		return true
 	}
-	return y
 }
 
-// max returns the larger of x or y
-func max(x, y int) int {
-	if x > y {
-		return x
+// continuesExpression reports whether token k, at the start of a line, continues
+// the expression on the previous line
+func (p *parser) continuesExpression(k int) bool {
+	t := p.tok(k)
+	switch t.kind {
+	case tokIdent:
+		switch t.text {
+		case "as", "satisfies", "instanceof", "in":
+			return true
+		}
+		return false
+	case tokPunct:
+		switch t.text {
+		case "(", "[", "{", "!", "~", "++", "--", "#", "@":
+			return false
+		}
+		return true
+	default:
+		return false
 	}
-	return y
 }
@@ -368,6 +368,268 @@ export const updateSomeTable = async ({
 				},
 			},
 		},
+		{
+			name: "braces inside strings, templates, comments and regex literals",
+			input: `
+						function tricky(input: string) {
+							const open = "{";
+							const close = '}';
+							const tpl = ` + "`${input} { ${ { a: 1 }.a } }`" + `;
+							// a stray } in a comment
+							/* and { another */
+							return /[{}]+/g.test(input + open + close + tpl);
+						}
+						export function after() { return 1 }
+					`,
+			expected: []types.Function{
+				{
+					Name: "tricky",
+					SourceCode: `function tricky(input: string) {
+							const open = "{";
+							const close = '}';
+							const tpl = ` + "`${input} { ${ { a: 1 }.a } }`" + `;
+							// a stray } in a comment
+							/* and { another */
+							return /[{}]+/g.test(input + open + close + tpl);
+						}`,
+					IsExported: false,
+				},
+				{
+					Name:       "after",
+					SourceCode: `export function after() { return 1 }`,
+					IsExported: true,
+				},
+			},
+		},
+		{
+			name: "class methods",
+			input: `
+						export class Service {
+							private readonly count: number = 0
+							constructor(private db: Db) {}
+							async load(id: string): Promise<{ id: string }> {
+								return { id };
+							}
+							private helper() { return this.count }
+							handle = (event: Event) => { return event }
+						}
+					`,
+			expected: []types.Function{
+				{
+					Name:     "load",
+					Receiver: "Service",
+					SourceCode: `async load(id: string): Promise<{ id: string }> {
+								return { id };
+							}`,
+					IsExported: true,
+				},
+				{
+					Name:       "helper",
+					Receiver:   "Service",
+					SourceCode: `private helper() { return this.count }`,
+					IsExported: false,
+				},
+				{
+					Name:       "handle",
+					Receiver:   "Service",
+					SourceCode: `handle = (event: Event) => { return event }`,
+					IsExported: true,
+				},
+			},
+		},
+		{
+			name: "object literal methods",
+			input: `
+						export const api = {
+							base: "/api",
+							get(path: string) { return fetch(path) },
+							post: async (path: string) => { return fetch(path) },
+						}
+					`,
+			expected: []types.Function{
+				{
+					Name:       "get",
+					Receiver:   "api",
+					SourceCode: `get(path: string) { return fetch(path) }`,
+					IsExported: true,
+				},
+				{
+					Name:       "post",
+					Receiver:   "api",
+					SourceCode: `post: async (path: string) => { return fetch(path) }`,
+					IsExported: true,
+				},
+			},
+		},
+		{
+			name: "accessors",
+			input: `
+						export class Temperature {
+							private celsius = 0
+							get fahrenheit(): number { return this.celsius * 1.8 + 32 }
+							set fahrenheit(value: number) { this.celsius = (value - 32) / 1.8 }
+							get kelvin() { return this.celsius + 273.15 }
+						}
+						export const settings = {
+							get theme() { return load("theme") },
+							set theme(value: string) { save("theme", value) },
+						}
+					`,
+			expected: []types.Function{
+				{
+					Name:     "fahrenheit",
+					Receiver: "Temperature",
+					SourceCode: `get fahrenheit(): number { return this.celsius * 1.8 + 32 }
+
+							set fahrenheit(value: number) { this.celsius = (value - 32) / 1.8 }`,
+					IsExported: true,
+				},
+				{
+					Name:       "kelvin",
+					Receiver:   "Temperature",
+					SourceCode: `get kelvin() { return this.celsius + 273.15 }`,
+					IsExported: true,
+				},
+				{
+					Name:     "theme",
+					Receiver: "settings",
+					SourceCode: `get theme() { return load("theme") }
+
+							set theme(value: string) { save("theme", value) }`,
+					IsExported: true,
+				},
+			},
+		},
+		{
+			name: "namespaces",
+			input: `
+						export namespace Geometry {
+							export function area(r: number) { return Math.PI * r * r }
+							function helper() { return 1 }
+							export namespace Lines {
+								export const length = (a: number, b: number) => Math.abs(b - a)
+							}
+							export class Point {
+								norm() { return 0 }
+							}
+						}
+						namespace Internal.Util {
+							export function id<T>(x: T) { return x }
+						}
+						declare module "external" {
+							export function ambient(): void;
+						}
+					`,
+			expected: []types.Function{
+				{
+					Name:       "area",
+					Receiver:   "Geometry",
+					SourceCode: `export function area(r: number) { return Math.PI * r * r }`,
+					IsExported: true,
+				},
+				{
+					Name:       "helper",
+					Receiver:   "Geometry",
+					SourceCode: `function helper() { return 1 }`,
+					IsExported: false,
+				},
+				{
+					Name:       "length",
+					Receiver:   "Geometry.Lines",
+					SourceCode: `export const length = (a: number, b: number) => Math.abs(b - a)`,
+					IsExported: true,
+				},
+				{
+					Name:       "norm",
+					Receiver:   "Geometry.Point",
+					SourceCode: `norm() { return 0 }`,
+					IsExported: true,
+				},
+				{
+					Name:       "id",
+					Receiver:   "Internal.Util",
+					SourceCode: `export function id<T>(x: T) { return x }`,
+					IsExported: false,
+				},
+			},
+		},
+		{
+			name: "computed names",
+			input: `
+						export class Range {
+							[Symbol.iterator]() { return this.values() }
+							['computed'] = () => { return 1 }
+							values() { return [] }
+						}
+						export const table = {
+							['key' + 1]() { return 1 },
+							[name]: () => 2,
+							plain() { return 3 },
+						}
+					`,
+			expected: []types.Function{
+				{
+					Name:       "values",
+					Receiver:   "Range",
+					SourceCode: `values() { return [] }`,
+					IsExported: true,
+				},
+				{
+					Name:       "plain",
+					Receiver:   "table",
+					SourceCode: `plain() { return 3 }`,
+					IsExported: true,
+				},
+			},
+		},
+		{
+			name: "overloads",
+			input: `
+						export function parse(input: string): number;
+						export function parse(input: number): number;
+						export function parse(input: string | number): number {
+							return Number(input);
+						}
+					`,
+			expected: []types.Function{
+				{
+					Name: "parse",
+					SourceCode: `export function parse(input: string): number;
+						export function parse(input: number): number;
+						export function parse(input: string | number): number {
+							return Number(input);
+						}`,
+					IsExported: true,
+				},
+			},
+		},
+		{
+			name: "export default and re-exports",
+			input: `
+						export default function main() { return 0 }
+						function helper() { return 1 }
+						const other = (x: number) => x * 2
+						export { helper, other as renamed }
+						export { external } from "./external"
+					`,
+			expected: []types.Function{
+				{
+					Name:       "main",
+					SourceCode: `export default function main() { return 0 }`,
+					IsExported: true,
+				},
+				{
+					Name:       "helper",
+					SourceCode: `function helper() { return 1 }`,
+					IsExported: true,
+				},
+				{
+					Name:       "other",
+					SourceCode: `const other = (x: number) => x * 2`,
+					IsExported: true,
+				},
+			},
+		},
 	}
 
 	// Helper function to normalize whitespace for comparison
@@ -401,6 +663,7 @@ export const updateSomeTable = async ({
 				}
 				actual := functions[i]
 				assert.Equal(t, expected.Name, actual.Name)
+				assert.Equal(t, expected.Receiver, actual.Receiver)
 				assert.Equal(t, expected.IsExported, actual.IsExported)
 				assert.Equal(t,
 					normalizeWhitespace(expected.SourceCode),
@@ -411,3 +674,77 @@ export const updateSomeTable = async ({
 		})
 	}
 }
+
+func TestTypeScriptSupport_GetFunctions_Metadata(t *testing.T) {
+	ts := NewTypeScriptSupport()
+
+	input := `import { db } from "./db"
+import type { Record } from "./types"
+
+export class Repo {
+	/** Finds a record by id */
+	find(id: string, opts?: { cache: boolean }): Promise<Record> {
+		return db.find(id)
+	}
+}
+
+export
+function standalone() {
+	return "}"
+}
+`
+
+	functions, err := ts.GetFunctions(input)
+	assert.NoError(t, err)
+	assert.Len(t, functions, 2)
+
+	assert.Equal(t, "find", functions[0].Name)
+	assert.Equal(t, "Repo", functions[0].Receiver)
+	assert.Equal(t, 6, functions[0].StartLine)
+	assert.Equal(t, 8, functions[0].EndLine)
+	assert.Equal(t, "Finds a record by id", functions[0].Doc)
+	assert.Equal(t, []types.Param{{Name: "id", Type: "string"}, {Name: "opts", Type: "{ cache: boolean }"}}, functions[0].Params)
+	assert.Equal(t, []types.Param{{Type: "Promise<Record>"}}, functions[0].Results)
+	assert.Equal(t, []string{"Record", "db"}, functions[0].References)
+
+	assert.Equal(t, "standalone", functions[1].Name)
+	assert.Equal(t, "", functions[1].Receiver)
+	assert.Equal(t, 11, functions[1].StartLine)
+	assert.Equal(t, 14, functions[1].EndLine)
+}
+
+func TestTypeScriptSupport_GetFunctions_AccessorPair(t *testing.T) {
+	ts := NewTypeScriptSupport()
+	input := `import { clamp } from "./math"
+import { Store } from "./store"
+
+export class Volume {
+	/** The volume, between 0 and 11 */
+	get level(): number {
+		return Store.get("level")
+	}
+
+	reset() {}
+
+	set level(value: number) {
+		Store.set("level", clamp(value, 0, 11))
+	}
+}
+`
+
+	functions, err := ts.GetFunctions(input)
+	assert.NoError(t, err)
+	assert.Len(t, functions, 2)
+
+	assert.Equal(t, "level", functions[0].Name)
+	assert.Equal(t, "Volume", functions[0].Receiver)
+	assert.Equal(t, 6, functions[0].StartLine)
+	assert.Equal(t, 14, functions[0].EndLine)
+	assert.Equal(t, "The volume, between 0 and 11", functions[0].Doc)
+	assert.Equal(t, []types.Param{{Name: "value", Type: "number"}}, functions[0].Params)
+	assert.Equal(t, []types.Param{{Type: "number"}}, functions[0].Results)
+	assert.Equal(t, []string{"Store", "clamp"}, functions[0].References)
+	assert.NotContains(t, functions[0].SourceCode, "reset")
+
+	assert.Equal(t, "reset", functions[1].Name)
+}
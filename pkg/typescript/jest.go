@@ -1,21 +1,77 @@
 package typescript
 
 import (
-	"os/exec"
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gwkline/artestian/pkg/command"
+	"github.com/gwkline/artestian/pkg/sandbox"
+	"github.com/gwkline/artestian/types"
 )
 
-type JestRunner struct{}
+type JestRunner struct {
+	sandbox *sandbox.Sandbox
+}
+
+func (r *JestRunner) RunTests(ctx context.Context, rootDir, testFilePath string) (types.RunResult, error) {
+	return r.run(ctx, rootDir, testFilePath)
+}
+
+// RerunTests runs Jest options.Runs times, since Jest can't repeat tests itself, and
+// combines the runs into one result
+func (r *JestRunner) RerunTests(ctx context.Context, rootDir, testFilePath string, options types.RerunOptions) (types.RunResult, error) {
+	var extraArgs []string
+	if options.Shuffle {
+		extraArgs = append(extraArgs, "--randomize", "--showSeed")
+	}
 
-func (r *JestRunner) RunTests(rootDir, testFilePath string) (bool, string, error) {
-	cmd := exec.Command("npx", "jest", testFilePath, "--no-cache")
-	cmd.Dir = rootDir
+	combined := types.RunResult{Passed: true}
+	var outputs []string
+	for i := 0; i < max(1, options.Runs); i++ {
+		result, err := r.run(ctx, rootDir, testFilePath, extraArgs...)
+		if err != nil {
+			return types.RunResult{}, err
+		}
+		combined.Passed = combined.Passed && result.Passed
+		combined.Tests = append(combined.Tests, result.Tests...)
+		combined.Diagnostics = append(combined.Diagnostics, result.Diagnostics...)
+		combined.TimedOut = combined.TimedOut || result.TimedOut
+		if combined.Panic == "" {
+			combined.Panic = result.Panic
+		}
+		outputs = append(outputs, fmt.Sprintf("run %d:\n%s", i+1, result.Output))
+	}
+	combined.Output = strings.Join(outputs, "\n")
+	return combined, nil
+}
 
-	output, err := cmd.CombinedOutput()
+func (r *JestRunner) run(ctx context.Context, rootDir, testFilePath string, extraArgs ...string) (types.RunResult, error) {
+	args := append([]string{"jest", testFilePath, "--no-cache", "--json", "--testLocationInResults"}, extraArgs...)
+	ctx, cancel := r.sandbox.WithTimeout(ctx)
+	defer cancel()
+	cmd, err := r.sandbox.Command(ctx, filepath.Dir(testFilePath), rootDir, "npx", args...)
 	if err != nil {
-		// Jest returns non-zero exit code on test failures
-		return false, string(output), nil
+		return types.RunResult{}, err
+	}
+
+	// The JSON report is written to stdout, everything else to stderr
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err := command.Canceled(ctx); err != nil {
+		return types.RunResult{}, err
 	}
-	return true, string(output), nil
+
+	result := parseJestJSON(r.sandbox.RealPaths(stdout.String()), r.sandbox.RealPaths(stderr.String()+stdout.String()))
+	// Jest returns non-zero exit code on test failures
+	result.Passed = result.Passed && err == nil
+	result.TimedOut = result.TimedOut || command.TimedOut(ctx)
+	return result, nil
 }
 
 func (r *JestRunner) GetName() string {
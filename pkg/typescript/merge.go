@@ -0,0 +1,216 @@
+package typescript
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	sideEffectImport = regexp.MustCompile(`^import\s+(['"])([^'"]+)['"]\s*(;?)$`)
+	fromImport       = regexp.MustCompile(`^import\s+(type\s+)?(.+?)\s+from\s+(['"])([^'"]+)['"]\s*(;?)$`)
+	importEnd        = regexp.MustCompile(`(^import\s+['"][^'"]+['"]|\sfrom\s+['"][^'"]+['"]|=\s*require\(['"][^'"]+['"]\))\s*;?\s*$`)
+)
+
+// moduleImports is what the merged file imports from one module
+type moduleImports struct {
+	module    string
+	typeOnly  bool
+	quote     string
+	semicolon string
+	defaults  []string
+	namespace []string
+	named     []string
+	bare      bool // Imported for its side effects only
+}
+
+// MergeTests combines test files generated separately into one file. Imports at the top
+// of each file are combined, with each name imported once, and the first file's leading
+// comments, like a @jest-environment docblock, are kept. Everything after the imports is
+// kept as it is, in order.
+func (ts *TypeScriptSupport) MergeTests(tests []string) (string, error) {
+	var header string
+	var modules []*moduleImports
+	byKey := make(map[string]*moduleImports)
+	var other []string // Import statements that aren't understood, kept once each
+	var bodies []string
+
+	for i, test := range tests {
+		comments, statements, body, err := splitImports(test)
+		if err != nil {
+			return "", fmt.Errorf("failed to read imports of test %d: %w", i+1, err)
+		}
+		if i == 0 {
+			header = comments
+		}
+		bodies = append(bodies, body)
+
+		for _, statement := range statements {
+			if match := sideEffectImport.FindStringSubmatch(statement); match != nil {
+				module(&modules, byKey, match[2], false, match[1], match[3]).bare = true
+				continue
+			}
+			match := fromImport.FindStringSubmatch(statement)
+			if match == nil {
+				if !contains(other, statement) {
+					other = append(other, statement)
+				}
+				continue
+			}
+			m := module(&modules, byKey, match[4], match[1] != "", match[3], match[5])
+			clause := match[2]
+
+			if open := strings.Index(clause, "{"); open >= 0 {
+				close := strings.LastIndex(clause, "}")
+				if close < open {
+					return "", fmt.Errorf("malformed import: %s", statement)
+				}
+				for _, name := range strings.Split(clause[open+1:close], ",") {
+					if name = strings.Join(strings.Fields(name), " "); name != "" && !contains(m.named, name) {
+						m.named = append(m.named, name)
+					}
+				}
+				clause = clause[:open]
+			}
+			for _, part := range strings.Split(clause, ",") {
+				part = strings.Join(strings.Fields(part), " ")
+				switch {
+				case part == "":
+				case strings.HasPrefix(part, "* as "):
+					if !contains(m.namespace, part) {
+						m.namespace = append(m.namespace, part)
+					}
+				case !contains(m.defaults, part):
+					m.defaults = append(m.defaults, part)
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	for _, m := range modules {
+		for _, statement := range m.statements() {
+			b.WriteString(statement + "\n")
+		}
+	}
+	for _, statement := range other {
+		b.WriteString(statement + "\n")
+	}
+	for _, body := range bodies {
+		if body != "" {
+			fmt.Fprintf(&b, "\n%s\n", body)
+		}
+	}
+	return b.String(), nil
+}
+
+// module returns the imports of a module, adding them the first time it's seen
+func module(modules *[]*moduleImports, byKey map[string]*moduleImports, name string, typeOnly bool, quote, semicolon string) *moduleImports {
+	key := fmt.Sprintf("%s|%t", name, typeOnly)
+	if m, ok := byKey[key]; ok {
+		return m
+	}
+	m := &moduleImports{module: name, typeOnly: typeOnly, quote: quote, semicolon: semicolon}
+	byKey[key] = m
+	*modules = append(*modules, m)
+	return m
+}
+
+// statements returns the import statements for the module. A namespace import can't be
+// combined with named imports, and each further default import needs its own statement.
+func (m *moduleImports) statements() []string {
+	from := " from " + m.quote + m.module + m.quote + m.semicolon
+	keyword := "import "
+	if m.typeOnly {
+		keyword = "import type "
+	}
+
+	var statements []string
+	var first []string
+	if len(m.defaults) > 0 {
+		first = append(first, m.defaults[0])
+	}
+	if len(m.named) > 0 {
+		first = append(first, "{ "+strings.Join(m.named, ", ")+" }")
+	}
+	if len(first) > 0 {
+		statements = append(statements, keyword+strings.Join(first, ", ")+from)
+	}
+	for _, name := range m.defaults[min(1, len(m.defaults)):] {
+		statements = append(statements, keyword+name+from)
+	}
+	for _, namespace := range m.namespace {
+		statements = append(statements, keyword+namespace+from)
+	}
+	if len(statements) == 0 && m.bare {
+		statements = append(statements, "import "+m.quote+m.module+m.quote+m.semicolon)
+	}
+	return statements
+}
+
+// splitImports splits a test file into its leading comments, the import statements at
+// the top, each on one line, and the rest of the file
+func splitImports(test string) (string, []string, string, error) {
+	lines := strings.Split(test, "\n")
+	var comments []string
+	var statements []string
+
+	i := 0
+	for i < len(lines) {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "//"):
+			if len(statements) == 0 {
+				comments = append(comments, lines[i])
+			}
+			i++
+		case strings.HasPrefix(trimmed, "/*"):
+			for ; i < len(lines); i++ {
+				if len(statements) == 0 {
+					comments = append(comments, lines[i])
+				}
+				if strings.Contains(lines[i], "*/") {
+					break
+				}
+			}
+			i++
+		case strings.HasPrefix(trimmed, "import ") || trimmed == "import":
+			start := i
+			var statement []string
+			for ; i < len(lines); i++ {
+				statement = append(statement, strings.TrimSpace(lines[i]))
+				joined := strings.Join(statement, " ")
+				if importEnd.MatchString(joined) || strings.HasSuffix(joined, ";") {
+					break
+				}
+			}
+			if i == len(lines) {
+				return "", nil, "", fmt.Errorf("unterminated import on line %d", start+1)
+			}
+			statements = append(statements, strings.Join(strings.Fields(strings.Join(statement, " ")), " "))
+			i++
+		default:
+			return trimComments(comments), statements, strings.TrimSpace(strings.Join(lines[i:], "\n")), nil
+		}
+	}
+	return trimComments(comments), statements, "", nil
+}
+
+// trimComments joins leading comment lines, dropping blank lines around them
+func trimComments(lines []string) string {
+	comments := strings.TrimSpace(strings.Join(lines, "\n"))
+	if comments == "" {
+		return ""
+	}
+	return comments + "\n\n"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package typescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeTests(t *testing.T) {
+	tests := []struct {
+		name     string
+		tests    []string
+		expected string
+	}{
+		{
+			name: "imports are combined",
+			tests: []string{
+				"/**\n * @jest-environment node\n */\nimport { add } from './math';\nimport type { Options } from './types';\n\ndescribe('add', () => {\n  it('adds', () => expect(add(1, 1)).toBe(2));\n});\n",
+				"// Tests for sub\nimport {\n  sub,\n  add,\n} from './math';\nimport * as fs from 'fs';\nimport './setup';\n\ndescribe('sub', () => {\n  it('subtracts', () => expect(sub(add(1, 1), 1)).toBe(1));\n});\n",
+				"import math, { mul } from './math';\nimport type { Options } from './types';\nimport './setup';\n\ntest('mul', () => expect(mul(2, 2)).toBe(4));\n",
+			},
+			expected: "/**\n * @jest-environment node\n */\n\n" +
+				"import math, { add, sub, mul } from './math';\n" +
+				"import type { Options } from './types';\n" +
+				"import * as fs from 'fs';\n" +
+				"import './setup';\n" +
+				"\ndescribe('add', () => {\n  it('adds', () => expect(add(1, 1)).toBe(2));\n});\n" +
+				"\ndescribe('sub', () => {\n  it('subtracts', () => expect(sub(add(1, 1), 1)).toBe(1));\n});\n" +
+				"\ntest('mul', () => expect(mul(2, 2)).toBe(4));\n",
+		},
+		{
+			name: "statements that aren't understood are kept once",
+			tests: []string{
+				"import fs = require(\"fs\");\n\ntest('a', () => {});\n",
+				"import fs = require(\"fs\");\n\ntest('b', () => {});\n",
+			},
+			expected: "import fs = require(\"fs\");\n\ntest('a', () => {});\n\ntest('b', () => {});\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := NewTypeScriptSupport().MergeTests(tt.tests)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, merged)
+		})
+	}
+}
+
+func TestMergeTests_UnterminatedImport(t *testing.T) {
+	_, err := NewTypeScriptSupport().MergeTests([]string{"import { add,\n  sub\n"})
+	assert.ErrorContains(t, err, "unterminated import on line 1")
+}
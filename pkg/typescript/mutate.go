@@ -0,0 +1,107 @@
+package typescript
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// swappedOperators maps each binary operator to the one it's replaced with
+var swappedOperators = map[string]string{
+	"===": "!==", "!==": "===", "==": "!=", "!=": "==",
+	"<": ">=", ">=": "<", ">": "<=", "<=": ">",
+	"+": "-", "-": "+", "*": "/", "/": "*",
+	"&&": "||", "||": "&&",
+}
+
+// Mutate returns copies of the source with one change each to function: swapped
+// operators, negated if conditions, changed numbers, strings and booleans, and returned
+// values replaced by undefined. Operators only count as binary when surrounded by
+// whitespace, which keeps unary minus and generic brackets intact.
+func (ts *TypeScriptSupport) Mutate(sourceCode string, function types.Function) ([]types.Mutant, error) {
+	tokens, err := tokenize(sourceCode)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: sourceCode, toks: tokens}
+	for i, c := range sourceCode {
+		if c == '\n' {
+			p.newlines = append(p.newlines, i)
+		}
+	}
+
+	// The function's tokens, from the opening brace of its body
+	from := sort.Search(len(p.toks), func(k int) bool { return p.lineAt(p.toks[k].start) >= function.StartLine })
+	to := sort.Search(len(p.toks), func(k int) bool { return p.lineAt(p.toks[k].start) > function.EndLine })
+	for from < to && !p.is(from, "{") && !p.is(from, "=>") {
+		if p.is(from, "(") {
+			from = p.skipBalanced(from)
+			continue
+		}
+		from++
+	}
+	if from >= to {
+		return nil, fmt.Errorf("function %s not found", function.Name)
+	}
+
+	var mutants []types.Mutant
+	seen := make(map[string]bool) // Different mutations can make the same change
+	replace := func(start, end int, replacement, description string) {
+		mutated := sourceCode[:start] + replacement + sourceCode[end:]
+		if seen[mutated] {
+			return
+		}
+		seen[mutated] = true
+		mutants = append(mutants, types.Mutant{
+			Description: description,
+			Line:        p.lineAt(start),
+			SourceCode:  mutated,
+		})
+	}
+
+	for k := from; k < to; k++ {
+		t := p.tok(k)
+		switch {
+		case t.kind == tokPunct && swappedOperators[t.text] != "" && p.spaced(t):
+			op := swappedOperators[t.text]
+			replace(t.start, t.end, op, fmt.Sprintf("replaced %s with %s", t.text, op))
+
+		case p.is(k, "if") && p.is(k+1, "("):
+			close := p.skipBalanced(k+1) - 1
+			if close > k+2 {
+				cond := sourceCode[p.tok(k+2).start:p.tok(close-1).end]
+				replace(p.tok(k+2).start, p.tok(close-1).end, "!("+cond+")", "negated if condition "+cond)
+			}
+
+		case t.kind == tokNumber:
+			if value, err := strconv.ParseFloat(t.text, 64); err == nil {
+				next := strconv.FormatFloat(value+1, 'f', -1, 64)
+				replace(t.start, t.end, next, fmt.Sprintf("replaced %s with %s", t.text, next))
+			}
+
+		case t.kind == tokString && len(t.text) > 2 && !p.is(k+1, ":"):
+			replace(t.start, t.end, `""`, fmt.Sprintf("replaced %s with an empty string", t.text))
+
+		case (p.is(k, "true") || p.is(k, "false")) && !p.is(k-1, ".") && !p.is(k+1, ":"):
+			flipped := map[string]string{"true": "false", "false": "true"}[t.text]
+			replace(t.start, t.end, flipped, fmt.Sprintf("replaced %s with %s", t.text, flipped))
+
+		case p.is(k, "return") && k+1 < to && !p.tok(k+1).newlineBefore && !p.is(k+1, ";") && !p.is(k+1, "}"):
+			end := p.skipExpression(k + 1)
+			if end > k+1 && end <= to {
+				replace(p.tok(k+1).start, p.tok(end-1).end, "undefined as any", "returned undefined")
+			}
+		}
+	}
+
+	return mutants, nil
+}
+
+// spaced reports whether a token is surrounded by whitespace, as binary operators
+// usually are
+func (p *parser) spaced(t token) bool {
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+	return t.start > 0 && t.end < len(p.src) && isSpace(p.src[t.start-1]) && isSpace(p.src[t.end])
+}
@@ -0,0 +1,60 @@
+package typescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gwkline/artestian/types"
+)
+
+const mutateSource = `import { Item } from "./item";
+
+export function total(items: Array<Item>, member: boolean): number {
+	let sum = -1 + items.length;
+	if (member && sum > 100) {
+		return sum - 10;
+	}
+	const label = { kind: "total", exact: true };
+	return sum;
+}
+
+export function other(): boolean {
+	return true;
+}
+`
+
+func TestTypeScriptSupport_Mutate(t *testing.T) {
+	mutants, err := NewTypeScriptSupport().Mutate(mutateSource, types.Function{Name: "total", StartLine: 3, EndLine: 10})
+	require.NoError(t, err)
+
+	expected := []types.Mutant{
+		{Description: "replaced 1 with 2", Line: 4, SourceCode: "let sum = -2 + items.length;"},
+		{Description: "replaced + with -", Line: 4, SourceCode: "let sum = -1 - items.length;"},
+		{Description: "negated if condition member && sum > 100", Line: 5, SourceCode: "if (!(member && sum > 100)) {"},
+		{Description: "replaced && with ||", Line: 5, SourceCode: "if (member || sum > 100) {"},
+		{Description: "replaced > with <=", Line: 5, SourceCode: "if (member && sum <= 100) {"},
+		{Description: "replaced 100 with 101", Line: 5, SourceCode: "if (member && sum > 101) {"},
+		{Description: "returned undefined", Line: 6, SourceCode: "return undefined as any;\n\t}"},
+		{Description: "replaced - with +", Line: 6, SourceCode: "return sum + 10;"},
+		{Description: "replaced 10 with 11", Line: 6, SourceCode: "return sum - 11;"},
+		{Description: `replaced "total" with an empty string`, Line: 8, SourceCode: `{ kind: "", exact: true }`},
+		{Description: "replaced true with false", Line: 8, SourceCode: `{ kind: "total", exact: false }`},
+		{Description: "returned undefined", Line: 9, SourceCode: "return undefined as any;\n}"},
+	}
+	require.Len(t, mutants, len(expected))
+	for i, mutant := range mutants {
+		assert.Equal(t, expected[i].Description, mutant.Description)
+		assert.Equal(t, expected[i].Line, mutant.Line)
+		assert.Contains(t, mutant.SourceCode, expected[i].SourceCode)
+		// The signature and the rest of the file are left alone
+		assert.Contains(t, mutant.SourceCode, "total(items: Array<Item>, member: boolean): number {")
+		assert.Contains(t, mutant.SourceCode, "export function other(): boolean {\n\treturn true;\n}\n")
+	}
+}
+
+func TestTypeScriptSupport_Mutate_NotFound(t *testing.T) {
+	_, err := NewTypeScriptSupport().Mutate(mutateSource, types.Function{Name: "missing", StartLine: 20, EndLine: 25})
+	assert.Error(t, err)
+}
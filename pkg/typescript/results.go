@@ -0,0 +1,148 @@
+package typescript
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// jestReport is the subset of `jest --json` output used to build results
+type jestReport struct {
+	Success     bool `json:"success"`
+	TestResults []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			FailureMessages []string `json:"failureMessages"`
+			Location        *struct {
+				Line   int `json:"line"`
+				Column int `json:"column"`
+			} `json:"location"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+var (
+	// tscPattern matches `tsc --pretty false` diagnostics, e.g. "a.test.ts(3,5): error TS2345: message"
+	tscPattern = regexp.MustCompile(`^(.+?)\((\d+),(\d+)\): error (TS\d+): (.+)$`)
+	// tscGlobalPattern matches diagnostics without a location, e.g. "error TS5083: Cannot read file 'tsconfig.json'."
+	tscGlobalPattern = regexp.MustCompile(`^error (TS\d+): (.+)$`)
+	// tsJestPattern matches diagnostics reported by ts-jest, e.g. "a.test.ts:3:5 - error TS2345: message"
+	tsJestPattern = regexp.MustCompile(`(\S+\.tsx?):(\d+):(\d+) - error (TS\d+): (.+)$`)
+	// stackPattern matches file locations in stack traces
+	stackPattern = regexp.MustCompile(`([^\s()]+\.[jt]sx?):(\d+):(\d+)`)
+	ansiPattern  = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// jestStatuses maps Jest assertion statuses to test statuses
+var jestStatuses = map[string]types.TestStatus{
+	"passed":   types.TestStatusPass,
+	"failed":   types.TestStatusFail,
+	"pending":  types.TestStatusSkip,
+	"skipped":  types.TestStatusSkip,
+	"todo":     types.TestStatusSkip,
+	"disabled": types.TestStatusSkip,
+}
+
+// parseJestJSON converts `jest --json` output into a structured result. The raw
+// output is returned unparsed if it is not a Jest report.
+func parseJestJSON(jsonOutput, output string) types.RunResult {
+	result := types.RunResult{Output: output}
+
+	var report jestReport
+	if err := json.Unmarshal([]byte(jsonOutput), &report); err != nil {
+		return result
+	}
+	result.Passed = report.Success
+
+	for _, suite := range report.TestResults {
+		// The suite failed before any test ran, e.g. a compile or import error
+		if len(suite.AssertionResults) == 0 && suite.Message != "" {
+			message := ansiPattern.ReplaceAllString(suite.Message, "")
+			result.Diagnostics = append(result.Diagnostics, parseDiagnostics(message)...)
+			result.Tests = append(result.Tests, types.TestCase{
+				Name:      suite.Name,
+				Status:    types.TestStatusFail,
+				Failures:  []string{strings.TrimSpace(message)},
+				Locations: findLocations(message),
+			})
+			continue
+		}
+
+		for _, assertion := range suite.AssertionResults {
+			tc := types.TestCase{
+				Name:   assertion.FullName,
+				Status: jestStatuses[assertion.Status],
+			}
+			if tc.Status == "" {
+				tc.Status = types.TestStatusFail
+			}
+			for _, message := range assertion.FailureMessages {
+				message = ansiPattern.ReplaceAllString(message, "")
+				if strings.Contains(message, "Exceeded timeout of") {
+					result.TimedOut = true
+				}
+				tc.Failures = append(tc.Failures, strings.TrimSpace(message))
+				tc.Locations = append(tc.Locations, findLocations(message)...)
+			}
+			if assertion.Location != nil && tc.Status == types.TestStatusFail {
+				tc.Locations = append([]types.Location{{File: suite.Name, Line: assertion.Location.Line, Column: assertion.Location.Column}}, tc.Locations...)
+			}
+			result.Tests = append(result.Tests, tc)
+		}
+	}
+
+	return result
+}
+
+// parseDiagnostics extracts TypeScript compiler diagnostics in either tsc or ts-jest format,
+// and global tsc diagnostics without a file. Indented lines following a diagnostic are
+// treated as its continuation.
+func parseDiagnostics(output string) []types.Diagnostic {
+	var diagnostics []types.Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		match := tscPattern.FindStringSubmatch(line)
+		if match == nil {
+			match = tsJestPattern.FindStringSubmatch(line)
+		}
+		if match == nil {
+			if global := tscGlobalPattern.FindStringSubmatch(line); global != nil {
+				diagnostics = append(diagnostics, types.Diagnostic{Code: global[1], Message: global[2]})
+				continue
+			}
+			if len(diagnostics) > 0 && strings.HasPrefix(line, "  ") && strings.TrimSpace(line) != "" {
+				last := &diagnostics[len(diagnostics)-1]
+				last.Message += "\n" + strings.TrimSpace(line)
+			}
+			continue
+		}
+
+		lineNum, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		diagnostics = append(diagnostics, types.Diagnostic{
+			Location: types.Location{File: strings.TrimSpace(match[1]), Line: lineNum, Column: column},
+			Code:     match[4],
+			Message:  match[5],
+		})
+	}
+	return diagnostics
+}
+
+func findLocations(text string) []types.Location {
+	var locations []types.Location
+	for _, match := range stackPattern.FindAllStringSubmatch(text, -1) {
+		if strings.Contains(match[1], "node_modules") {
+			continue
+		}
+		lineNum, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		locations = append(locations, types.Location{File: match[1], Line: lineNum, Column: column})
+	}
+	return locations
+}
@@ -0,0 +1,116 @@
+package typescript
+
+import (
+	"testing"
+
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJestJSON(t *testing.T) {
+	report := `{
+  "success": false,
+  "testResults": [
+    {
+      "name": "/repo/src/sum.test.ts",
+      "status": "failed",
+      "message": "",
+      "assertionResults": [
+        {
+          "fullName": "sum adds numbers",
+          "status": "passed",
+          "failureMessages": [],
+          "location": {"line": 4, "column": 3}
+        },
+        {
+          "fullName": "sum handles negatives",
+          "status": "failed",
+          "failureMessages": ["Error: expect(received).toBe(expected)\n\nExpected: -2\nReceived: 2\n    at Object.<anonymous> (/repo/src/sum.test.ts:9:25)"],
+          "location": {"line": 8, "column": 3}
+        },
+        {
+          "fullName": "sum is slow",
+          "status": "failed",
+          "failureMessages": ["thrown: \"Exceeded timeout of 5000 ms for a test."],
+          "location": null
+        }
+      ]
+    }
+  ]
+}`
+
+	result := parseJestJSON(report, report)
+
+	assert.False(t, result.Passed)
+	assert.True(t, result.TimedOut)
+	assert.Len(t, result.Tests, 3)
+	assert.Equal(t, types.TestStatusPass, result.Tests[0].Status)
+	assert.Empty(t, result.Tests[0].Failures)
+	assert.Equal(t, "sum handles negatives", result.Tests[1].Name)
+	assert.Equal(t, types.TestStatusFail, result.Tests[1].Status)
+	assert.Equal(t, []types.Location{
+		{File: "/repo/src/sum.test.ts", Line: 8, Column: 3},
+		{File: "/repo/src/sum.test.ts", Line: 9, Column: 25},
+	}, result.Tests[1].Locations)
+}
+
+func TestParseJestJSON_SuiteFailure(t *testing.T) {
+	report := `{
+  "success": false,
+  "testResults": [
+    {
+      "name": "/repo/src/sum.test.ts",
+      "status": "failed",
+      "message": "  \u001b[1mTest suite failed to run\u001b[22m\n\n    src/sum.test.ts:3:10 - error TS2305: Module '\"./sum\"' has no exported member 'sub'.\n",
+      "assertionResults": []
+    }
+  ]
+}`
+
+	result := parseJestJSON(report, report)
+
+	assert.Equal(t, []types.Diagnostic{
+		{
+			Location: types.Location{File: "src/sum.test.ts", Line: 3, Column: 10},
+			Code:     "TS2305",
+			Message:  `Module '"./sum"' has no exported member 'sub'.`,
+		},
+	}, result.Diagnostics)
+	assert.Len(t, result.Tests, 1)
+	assert.Equal(t, types.TestStatusFail, result.Tests[0].Status)
+}
+
+func TestParseJestJSON_NotJSON(t *testing.T) {
+	result := parseJestJSON("", "npx: command not found")
+
+	assert.False(t, result.Passed)
+	assert.Empty(t, result.Tests)
+	assert.Equal(t, "npx: command not found", result.Output)
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `src/sum.test.ts(3,10): error TS2345: Argument of type 'string' is not assignable to parameter of type 'number'.
+src/other.ts(12,1): error TS2322: Type 'A' is not assignable to type 'B'.
+  Property 'x' is missing in type 'A'.
+error TS5083: Cannot read file '/repo/tsconfig.base.json'.
+`
+
+	diagnostics := parseDiagnostics(output)
+
+	assert.Equal(t, []types.Diagnostic{
+		{
+			Location: types.Location{File: "src/sum.test.ts", Line: 3, Column: 10},
+			Code:     "TS2345",
+			Message:  "Argument of type 'string' is not assignable to parameter of type 'number'.",
+		},
+		{
+			Location: types.Location{File: "src/other.ts", Line: 12, Column: 1},
+			Code:     "TS2322",
+			Message:  "Type 'A' is not assignable to type 'B'.\nProperty 'x' is missing in type 'A'.",
+		},
+		{
+			Code:    "TS5083",
+			Message: "Cannot read file '/repo/tsconfig.base.json'.",
+		},
+	}, diagnostics)
+}
@@ -0,0 +1,307 @@
+package typescript
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokPunct
+	tokString
+	tokTemplate
+	tokRegex
+	tokNumber
+)
+
+// token is a single lexical token of TypeScript source. Offsets are byte
+// offsets into the original source.
+type token struct {
+	kind          tokenKind
+	text          string
+	start         int
+	end           int
+	newlineBefore bool
+	comments      []string // comments between the previous token and this one
+}
+
+// multiCharPuncts lists punctuators that are emitted as a single token, longest first.
+// '<' and '>' are always emitted on their own so generic brackets can be balanced.
+var multiCharPuncts = []string{
+	"...", "===", "!==", "**=", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "**",
+}
+
+// keywordsBeforeRegex are keywords after which a '/' starts a regular expression literal
+var keywordsBeforeRegex = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+type lexer struct {
+	src      string
+	pos      int
+	tokens   []token
+	newline  bool
+	comments []string
+}
+
+// tokenize splits TypeScript source into tokens, discarding whitespace. Comments are
+// attached to the token that follows them.
+// Strings, template literals (including nested ${} expressions) and regex literals
+// are each returned as a single token so their contents never affect bracket matching.
+func tokenize(src string) ([]token, error) {
+	l := &lexer{src: src}
+	if err := l.run(); err != nil {
+		return nil, err
+	}
+	return l.tokens, nil
+}
+
+func (l *lexer) run() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		start := l.pos
+
+		switch {
+		case c == '\n':
+			l.newline = true
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			end := strings.IndexByte(l.src[l.pos:], '\n')
+			if end == -1 {
+				l.pos = len(l.src)
+			} else {
+				l.pos += end
+			}
+			l.comments = append(l.comments, l.src[start:l.pos])
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end == -1 {
+				return fmt.Errorf("unterminated comment at line %d", l.line(start))
+			}
+			if strings.Contains(l.src[l.pos:l.pos+2+end], "\n") {
+				l.newline = true
+			}
+			l.pos += end + 4
+			l.comments = append(l.comments, l.src[start:l.pos])
+		case c == '"' || c == '\'':
+			end, err := l.scanString(l.pos)
+			if err != nil {
+				return err
+			}
+			l.emit(tokString, start, end)
+		case c == '`':
+			end, err := l.scanTemplate(l.pos)
+			if err != nil {
+				return err
+			}
+			l.emit(tokTemplate, start, end)
+		case c == '/' && l.regexAllowed():
+			end, ok := l.scanRegex(l.pos)
+			if !ok {
+				l.emit(tokPunct, start, start+1)
+				continue
+			}
+			l.emit(tokRegex, start, end)
+		case isIdentStart(c) || c == '#':
+			end := l.pos + 1
+			for end < len(l.src) && isIdentPart(l.src[end]) {
+				end++
+			}
+			l.emit(tokIdent, start, end)
+		case isDigit(c) || (c == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1])):
+			end := l.pos + 1
+			for end < len(l.src) && (isIdentPart(l.src[end]) || l.src[end] == '.') {
+				end++
+			}
+			l.emit(tokNumber, start, end)
+		default:
+			end := start + 1
+			for _, p := range multiCharPuncts {
+				if strings.HasPrefix(l.src[l.pos:], p) {
+					end = start + len(p)
+					break
+				}
+			}
+			l.emit(tokPunct, start, end)
+		}
+	}
+	return nil
+}
+
+func (l *lexer) emit(kind tokenKind, start, end int) {
+	l.tokens = append(l.tokens, token{
+		kind:          kind,
+		text:          l.src[start:end],
+		start:         start,
+		end:           end,
+		newlineBefore: l.newline,
+		comments:      l.comments,
+	})
+	l.newline = false
+	l.comments = nil
+	l.pos = end
+}
+
+// regexAllowed reports whether a '/' at the current position starts a regex literal
+// rather than a division operator, based on the previous token
+func (l *lexer) regexAllowed() bool {
+	if len(l.tokens) == 0 {
+		return true
+	}
+	prev := l.tokens[len(l.tokens)-1]
+	switch prev.kind {
+	case tokPunct:
+		return prev.text != ")" && prev.text != "]" && prev.text != "}"
+	case tokIdent:
+		return keywordsBeforeRegex[prev.text]
+	default:
+		return false
+	}
+}
+
+// scanString returns the offset just past the string literal starting at pos
+func (l *lexer) scanString(pos int) (int, error) {
+	quote := l.src[pos]
+	for i := pos + 1; i < len(l.src); i++ {
+		switch l.src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		case '\n':
+			return 0, fmt.Errorf("unterminated string literal at line %d", l.line(pos))
+		}
+	}
+	return 0, fmt.Errorf("unterminated string literal at line %d", l.line(pos))
+}
+
+// scanTemplate returns the offset just past the template literal starting at pos,
+// skipping over any nested ${} expressions
+func (l *lexer) scanTemplate(pos int) (int, error) {
+	for i := pos + 1; i < len(l.src); i++ {
+		switch {
+		case l.src[i] == '\\':
+			i++
+		case l.src[i] == '`':
+			return i + 1, nil
+		case strings.HasPrefix(l.src[i:], "${"):
+			end, err := l.scanExpression(i + 2)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		}
+	}
+	return 0, fmt.Errorf("unterminated template literal at line %d", l.line(pos))
+}
+
+// scanExpression returns the offset just past the '}' closing a template expression
+func (l *lexer) scanExpression(pos int) (int, error) {
+	depth := 0
+	for i := pos; i < len(l.src); i++ {
+		switch c := l.src[i]; {
+		case c == '"' || c == '\'':
+			end, err := l.scanString(i)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		case c == '`':
+			end, err := l.scanTemplate(i)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		case strings.HasPrefix(l.src[i:], "//"):
+			end := strings.IndexByte(l.src[i:], '\n')
+			if end == -1 {
+				return 0, fmt.Errorf("unterminated template expression at line %d", l.line(pos))
+			}
+			i += end
+		case strings.HasPrefix(l.src[i:], "/*"):
+			end := strings.Index(l.src[i+2:], "*/")
+			if end == -1 {
+				return 0, fmt.Errorf("unterminated comment at line %d", l.line(i))
+			}
+			i += end + 3
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i + 1, nil
+			}
+			depth--
+		}
+	}
+	return 0, fmt.Errorf("unterminated template expression at line %d", l.line(pos))
+}
+
+// scanRegex returns the offset just past the regex literal (including flags) starting at pos.
+// It returns false if no closing '/' is found on the same line.
+func (l *lexer) scanRegex(pos int) (int, bool) {
+	inClass := false
+	for i := pos + 1; i < len(l.src); i++ {
+		switch l.src[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return 0, false
+		case '/':
+			if inClass {
+				continue
+			}
+			end := i + 1
+			for end < len(l.src) && isIdentPart(l.src[end]) {
+				end++
+			}
+			return end, true
+		}
+	}
+	return 0, false
+}
+
+// commentText strips comment markers and JSDoc-style leading asterisks
+func commentText(comments []string) string {
+	var lines []string
+	for _, c := range comments {
+		if strings.HasPrefix(c, "//") {
+			lines = append(lines, strings.TrimSpace(strings.TrimPrefix(c, "//")))
+			continue
+		}
+		c = strings.TrimSuffix(strings.TrimPrefix(c, "/*"), "*/")
+		for _, line := range strings.Split(c, "\n") {
+			line = strings.TrimSpace(line)
+			line = strings.TrimSpace(strings.TrimLeft(line, "*"))
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (l *lexer) line(pos int) int {
+	return strings.Count(l.src[:pos], "\n") + 1
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
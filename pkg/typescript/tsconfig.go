@@ -0,0 +1,207 @@
+package typescript
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pathConfig holds the module resolution settings of a tsconfig.json. Configs pulled in
+// through "extends" are not followed.
+type pathConfig struct {
+	dir   string              // Directory containing the tsconfig.json
+	base  string              // Directory non-relative paths resolve against
+	paths map[string][]string // Path aliases like "@/*": ["src/*"]
+}
+
+// loadPathConfig loads the module resolution settings of the nearest tsconfig.json at or
+// above dir, or returns nil if there is none
+func loadPathConfig(dir string) *pathConfig {
+	path := findTsconfig(dir)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return parsePathConfig(filepath.Dir(path), data)
+}
+
+func parsePathConfig(dir string, data []byte) *pathConfig {
+	var raw struct {
+		CompilerOptions struct {
+			BaseURL string              `json:"baseUrl"`
+			Paths   map[string][]string `json:"paths"`
+		} `json:"compilerOptions"`
+	}
+	config := &pathConfig{dir: dir}
+	if err := json.Unmarshal([]byte(stripJSONC(string(data))), &raw); err != nil {
+		return config
+	}
+
+	config.paths = raw.CompilerOptions.Paths
+	if raw.CompilerOptions.BaseURL != "" {
+		config.base = filepath.Join(dir, raw.CompilerOptions.BaseURL)
+	}
+	return config
+}
+
+// stripJSONC removes the comments and trailing commas tsconfig.json allows, leaving
+// the contents of strings untouched
+func stripJSONC(src string) string {
+	var out strings.Builder
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(src))
+			out.WriteString(src[i:end])
+			i = end - 1
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			i--
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return out.String()
+			}
+			i += end + 3
+		case c == ',' && closesAfter(src, i+1):
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// closesAfter reports whether the next token after whitespace and comments closes an object or array
+func closesAfter(src string, i int) bool {
+	for i < len(src) {
+		switch {
+		case src[i] == ' ' || src[i] == '\t' || src[i] == '\n' || src[i] == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return false
+			}
+			i += end + 4
+		default:
+			return src[i] == '}' || src[i] == ']'
+		}
+	}
+	return false
+}
+
+// resolveModule returns the file an import specifier refers to, or "" for packages and
+// specifiers that don't resolve to a TypeScript file in the project
+func resolveModule(config *pathConfig, fromDir, specifier string) string {
+	if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") || specifier == "." || specifier == ".." {
+		return resolveFile(filepath.Join(fromDir, specifier))
+	}
+	if config == nil {
+		return ""
+	}
+
+	base := config.base
+	if base == "" {
+		base = config.dir
+	}
+	for _, pattern := range pathPatterns(config.paths) {
+		wildcard, ok := matchPathPattern(pattern, specifier)
+		if !ok {
+			continue
+		}
+		for _, target := range config.paths[pattern] {
+			if file := resolveFile(filepath.Join(base, strings.Replace(target, "*", wildcard, 1))); file != "" {
+				return file
+			}
+		}
+	}
+
+	if config.base != "" {
+		return resolveFile(filepath.Join(config.base, specifier))
+	}
+	return ""
+}
+
+// pathPatterns returns the paths keys in the order TypeScript tries them, longest prefix first
+func pathPatterns(paths map[string][]string) []string {
+	patterns := make([]string, 0, len(paths))
+	for pattern := range paths {
+		patterns = append(patterns, pattern)
+	}
+	prefix := func(pattern string) int {
+		if star := strings.IndexByte(pattern, '*'); star != -1 {
+			return star
+		}
+		return len(pattern) + 1 // Exact matches win
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if prefix(patterns[i]) != prefix(patterns[j]) {
+			return prefix(patterns[i]) > prefix(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	return patterns
+}
+
+// matchPathPattern matches a specifier against a tsconfig paths key with at most one
+// "*", returning the text the wildcard matched
+func matchPathPattern(pattern, specifier string) (string, bool) {
+	star := strings.IndexByte(pattern, '*')
+	if star == -1 {
+		return "", pattern == specifier
+	}
+	prefix, suffix := pattern[:star], pattern[star+1:]
+	if len(specifier) < len(prefix)+len(suffix) || !strings.HasPrefix(specifier, prefix) || !strings.HasSuffix(specifier, suffix) {
+		return "", false
+	}
+	return specifier[len(prefix) : len(specifier)-len(suffix)], true
+}
+
+// resolveFile applies TypeScript's extension lookup to an import path. Imports written
+// with a .js extension refer to the .ts source.
+func resolveFile(path string) string {
+	switch filepath.Ext(path) {
+	case ".ts", ".tsx":
+		if isFile(path) {
+			return path
+		}
+	case ".js", ".jsx", ".mjs":
+		stem := strings.TrimSuffix(path, filepath.Ext(path))
+		for _, ext := range []string{".ts", ".tsx", ".d.ts"} {
+			if isFile(stem + ext) {
+				return stem + ext
+			}
+		}
+	}
+
+	for _, candidate := range []string{path + ".ts", path + ".tsx", path + ".d.ts", filepath.Join(path, "index.ts"), filepath.Join(path, "index.tsx")} {
+		if isFile(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
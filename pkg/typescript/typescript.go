@@ -1,20 +1,37 @@
 package typescript
 
 import (
-	"os/exec"
-	"path/filepath"
+	"sync"
 
+	"github.com/gwkline/artestian/pkg/sandbox"
 	"github.com/gwkline/artestian/types"
 )
 
-type TypeScriptSupport struct{}
+// Options configures how tsc and jest are invoked
+type Options struct {
+	Sandbox *sandbox.Sandbox // Isolates jest and tsc, nil runs them directly
+}
+
+type TypeScriptSupport struct {
+	options Options
+
+	mu        sync.Mutex
+	baselines map[string]map[string]bool // pre-existing diagnostics by source directory
+}
 
 func NewTypeScriptSupport() *TypeScriptSupport {
-	return &TypeScriptSupport{}
+	return NewTypeScriptSupportWithOptions(Options{})
+}
+
+func NewTypeScriptSupportWithOptions(options Options) *TypeScriptSupport {
+	return &TypeScriptSupport{
+		options:   options,
+		baselines: make(map[string]map[string]bool),
+	}
 }
 
 func (ts *TypeScriptSupport) GetTestRunner() types.ITestRunner {
-	return &JestRunner{}
+	return &JestRunner{sandbox: ts.options.Sandbox}
 }
 
 func (ts *TypeScriptSupport) GetFileExtension() string {
@@ -25,20 +42,6 @@ func (ts *TypeScriptSupport) GetTestFilePattern() string {
 	return ".test.ts"
 }
 
-func (r *TypeScriptSupport) CheckTypes(testFilePath string) (bool, string, error) {
-	// Get the directory of the test file
-	dir := filepath.Dir(testFilePath)
-
-	cmd := exec.Command("npx", "tsc", "--noEmit")
-	cmd.Dir = dir
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false, string(output), nil
-	}
-	return true, string(output), nil
-}
-
 func (ts *TypeScriptSupport) GetName() string {
 	return "typescript"
 }
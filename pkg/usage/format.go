@@ -0,0 +1,60 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// FormatSummary renders the usage summary as aligned tables, by operation and by file
+func FormatSummary(summary types.UsageSummary) string {
+	var b strings.Builder
+
+	b.WriteString("Usage by operation\n")
+	writeTable(&b, "OPERATION", summary.ByOperation, summary.Total)
+
+	if len(summary.ByFile) > 0 {
+		b.WriteString("\nUsage by file\n")
+		writeTable(&b, "FILE", summary.ByFile, summary.Total)
+	}
+
+	fmt.Fprintf(&b, "\nTests: %d passing, %d failing", summary.PassingTests, summary.FailingTests)
+	if summary.PassingTests > 0 {
+		fmt.Fprintf(&b, ", $%.4f per passing test", summary.CostPerPassingTest)
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+func writeTable(b *strings.Builder, label string, rows map[string]types.Usage, total types.Usage) {
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%s\tCALLS\tINPUT\tOUTPUT\tCACHE WRITE\tCACHE READ\tTIME\tCOST\t\n", label)
+	for _, name := range names {
+		writeRow(w, name, rows[name])
+	}
+	writeRow(w, "total", total)
+	w.Flush()
+}
+
+func writeRow(w *tabwriter.Writer, name string, usage types.Usage) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t$%.4f\t\n",
+		name,
+		usage.Calls,
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.CacheCreationTokens,
+		usage.CacheReadTokens,
+		usage.Duration.Round(time.Millisecond),
+		usage.Cost)
+}
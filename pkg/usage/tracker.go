@@ -0,0 +1,189 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gwkline/artestian/types"
+)
+
+// DefaultPricing holds the list prices of the models artestian uses, in USD per million tokens
+var DefaultPricing = map[string]types.Price{
+	"claude-3-5-sonnet-latest":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-5-sonnet-20241022": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-5-haiku-latest":    {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"claude-3-5-haiku-20241022":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+}
+
+// ErrBudgetExceeded is returned once a run or function has used up its budget
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Limits caps the usage of a run. Zero values are unlimited.
+type Limits struct {
+	MaxTokens           int64         // Input, output and cache tokens across the run
+	MaxCost             float64       // Estimated cost in USD across the run
+	MaxDuration         time.Duration // Wall-clock time since the tracker was created
+	MaxCallsPerFunction int           // Agent calls attributed to a single function
+}
+
+// Tracker aggregates usage for a run and enforces its limits. It is safe for concurrent use.
+type Tracker struct {
+	mu       sync.Mutex
+	pricing  map[string]types.Price
+	limits   Limits
+	start    time.Time
+	file     string
+	function string
+	summary  types.UsageSummary
+}
+
+// NewTracker creates a tracker that prices calls with the default prices, overridden by pricing
+func NewTracker(pricing map[string]types.Price, limits Limits) *Tracker {
+	prices := make(map[string]types.Price, len(DefaultPricing)+len(pricing))
+	for model, price := range DefaultPricing {
+		prices[model] = price
+	}
+	for model, price := range pricing {
+		prices[model] = price
+	}
+
+	return &Tracker{
+		pricing: prices,
+		limits:  limits,
+		start:   time.Now(),
+		summary: types.UsageSummary{
+			ByOperation: make(map[string]types.Usage),
+			ByFile:      make(map[string]types.Usage),
+			ByFunction:  make(map[string]types.Usage),
+		},
+	}
+}
+
+// SetTarget attributes following calls to a file and function. The function is empty
+// for per-file operations like picking an example.
+func (t *Tracker) SetTarget(file, function string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.file = file
+	t.function = function
+}
+
+// Record adds the usage of a call to every aggregate and returns it with its estimated cost
+func (t *Tracker) Record(operation string, model string, usage types.Usage) types.Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage.Cost = cost(t.pricing[model], usage)
+
+	t.summary.Total = add(t.summary.Total, usage)
+	t.summary.ByOperation[operation] = add(t.summary.ByOperation[operation], usage)
+	if t.file != "" {
+		t.summary.ByFile[t.file] = add(t.summary.ByFile[t.file], usage)
+	}
+	if t.function != "" {
+		key := functionKey(t.file, t.function)
+		t.summary.ByFunction[key] = add(t.summary.ByFunction[key], usage)
+	}
+
+	return usage
+}
+
+// RecordTest counts a generated test, so cost can be reported per passing test
+func (t *Tracker) RecordTest(passed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if passed {
+		t.summary.PassingTests++
+	} else {
+		t.summary.FailingTests++
+	}
+}
+
+// CheckBudget returns ErrBudgetExceeded once the run has used up its token, cost or time budget
+func (t *Tracker) CheckBudget() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := t.summary.Total
+	tokens := total.InputTokens + total.OutputTokens + total.CacheCreationTokens + total.CacheReadTokens
+	if t.limits.MaxTokens > 0 && tokens >= t.limits.MaxTokens {
+		return fmt.Errorf("%w: used %d of %d tokens", ErrBudgetExceeded, tokens, t.limits.MaxTokens)
+	}
+	if t.limits.MaxCost > 0 && total.Cost >= t.limits.MaxCost {
+		return fmt.Errorf("%w: spent $%.4f of $%.2f", ErrBudgetExceeded, total.Cost, t.limits.MaxCost)
+	}
+	if elapsed := time.Since(t.start); t.limits.MaxDuration > 0 && elapsed >= t.limits.MaxDuration {
+		return fmt.Errorf("%w: ran for %s of %s", ErrBudgetExceeded, elapsed.Round(time.Second), t.limits.MaxDuration)
+	}
+	return nil
+}
+
+// CheckFunctionBudget returns ErrBudgetExceeded once the current function has used up its agent calls
+func (t *Tracker) CheckFunctionBudget() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.limits.MaxCallsPerFunction <= 0 || t.function == "" {
+		return nil
+	}
+	if calls := t.summary.ByFunction[functionKey(t.file, t.function)].Calls; calls >= t.limits.MaxCallsPerFunction {
+		return fmt.Errorf("%w: %s made %d of %d agent calls", ErrBudgetExceeded, t.function, calls, t.limits.MaxCallsPerFunction)
+	}
+	return nil
+}
+
+// FunctionCalls returns the number of agent calls made so far for a function of a file
+func (t *Tracker) FunctionCalls(file, function string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.summary.ByFunction[functionKey(file, function)].Calls
+}
+
+// functionKey is the key of a function in UsageSummary.ByFunction
+func functionKey(file, function string) string {
+	return file + ":" + function
+}
+
+// Summary returns a copy of the aggregated usage
+func (t *Tracker) Summary() types.UsageSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summary := t.summary
+	summary.ByOperation = copyUsage(t.summary.ByOperation)
+	summary.ByFile = copyUsage(t.summary.ByFile)
+	summary.ByFunction = copyUsage(t.summary.ByFunction)
+	if summary.PassingTests > 0 {
+		summary.CostPerPassingTest = summary.Total.Cost / float64(summary.PassingTests)
+	}
+	return summary
+}
+
+func cost(price types.Price, usage types.Usage) float64 {
+	return (float64(usage.InputTokens)*price.Input +
+		float64(usage.OutputTokens)*price.Output +
+		float64(usage.CacheCreationTokens)*price.CacheWrite +
+		float64(usage.CacheReadTokens)*price.CacheRead) / 1_000_000
+}
+
+func add(a, b types.Usage) types.Usage {
+	return types.Usage{
+		Calls:               a.Calls + b.Calls,
+		InputTokens:         a.InputTokens + b.InputTokens,
+		OutputTokens:        a.OutputTokens + b.OutputTokens,
+		CacheCreationTokens: a.CacheCreationTokens + b.CacheCreationTokens,
+		CacheReadTokens:     a.CacheReadTokens + b.CacheReadTokens,
+		Duration:            a.Duration + b.Duration,
+		Cost:                a.Cost + b.Cost,
+	}
+}
+
+func copyUsage(m map[string]types.Usage) map[string]types.Usage {
+	c := make(map[string]types.Usage, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
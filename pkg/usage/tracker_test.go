@@ -0,0 +1,129 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gwkline/artestian/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(map[string]types.Price{
+		"custom-model": {Input: 1, Output: 2, CacheWrite: 4, CacheRead: 0.5},
+	}, Limits{})
+
+	tracker.SetTarget("pkg/math.go", "")
+	pick := tracker.Record("pick_example", "custom-model", types.Usage{Calls: 1, InputTokens: 1_000_000, Duration: time.Second})
+	assert.InDelta(t, 1.0, pick.Cost, 1e-9)
+
+	tracker.SetTarget("pkg/math.go", "Calculator.Add")
+	tracker.Record("generate_test", "custom-model", types.Usage{Calls: 1, InputTokens: 500_000, OutputTokens: 500_000, CacheCreationTokens: 250_000, CacheReadTokens: 1_000_000})
+	tracker.Record("fix_type_errors", "unknown-model", types.Usage{Calls: 1, InputTokens: 100})
+	tracker.RecordTest(true)
+
+	tracker.SetTarget("pkg/strings.go", "Reverse")
+	tracker.Record("generate_test", "claude-3-5-sonnet-latest", types.Usage{Calls: 1, OutputTokens: 1_000_000})
+	tracker.RecordTest(false)
+
+	summary := tracker.Summary()
+
+	assert.Equal(t, 4, summary.Total.Calls)
+	assert.Equal(t, int64(1_500_100), summary.Total.InputTokens)
+	assert.InDelta(t, 1.0+3.0+15.0, summary.Total.Cost, 1e-9)
+	assert.Equal(t, time.Second, summary.Total.Duration)
+
+	assert.Equal(t, 2, summary.ByOperation["generate_test"].Calls)
+	assert.Equal(t, 1, summary.ByOperation["fix_type_errors"].Calls)
+	assert.Zero(t, summary.ByOperation["fix_type_errors"].Cost)
+
+	assert.Equal(t, 3, summary.ByFile["pkg/math.go"].Calls)
+	assert.Equal(t, 1, summary.ByFile["pkg/strings.go"].Calls)
+	assert.Equal(t, 2, summary.ByFunction["pkg/math.go:Calculator.Add"].Calls)
+	assert.NotContains(t, summary.ByFunction, "pkg/math.go:")
+	assert.Equal(t, 2, tracker.FunctionCalls("pkg/math.go", "Calculator.Add"))
+	assert.Equal(t, 1, tracker.FunctionCalls("pkg/strings.go", "Reverse"))
+	assert.Zero(t, tracker.FunctionCalls("pkg/strings.go", "Calculator.Add"))
+
+	assert.Equal(t, 1, summary.PassingTests)
+	assert.Equal(t, 1, summary.FailingTests)
+	assert.InDelta(t, 19.0, summary.CostPerPassingTest, 1e-9)
+
+	output := FormatSummary(summary)
+	assert.Contains(t, output, "generate_test")
+	assert.Contains(t, output, "pkg/strings.go")
+	assert.Contains(t, output, "$19.0000 per passing test")
+}
+
+func TestTracker_CheckBudget(t *testing.T) {
+	tests := []struct {
+		name          string
+		limits        Limits
+		usage         types.Usage
+		expectedError string
+	}{
+		{
+			name:   "no limits",
+			usage:  types.Usage{Calls: 10, InputTokens: 1_000_000},
+			limits: Limits{},
+		},
+		{
+			name:   "under every limit",
+			limits: Limits{MaxTokens: 1000, MaxCost: 1, MaxDuration: time.Hour},
+			usage:  types.Usage{Calls: 1, InputTokens: 100, OutputTokens: 100},
+		},
+		{
+			name:          "token limit counts cache tokens",
+			limits:        Limits{MaxTokens: 1000},
+			usage:         types.Usage{Calls: 1, InputTokens: 100, CacheReadTokens: 900},
+			expectedError: "used 1000 of 1000 tokens",
+		},
+		{
+			name:          "cost limit",
+			limits:        Limits{MaxCost: 0.01},
+			usage:         types.Usage{Calls: 1, OutputTokens: 1000},
+			expectedError: "spent $0.0150 of $0.01",
+		},
+		{
+			name:          "time limit",
+			limits:        Limits{MaxDuration: time.Nanosecond},
+			usage:         types.Usage{Calls: 1},
+			expectedError: "ran for",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(nil, tt.limits)
+			tracker.Record("generate_test", "claude-3-5-sonnet-latest", tt.usage)
+			time.Sleep(time.Millisecond)
+
+			err := tracker.CheckBudget()
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrBudgetExceeded)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestTracker_CheckFunctionBudget(t *testing.T) {
+	tracker := NewTracker(nil, Limits{MaxCallsPerFunction: 2})
+
+	tracker.SetTarget("math.go", "")
+	tracker.Record("pick_example", "claude-3-5-sonnet-latest", types.Usage{Calls: 1})
+	tracker.Record("pick_example", "claude-3-5-sonnet-latest", types.Usage{Calls: 1})
+	assert.NoError(t, tracker.CheckFunctionBudget(), "per-file calls don't count towards a function")
+
+	tracker.SetTarget("math.go", "Add")
+	tracker.Record("generate_test", "claude-3-5-sonnet-latest", types.Usage{Calls: 1})
+	assert.NoError(t, tracker.CheckFunctionBudget())
+
+	tracker.Record("fix_type_errors", "claude-3-5-sonnet-latest", types.Usage{Calls: 1})
+	assert.ErrorIs(t, tracker.CheckFunctionBudget(), ErrBudgetExceeded)
+
+	tracker.SetTarget("math.go", "Subtract")
+	assert.NoError(t, tracker.CheckFunctionBudget())
+}
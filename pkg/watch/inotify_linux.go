@@ -0,0 +1,153 @@
+package watch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// watchMask is the directory events that mean a file was saved, created or removed.
+// Created files are reported once they're closed after writing.
+const watchMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotify watches every directory under the root, adding directories as they're created
+type inotify struct {
+	fd      int
+	file    *os.File
+	skipDir func(path string) bool
+	watches map[int32]string // Watch descriptor to directory, only used by read after New
+
+	events chan string
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newNotifier(root string, skipDir func(path string) bool) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify is not available: %w", err)
+	}
+	n := &inotify{
+		fd: fd,
+		// A non-blocking descriptor uses the runtime poller, so Close interrupts Read
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		skipDir: skipDir,
+		watches: make(map[int32]string),
+		events:  make(chan string),
+		done:    make(chan struct{}),
+	}
+	if err := n.addTree(root, false); err != nil {
+		n.file.Close()
+		return nil, err
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) Events() <-chan string {
+	return n.events
+}
+
+func (n *inotify) Close() error {
+	var err error
+	n.once.Do(func() {
+		close(n.done)
+		err = n.file.Close()
+	})
+	return err
+}
+
+// addTree watches dir and the directories under it. When report is set the files
+// already in them are reported, for a directory moved or copied into the tree.
+func (n *inotify) addTree(dir string, report bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if report {
+				n.send(path)
+			}
+			return nil
+		}
+		if path != dir && n.skipDir(path) {
+			return filepath.SkipDir
+		}
+		wd, err := syscall.InotifyAddWatch(n.fd, path, watchMask)
+		if err != nil {
+			// ENOSPC means fs.inotify.max_user_watches is too low for the tree
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		n.watches[int32(wd)] = path
+		return nil
+	})
+}
+
+func (n *inotify) read() {
+	defer close(n.events)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= size; {
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			length := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			name := strings.TrimRight(string(buf[offset+syscall.SizeofInotifyEvent:offset+syscall.SizeofInotifyEvent+length]), "\x00")
+			offset += syscall.SizeofInotifyEvent + length
+
+			if mask&syscall.IN_Q_OVERFLOW != 0 {
+				slog.Warn("too many file changes at once, some were missed")
+				continue
+			}
+			dir, ok := n.watches[wd]
+			if !ok {
+				continue
+			}
+			if mask&syscall.IN_IGNORED != 0 {
+				delete(n.watches, wd)
+				continue
+			}
+			path := filepath.Join(dir, name)
+
+			if mask&syscall.IN_ISDIR != 0 {
+				if mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && !n.skipDir(path) {
+					if err := n.addTree(path, true); err != nil {
+						slog.Warn("failed to watch new directory", "path", path, "error", err)
+					}
+				}
+				continue
+			}
+			// Wait for a new file to be written and closed
+			if mask&syscall.IN_CREATE != 0 {
+				continue
+			}
+			if !n.send(path) {
+				return
+			}
+		}
+	}
+}
+
+// send reports path, and returns false once the notifier is closed
+func (n *inotify) send(path string) bool {
+	select {
+	case n.events <- path:
+		return true
+	case <-n.done:
+		return false
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watch
+
+import "errors"
+
+func newNotifier(root string, skipDir func(path string) bool) (notifier, error) {
+	return nil, errors.New("inotify is only available on Linux")
+}
@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// notifier reports the paths of files that were saved, created or removed
+type notifier interface {
+	Events() <-chan string
+	Close() error
+}
+
+// poller finds changed files by walking the tree and comparing modification times and
+// sizes, where inotify isn't available
+type poller struct {
+	root     string
+	interval time.Duration
+	skipDir  func(path string) bool
+	include  func(path string) bool
+
+	events chan string
+	done   chan struct{}
+	once   sync.Once
+	files  map[string]fileState
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func newPoller(root string, interval time.Duration, skipDir, include func(path string) bool) *poller {
+	p := &poller{
+		root:     root,
+		interval: interval,
+		skipDir:  skipDir,
+		include:  include,
+		events:   make(chan string),
+		done:     make(chan struct{}),
+	}
+	p.files = p.scan()
+	go p.run()
+	return p
+}
+
+func (p *poller) Events() <-chan string {
+	return p.events
+}
+
+func (p *poller) Close() error {
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
+
+func (p *poller) run() {
+	defer close(p.events)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		files := p.scan()
+		var changed []string
+		for path, state := range files {
+			if previous, ok := p.files[path]; !ok || previous != state {
+				changed = append(changed, path)
+			}
+		}
+		for path := range p.files {
+			if _, ok := files[path]; !ok {
+				changed = append(changed, path)
+			}
+		}
+		p.files = files
+
+		for _, path := range changed {
+			select {
+			case p.events <- path:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// scan records the state of every included file under the root
+func (p *poller) scan() map[string]fileState {
+	files := make(map[string]fileState)
+	filepath.Walk(p.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != p.root && p.skipDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if p.include(path) {
+			files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return files
+}
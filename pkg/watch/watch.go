@@ -0,0 +1,379 @@
+package watch
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gwkline/artestian/pkg/finder"
+	"github.com/gwkline/artestian/types"
+)
+
+// Options configures how changes are detected
+type Options struct {
+	Debounce     time.Duration // Quiet time after the last change before changed files are looked at
+	Poll         bool          // Poll for changes even where inotify is available
+	PollInterval time.Duration
+}
+
+// Handler generates tests for the named functions of a source file, given as Name or
+// Receiver.Name
+type Handler func(ctx context.Context, sourcePath string, functions []string) error
+
+// Watcher queues functions for generation as source files under the root are saved
+type Watcher struct {
+	finder   *finder.FileFinder
+	language types.ILanguage
+	cfg      types.IConfig
+	options  Options
+
+	snapshots map[string]map[string]string // Source file to each function's source lines
+}
+
+// job is a source file and the functions in it waiting for tests
+type job struct {
+	path      string
+	functions []string
+}
+
+func New(f *finder.FileFinder, language types.ILanguage, cfg types.IConfig, options Options) *Watcher {
+	if options.Debounce <= 0 {
+		options.Debounce = 500 * time.Millisecond
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Second
+	}
+	return &Watcher{
+		finder:    f,
+		language:  language,
+		cfg:       cfg,
+		options:   options,
+		snapshots: make(map[string]map[string]string),
+	}
+}
+
+// Run records the functions in every source file under the root, then watches for saved
+// files. Functions that changed or are new and lack tests are queued, and handle is
+// called for one file at a time. Run returns once ctx is done and the current file is
+// finished.
+func (w *Watcher) Run(ctx context.Context, handle Handler) error {
+	root := w.cfg.GetRootDir()
+	if err := w.snapshotAll(root); err != nil {
+		return err
+	}
+
+	var n notifier
+	var err error
+	if !w.options.Poll {
+		if n, err = newNotifier(root, w.skipDir); err != nil {
+			slog.Warn("falling back to polling for changes", "reason", err)
+		}
+	}
+	if n == nil {
+		n = newPoller(root, w.options.PollInterval, w.skipDir, w.isSourceFile)
+	}
+	defer n.Close()
+	slog.Info("watching for changes", "root", root, "files", len(w.snapshots))
+
+	debounce := time.NewTimer(w.options.Debounce)
+	debounce.Stop()
+	pending := make(map[string]bool)
+	var queue []job
+	done := make(chan error, 1)
+	busy := false
+
+	start := func() {
+		for !busy && len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			// An earlier job may have covered them in the meantime
+			if next.functions = w.untested(next.path, next.functions); len(next.functions) == 0 {
+				continue
+			}
+			busy = true
+			slog.Info("generating tests for changed functions", "path", next.path, "functions", next.functions, "queued", len(queue))
+			go func() {
+				done <- handle(ctx, next.path, next.functions)
+			}()
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if busy {
+				<-done
+			}
+			return nil
+		case path, ok := <-n.Events():
+			if !ok {
+				return nil
+			}
+			if w.isSourceFile(path) {
+				pending[path] = true
+				debounce.Reset(w.options.Debounce)
+			}
+		case <-debounce.C:
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			pending = make(map[string]bool)
+
+			for _, path := range paths {
+				if functions := w.changes(path); len(functions) > 0 {
+					queue = enqueue(queue, job{path, functions})
+				}
+			}
+			start()
+		case err := <-done:
+			busy = false
+			if err != nil {
+				slog.Error("failed to generate tests", "error", err)
+			}
+			start()
+		}
+	}
+}
+
+// enqueue adds j to the queue, merging it with a job for the same file
+func enqueue(queue []job, j job) []job {
+	for i := range queue {
+		if queue[i].path != j.path {
+			continue
+		}
+		for _, function := range j.functions {
+			if !contains(queue[i].functions, function) {
+				queue[i].functions = append(queue[i].functions, function)
+			}
+		}
+		return queue
+	}
+	return append(queue, j)
+}
+
+// snapshotAll records the functions in every source file under root, so only later
+// changes are queued
+func (w *Watcher) snapshotAll(root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && w.skipDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if w.isSourceFile(path) {
+			if functions, ok := w.functions(path); ok {
+				w.snapshots[path] = functions
+			}
+		}
+		return nil
+	})
+}
+
+// changes updates the snapshot of a source file, and returns the functions that changed
+// or are new and have no tests. Deleted files and files that don't parse, e.g. halfway
+// through an edit, return nothing.
+func (w *Watcher) changes(path string) []string {
+	previous := w.snapshots[path]
+	current, ok := w.functions(path)
+	if !ok {
+		return nil
+	}
+	w.snapshots[path] = current
+
+	var changed []string
+	for name, lines := range current {
+		if old, ok := previous[name]; !ok || old != lines {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+	return w.untested(path, changed)
+}
+
+// functions returns the source lines of each function in a source file, using the spans
+// the language reports
+func (w *Watcher) functions(path string) (map[string]string, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		delete(w.snapshots, path)
+		return nil, false
+	}
+	functions, err := w.language.GetFunctions(string(content))
+	if err != nil {
+		slog.Debug("skipping file that doesn't parse", "path", path, "error", err)
+		return nil, false
+	}
+
+	lines := strings.Split(string(content), "\n")
+	result := make(map[string]string, len(functions))
+	for _, function := range functions {
+		result[qualifiedName(function)] = span(lines, function)
+	}
+	return result, true
+}
+
+// untested returns the functions none of the source file's test files cover: the one the
+// finder would generate, and any named <file>_<suffix>. A function is covered when a
+// test function is named after it, like TestAdd or TestCalculator_Add, or when the test
+// file calls it. Mentions in comments and strings don't count.
+func (w *Watcher) untested(path string, functions []string) []string {
+	pattern := w.language.GetTestFilePattern()
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return functions
+	}
+	var testNames []string
+	var testCode []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name != base+pattern && !(strings.HasPrefix(name, base+"_") && strings.HasSuffix(name, pattern)) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			continue
+		}
+		tests, err := w.language.GetFunctions(string(content))
+		if err != nil {
+			slog.Debug("skipping test file that doesn't parse", "path", name, "error", err)
+			continue
+		}
+		for _, test := range tests {
+			testNames = append(testNames, test.Name)
+		}
+		// TypeScript tests are calls to describe and it rather than declarations, so
+		// calls are looked for in the whole file
+		testCode = append(testCode, stripLiterals(string(content)))
+	}
+
+	var result []string
+	for _, function := range functions {
+		name := function[strings.LastIndex(function, ".")+1:]
+		if !namesTest(testNames, name) && !calls(testCode, name) {
+			result = append(result, function)
+		}
+	}
+	return result
+}
+
+// namesTest reports whether a test function is named after the function, e.g. TestAdd,
+// TestAdd_Negative or TestCalculator_Add for Add
+func namesTest(tests []string, name string) bool {
+	for _, test := range tests {
+		for _, prefix := range []string{"Test", "Benchmark", "Fuzz", "Example"} {
+			if !strings.HasPrefix(test, prefix) {
+				continue
+			}
+			for _, part := range strings.Split(strings.TrimPrefix(test, prefix), "_") {
+				if strings.EqualFold(part, name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// calls reports whether any of the code calls the function, as name(...) or
+// value.name(...), with type arguments in between allowed
+func calls(code []string, name string) bool {
+	call := regexp.MustCompile(`(^|[^\w$])` + regexp.QuoteMeta(name) + `\s*(<[^()]*>|\[[^()]*\])?\(`)
+	for _, c := range code {
+		if call.MatchString(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripLiterals blanks out comments and string literals, so names mentioned in them
+// aren't mistaken for code. Line breaks are kept.
+func stripLiterals(source string) string {
+	var b strings.Builder
+	for i := 0; i < len(source); i++ {
+		c := source[i]
+		switch {
+		case strings.HasPrefix(source[i:], "//"):
+			end := strings.IndexByte(source[i:], '\n')
+			if end < 0 {
+				return b.String()
+			}
+			i += end - 1
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			b.WriteString(strings.Repeat("\n", strings.Count(source[i:i+2+end], "\n")))
+			i += end + 3
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for ; j < len(source) && source[j] != c; j++ {
+				if source[j] == '\\' && c != '`' {
+					j++
+				} else if source[j] == '\n' {
+					b.WriteByte('\n')
+				}
+			}
+			b.WriteString(`""`)
+			i = j
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (w *Watcher) isSourceFile(path string) bool {
+	return w.finder.IsSourceFile(w.cfg, path)
+}
+
+// skipDir reports whether a directory isn't watched: hidden directories like .git, and
+// the configured excluded directories
+func (w *Watcher) skipDir(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".") || w.finder.IsExcludedDir(w.cfg, path)
+}
+
+// span returns the lines of the function
+func span(lines []string, function types.Function) string {
+	start, end := max(function.StartLine-1, 0), min(function.EndLine, len(lines))
+	if start >= end {
+		return function.SourceCode
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+func qualifiedName(function types.Function) string {
+	if function.Receiver == "" {
+		return function.Name
+	}
+	return function.Receiver + "." + function.Name
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
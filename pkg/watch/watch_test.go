@@ -0,0 +1,291 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gwkline/artestian/pkg/finder"
+	"github.com/gwkline/artestian/pkg/golang"
+	"github.com/gwkline/artestian/types"
+)
+
+type watchConfig struct {
+	types.IConfig
+	root     string
+	excluded []string
+}
+
+func (c *watchConfig) GetRootDir() string {
+	return c.root
+}
+
+func (c *watchConfig) GetExcludedDirs() []string {
+	return c.excluded
+}
+
+func (c *watchConfig) GetExcludedFiles() []string {
+	return []string{"_gen.go"}
+}
+
+func newWatcher(t *testing.T, files map[string]string, options Options) (*Watcher, string) {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, files)
+
+	language := golang.NewGoSupport()
+	cfg := &watchConfig{root: root, excluded: []string{filepath.Join(root, "vendor")}}
+	return New(finder.NewFileFinder(language), language, cfg, options), root
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+const mathSource = `package pkg
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Sub(a, b int) int {
+	return a - b
+}
+`
+
+const mathTest = `package pkg
+
+import "testing"
+
+// Add is covered by the README example
+func TestSub(t *testing.T) {
+	t.Log("Mul(2, 3)")
+}
+
+func TestHelpers(t *testing.T) {
+	c := &Calculator{}
+	c.Div(4, 2)
+}
+
+func TestCalculator_Pow(t *testing.T) {}
+`
+
+func TestWatcher_Changes(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "changed and new functions without tests",
+			file: "pkg/math.go",
+			content: `package pkg
+
+func Add(a, b int) int {
+	return b + a
+}
+
+func Sub(a, b int) int {
+	return b - a
+}
+
+func (c *Calculator) Mul(a, b int) int {
+	return a * b
+}
+`,
+			// Sub has a test in math_test.go, which only mentions Add and Mul in a comment
+			// and a string
+			expected: []string{"Add", "Calculator.Mul"},
+		},
+		{
+			name: "called from a test",
+			file: "pkg/math.go",
+			content: mathSource + `
+func (c *Calculator) Div(a, b int) int {
+	return a / b
+}
+`,
+			expected: nil,
+		},
+		{
+			name: "named by a test",
+			file: "pkg/math.go",
+			content: mathSource + `
+func (c *Calculator) Pow(a, b int) int {
+	return a
+}
+`,
+			expected: nil,
+		},
+		{
+			name:     "moved but unchanged",
+			file:     "pkg/math.go",
+			content:  "package pkg\n\n// Math helpers\n" + mathSource[len("package pkg\n"):],
+			expected: nil,
+		},
+		{
+			name:     "new file",
+			file:     "pkg/strings.go",
+			content:  "package pkg\n\nfunc Reverse(s string) string {\n\treturn s\n}\n",
+			expected: []string{"Reverse"},
+		},
+		{
+			name:     "tested in an additional test file",
+			file:     "pkg/strings.go",
+			content:  "package pkg\n\nfunc Upper(s string) string {\n\treturn s\n}\n",
+			expected: nil,
+		},
+		{
+			name:     "doesn't parse",
+			file:     "pkg/math.go",
+			content:  "package pkg\n\nfunc Add(a, b int) int {\n",
+			expected: nil,
+		},
+		{
+			name:     "deleted",
+			file:     "pkg/math.go",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, root := newWatcher(t, map[string]string{
+				"pkg/math.go":                 "package pkg\n" + mathSource[len("package pkg\n"):],
+				"pkg/math_test.go":            mathTest,
+				"pkg/strings_Upper_test.go":   "package pkg\n\nfunc TestUpper(t *testing.T) {}\n",
+				"pkg/stringsutil_test.go":     "package pkg\n\nfunc TestReverse(t *testing.T) {}\n",
+				"vendor/lib/lib.go":           "package lib\n",
+				".git/hooks/pre-commit.go":    "package hooks\n",
+				"pkg/generated_gen.go":        "package pkg\n",
+				"pkg/testdata/fixture_gen.go": "package testdata\n",
+			}, Options{})
+			require.NoError(t, w.snapshotAll(root))
+			assert.Len(t, w.snapshots, 1)
+
+			path := filepath.Join(root, tt.file)
+			if tt.content == "" {
+				require.NoError(t, os.Remove(path))
+			} else {
+				writeFiles(t, root, map[string]string{tt.file: tt.content})
+			}
+
+			assert.Equal(t, tt.expected, w.changes(path))
+		})
+	}
+}
+
+func TestCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		function string
+		expected bool
+	}{
+		{name: "go call", code: "func TestX(t *testing.T) {\n\tAdd(1, 2)\n}", function: "Add", expected: true},
+		{name: "method call", code: "c := &Calculator{}\nc.Add(1, 2)", function: "Add", expected: true},
+		{name: "generic call", code: "Map[int](values, f)", function: "Map", expected: true},
+		{name: "jest", code: "describe('add', () => {\n  it('adds', () => {\n    expect(add(1, 2)).toBe(3);\n  });\n});", function: "add", expected: true},
+		{name: "type arguments", code: "expect(parse<number>('1')).toBe(1);", function: "parse", expected: true},
+		{name: "longer name", code: "AddAll(1, 2)\nreadd(1)", function: "Add", expected: false},
+		{name: "not called", code: "var f = Add", function: "Add", expected: false},
+		{name: "comment", code: "// Add(1, 2) is tested elsewhere\n/* Add(3, 4) */", function: "Add", expected: false},
+		{name: "string", code: "t.Run(\"Add(1, 2)\", run)\nit('add(1, 2)', run)\nconst s = `add(${n})`", function: "add", expected: false},
+		{name: "escaped quote", code: "t.Log(\"\\\"Add(1)\")", function: "Add", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, calls([]string{stripLiterals(tt.code)}, tt.function))
+		})
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	queue := enqueue(nil, job{"a.go", []string{"Add"}})
+	queue = enqueue(queue, job{"b.go", []string{"Sub"}})
+	queue = enqueue(queue, job{"a.go", []string{"Add", "Mul"}})
+
+	assert.Equal(t, []job{{"a.go", []string{"Add", "Mul"}}, {"b.go", []string{"Sub"}}}, queue)
+}
+
+func TestWatcher_Run(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		name := "inotify"
+		if poll {
+			name = "poll"
+		}
+		t.Run(name, func(t *testing.T) {
+			w, root := newWatcher(t, map[string]string{"pkg/math.go": mathSource}, Options{
+				Debounce:     50 * time.Millisecond,
+				Poll:         poll,
+				PollInterval: 20 * time.Millisecond,
+			})
+			if !poll {
+				if _, err := newNotifier(root, w.skipDir); err != nil {
+					t.Skipf("inotify is not available: %v", err)
+				}
+			}
+
+			type call struct {
+				path      string
+				functions []string
+			}
+			calls := make(chan call, 10)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			errs := make(chan error, 1)
+			go func() {
+				errs <- w.Run(ctx, func(ctx context.Context, sourcePath string, functions []string) error {
+					calls <- call{sourcePath, functions}
+					return nil
+				})
+			}()
+
+			// Give the watcher time to take its snapshot and start watching
+			time.Sleep(200 * time.Millisecond)
+
+			// Saves in quick succession, and in a new directory, are handled once each
+			writeFiles(t, root, map[string]string{"pkg/math.go": mathSource + "\nfunc Mul(a, b int) int {\n\treturn a\n}\n"})
+			writeFiles(t, root, map[string]string{"pkg/math.go": mathSource + "\nfunc Mul(a, b int) int {\n\treturn a * b\n}\n"})
+			writeFiles(t, root, map[string]string{"pkg/strings/strings.go": "package strings\n\nfunc Reverse(s string) string {\n\treturn s\n}\n"})
+			writeFiles(t, root, map[string]string{"pkg/math_test.go": "package pkg\n"})
+
+			received := map[string][]string{}
+			for len(received) < 2 {
+				select {
+				case c := <-calls:
+					rel, err := filepath.Rel(root, c.path)
+					require.NoError(t, err)
+					assert.NotContains(t, received, rel)
+					received[rel] = c.functions
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out waiting for changes, received %v", received)
+				}
+			}
+			assert.Equal(t, map[string][]string{
+				"pkg/math.go":            {"Mul"},
+				"pkg/strings/strings.go": {"Reverse"},
+			}, received)
+
+			cancel()
+			select {
+			case err := <-errs:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("watcher did not stop")
+			}
+		})
+	}
+}
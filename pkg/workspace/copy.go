@@ -0,0 +1,173 @@
+package workspace
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// skippedDirs are never copied to a workspace
+var skippedDirs = map[string]bool{".git": true}
+
+// unsyncedDirs are copied once, and assumed not to change during a run
+var unsyncedDirs = map[string]bool{"node_modules": true}
+
+// copyTree copies src into the empty directory dst, cloning files where the filesystem
+// supports copy-on-write
+func copyTree(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	// GNU cp clones files on btrfs and xfs, and copies them elsewhere
+	args := []string{"-a", "--reflink=auto"}
+	for _, entry := range entries {
+		if !skippedDirs[entry.Name()] {
+			args = append(args, filepath.Join(src, entry.Name()))
+		}
+	}
+	if len(args) == 2 {
+		return nil
+	}
+	if _, err := exec.LookPath("cp"); err == nil {
+		if exec.Command("cp", append(args, dst)...).Run() == nil {
+			return nil
+		}
+	}
+	return syncTree(src, dst)
+}
+
+// syncTree makes dst match src, copying files whose size or modification time differ
+// and removing files that are only in dst
+func syncTree(src, dst string) error {
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if entry.IsDir() {
+			if path != src && skippedDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			if path != src && unsyncedDirs[entry.Name()] {
+				if _, err := os.Stat(target); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		return syncFile(path, target, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Remove what commands created in the copy and what was removed from the project
+	return filepath.WalkDir(dst, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dst {
+			return nil
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && unsyncedDirs[entry.Name()] {
+			return filepath.SkipDir
+		}
+		if _, err := os.Lstat(filepath.Join(src, rel)); os.IsNotExist(err) {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+}
+
+// syncPath brings the file at rel, a slash-separated path relative to src, up to date in
+// dst, removing it from dst when it's gone from src
+func syncPath(src, dst, rel string) error {
+	for _, name := range strings.Split(rel, "/") {
+		if skippedDirs[name] || unsyncedDirs[name] {
+			return nil
+		}
+	}
+	path := filepath.Join(src, filepath.FromSlash(rel))
+	target := filepath.Join(dst, filepath.FromSlash(rel))
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return os.RemoveAll(target)
+	}
+	if err != nil {
+		return err
+	}
+	// Submodules are reported as a whole, and left alone
+	if info.IsDir() {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return syncFile(path, target, fs.FileInfoToDirEntry(info))
+}
+
+// syncFile copies a file or symlink to target unless target already matches it
+func syncFile(path, target string, entry fs.DirEntry) error {
+	info, err := entry.Info()
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if current, err := os.Readlink(target); err == nil && current == link {
+			return nil
+		}
+		os.RemoveAll(target)
+		return os.Symlink(link, target)
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	if existing, err := os.Lstat(target); err == nil && existing.Mode() == info.Mode() && existing.Size() == info.Size() && existing.ModTime().Equal(info.ModTime()) {
+		return nil
+	}
+	os.RemoveAll(target)
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// Matching modification times let the next sync skip unchanged files
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
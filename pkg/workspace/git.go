@@ -0,0 +1,158 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gwkline/artestian/pkg/command"
+)
+
+// gitState remembers which files of a git repository differed from HEAD at the last
+// sync. Every other file matches its commit, so only these, the files that differ now
+// and the files a new HEAD changed have to be looked at again.
+type gitState struct {
+	head    string          // Commit checked out at the last sync, empty before the first commit
+	changed map[string]bool // Files that differed from HEAD at the last sync, relative to the root
+}
+
+// newGitState returns the state of the repository root is the top level of, or nil when
+// it isn't one
+func newGitState(root string) *gitState {
+	top, err := git(root, "rev-parse", "--show-toplevel")
+	if err != nil || !sameDir(strings.TrimSpace(top), root) {
+		return nil
+	}
+	head, changed, err := currentState(root)
+	if err != nil {
+		return nil
+	}
+	return &gitState{head: head, changed: changed}
+}
+
+// changes returns the files that may have changed since the last sync, relative to the
+// root, and remembers the current state. It reports false when every file has to be
+// looked at, e.g. after the first commit.
+func (s *gitState) changes(root string) ([]string, bool, error) {
+	head, changed, err := currentState(root)
+	if err != nil {
+		return nil, false, err
+	}
+
+	paths := make(map[string]bool, len(changed)+len(s.changed))
+	for path := range s.changed {
+		paths[path] = true
+	}
+	for path := range changed {
+		paths[path] = true
+	}
+	if head != s.head {
+		if head == "" || s.head == "" {
+			s.head, s.changed = head, changed
+			return nil, false, nil
+		}
+		diff, err := git(root, "diff", "--name-only", "-z", "--no-renames", s.head, head)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, path := range strings.Split(diff, "\x00") {
+			if path != "" {
+				paths[path] = true
+			}
+		}
+	}
+	s.head, s.changed = head, changed
+
+	result := make([]string, 0, len(paths))
+	for path := range paths {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result, true, nil
+}
+
+// currentState returns the commit checked out and the files that differ from it,
+// including untracked files
+func currentState(root string) (string, map[string]bool, error) {
+	// Fails before the first commit, when there's nothing to compare against
+	head, err := git(root, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		head = ""
+	}
+
+	status, err := git(root, "status", "--porcelain", "-z", "--untracked-files=all", "--no-renames")
+	if err != nil {
+		return "", nil, err
+	}
+	changed := make(map[string]bool)
+	for _, entry := range strings.Split(status, "\x00") {
+		// Entries are "XY path"
+		if len(entry) > 3 {
+			changed[entry[3:]] = true
+		}
+	}
+	return strings.TrimSpace(head), changed, nil
+}
+
+func git(root string, args ...string) (string, error) {
+	cmd := command.New(context.Background(), root, "git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(output), nil
+}
+
+// sameDir reports whether two paths are the same directory, following symlinks like
+// macOS's /tmp
+func sameDir(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// rootMarkers are files a project's tools may need from a directory above the one tests
+// are generated for: modules, workspaces, hoisted dependencies and extended configs
+var rootMarkers = []string{"go.mod", "go.work", "package.json", "tsconfig.json", "node_modules"}
+
+// FindRoot returns the directory to copy for a project in dir: the top level of the git
+// repository it's in, or else the outermost directory at or above dir holding one of the
+// rootMarkers. The home directory and the filesystem root are never used, and dir is
+// returned when nothing else fits.
+func FindRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve project root: %w", err)
+	}
+	home, _ := os.UserHomeDir()
+
+	root := dir
+	for current := dir; ; current = filepath.Dir(current) {
+		if current == filepath.Dir(current) || (home != "" && sameDir(current, home)) {
+			return root, nil
+		}
+		if exists(filepath.Join(current, ".git")) {
+			return current, nil
+		}
+		for _, marker := range rootMarkers {
+			if exists(filepath.Join(current, marker)) {
+				root = current
+				break
+			}
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,137 @@
+package workspace
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindRoot(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string // Created under a temp directory
+		dir      string   // Directory to find the root for
+		expected string
+	}{
+		{
+			name:     "git repository",
+			files:    []string{".git/HEAD", "services/api/go.mod", "services/api/pkg/add.go"},
+			dir:      "services/api",
+			expected: ".",
+		},
+		{
+			name:     "go.work above the module",
+			files:    []string{"go.work", "services/api/go.mod"},
+			dir:      "services/api",
+			expected: ".",
+		},
+		{
+			name:     "hoisted node_modules",
+			files:    []string{"node_modules/.bin/jest", "tsconfig.json", "packages/web/src/app.ts"},
+			dir:      "packages/web",
+			expected: ".",
+		},
+		{
+			name:     "nothing above the project",
+			files:    []string{"app/go.mod"},
+			dir:      "app",
+			expected: "app",
+		},
+		{
+			name:     "home directory is never copied",
+			files:    []string{"home/package.json", "home/src/app/go.mod"},
+			dir:      "home/src/app",
+			expected: "home/src/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			for _, file := range tt.files {
+				writeFiles(t, base, map[string]string{file: ""})
+			}
+			t.Setenv("HOME", filepath.Join(base, "home"))
+
+			root, err := FindRoot(filepath.Join(base, tt.dir))
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(base, tt.expected), root)
+		})
+	}
+}
+
+func TestWorkspace_SyncGit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping git test in short mode")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	git("init", "--quiet", "--initial-branch=main")
+	writeFiles(t, root, map[string]string{
+		".gitignore": "build/\n",
+		"pkg/add.go": "package pkg\n",
+		"pkg/sub.go": "package pkg\n",
+		"build/out":  "1\n",
+	})
+	git("add", ".")
+	git("commit", "--quiet", "--message", "Initial commit")
+
+	w, err := New(root)
+	require.NoError(t, err)
+	defer w.Close()
+	require.NotNil(t, w.git)
+	path := func(rel string) string {
+		return filepath.Join(w.Dir(), rel)
+	}
+
+	// Edited, new and deleted files are brought over
+	writeFiles(t, root, map[string]string{"pkg/add.go": "package pkg\n\nfunc Add() {}\n", "pkg/mul/mul.go": "package mul\n"})
+	require.NoError(t, os.Remove(filepath.Join(root, "pkg", "sub.go")))
+	require.NoError(t, w.Sync())
+	assert.Equal(t, "package pkg\n\nfunc Add() {}\n", readFile(t, path("pkg/add.go")))
+	assert.Equal(t, "package mul\n", readFile(t, path("pkg/mul/mul.go")))
+	assert.NoFileExists(t, path("pkg/sub.go"))
+
+	// A file reverted to HEAD is reverted in the workspace too
+	git("checkout", "--quiet", "--", "pkg/add.go", "pkg/sub.go")
+	require.NoError(t, w.Sync())
+	assert.Equal(t, "package pkg\n", readFile(t, path("pkg/add.go")))
+	assert.FileExists(t, path("pkg/sub.go"))
+
+	// Files a branch switch changes are brought over
+	git("checkout", "--quiet", "-b", "feature")
+	writeFiles(t, root, map[string]string{"pkg/sub.go": "package pkg\n\nfunc Sub() {}\n"})
+	git("commit", "--quiet", "--all", "--message", "Add Sub")
+	git("checkout", "--quiet", "main")
+	require.NoError(t, w.Sync())
+	git("checkout", "--quiet", "feature")
+	require.NoError(t, w.Sync())
+	assert.Equal(t, "package pkg\n\nfunc Sub() {}\n", readFile(t, path("pkg/sub.go")))
+
+	// Only those files are looked at: unchanged and ignored files are left alone
+	writeFiles(t, w.Dir(), map[string]string{"pkg/add.go": "package workspace\n"})
+	writeFiles(t, root, map[string]string{"build/out": "2\n"})
+	require.NoError(t, w.Sync())
+	assert.Equal(t, "package workspace\n", readFile(t, path("pkg/add.go")))
+	assert.Equal(t, "1\n", readFile(t, path("build/out")))
+}
@@ -0,0 +1,160 @@
+package workspace
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Workspace is a scratch copy of a project that tests are generated and repaired in, so
+// temporary files and failed attempts never reach the project
+type Workspace struct {
+	root string    // The project
+	base string    // Temp directory holding the copy and kept files
+	dir  string    // The copy of the project
+	git  *gitState // Set when root is a git repository, so Sync only copies changed files
+
+	mu   sync.Mutex
+	kept int // Files kept with Keep
+}
+
+// New copies root into a new temp directory, cloning files where the filesystem
+// supports copy-on-write
+func New(root string) (*Workspace, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project root: %w", err)
+	}
+
+	base, err := os.MkdirTemp("", "artestian-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	w := &Workspace{root: root, base: base, dir: filepath.Join(base, "project")}
+
+	if err := os.Mkdir(w.dir, 0755); err != nil {
+		os.RemoveAll(base)
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	if err := copyTree(root, w.dir); err != nil {
+		os.RemoveAll(base)
+		return nil, fmt.Errorf("failed to copy project to workspace: %w", err)
+	}
+	w.git = newGitState(root)
+	slog.Debug("created workspace", "root", root, "dir", w.dir, "git", w.git != nil)
+	return w, nil
+}
+
+// Root returns the project the workspace is a copy of
+func (w *Workspace) Root() string {
+	return w.root
+}
+
+// Dir returns the copy of the project
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// Sync brings the workspace up to date with the project. In a git repository only files
+// that differ from HEAD, or did at the last sync, are copied again or removed, along with
+// the files a new HEAD changed; ignored files are copied once. Elsewhere the whole tree is
+// compared, files that only exist in the workspace are removed, and changed files are
+// copied again.
+func (w *Workspace) Sync() error {
+	if w.git != nil {
+		paths, ok, err := w.git.changes(w.root)
+		if err != nil {
+			return fmt.Errorf("failed to update workspace: %w", err)
+		}
+		if ok {
+			for _, path := range paths {
+				if err := syncPath(w.root, w.dir, path); err != nil {
+					return fmt.Errorf("failed to update workspace: %w", err)
+				}
+			}
+			return nil
+		}
+	}
+
+	if err := syncTree(w.root, w.dir); err != nil {
+		return fmt.Errorf("failed to update workspace: %w", err)
+	}
+	return nil
+}
+
+// Path maps a path in the project to the same path in the workspace
+func (w *Workspace) Path(path string) (string, error) {
+	rel, err := w.rel(w.root, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(w.dir, rel), nil
+}
+
+// RealPath maps a path in the workspace back to the same path in the project
+func (w *Workspace) RealPath(path string) (string, error) {
+	rel, err := w.rel(w.dir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(w.root, rel), nil
+}
+
+// RealPaths rewrites every workspace path in text to the project path
+func (w *Workspace) RealPaths(text string) string {
+	return strings.ReplaceAll(text, w.dir, w.root)
+}
+
+func (w *Workspace) rel(dir, path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside %s", path, dir)
+	}
+	return rel, nil
+}
+
+// Keep copies a file from the workspace to a directory next to it that outlives Close,
+// and returns where it was kept
+func (w *Workspace) Keep(path string) (string, error) {
+	rel, err := w.rel(w.dir, path)
+	if err != nil {
+		return "", err
+	}
+	kept := filepath.Join(w.base, "failed", rel)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(kept), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(kept), err)
+	}
+	if err := os.WriteFile(kept, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to keep %s: %w", path, err)
+	}
+
+	w.mu.Lock()
+	w.kept++
+	w.mu.Unlock()
+	return kept, nil
+}
+
+// Close removes the workspace, apart from any kept files
+func (w *Workspace) Close() error {
+	w.mu.Lock()
+	kept := w.kept
+	w.mu.Unlock()
+
+	if kept == 0 {
+		return os.RemoveAll(w.base)
+	}
+	slog.Info("kept failed tests", "count", kept, "dir", filepath.Join(w.base, "failed"))
+	return os.RemoveAll(w.dir)
+}
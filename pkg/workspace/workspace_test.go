@@ -0,0 +1,96 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestWorkspace(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":                "module example.com/app\n",
+		"pkg/add.go":            "package pkg\n",
+		".git/HEAD":             "ref: refs/heads/main\n",
+		"node_modules/x/add.js": "module.exports = 1\n",
+	})
+	require.NoError(t, os.Symlink("add.go", filepath.Join(root, "pkg", "link.go")))
+
+	w, err := New(root)
+	require.NoError(t, err)
+	defer w.Close()
+
+	source, err := w.Path(filepath.Join(root, "pkg", "add.go"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(w.Dir(), "pkg", "add.go"), source)
+	assert.Equal(t, "package pkg\n", readFile(t, source))
+	assert.Equal(t, "package pkg\n", readFile(t, filepath.Join(w.Dir(), "pkg", "link.go")))
+	assert.FileExists(t, filepath.Join(w.Dir(), "node_modules", "x", "add.js"))
+	assert.NoDirExists(t, filepath.Join(w.Dir(), ".git"))
+
+	real, err := w.RealPath(source)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "pkg", "add.go"), real)
+	assert.Equal(t, "FAIL "+real+":3", w.RealPaths("FAIL "+source+":3"))
+
+	_, err = w.Path(t.TempDir())
+	assert.ErrorContains(t, err, "is outside")
+
+	// Changes in the workspace don't reach the project
+	writeFiles(t, w.Dir(), map[string]string{"pkg/add.go": "package changed\n", "pkg/Add123_test.go": "package pkg\n"})
+	assert.Equal(t, "package pkg\n", readFile(t, filepath.Join(root, "pkg", "add.go")))
+
+	// Sync resets the workspace to the project, including the project's own changes
+	writeFiles(t, root, map[string]string{"pkg/sub.go": "package pkg\n\nfunc Sub() {}\n"})
+	require.NoError(t, w.Sync())
+	assert.Equal(t, "package pkg\n", readFile(t, source))
+	assert.Equal(t, "package pkg\n\nfunc Sub() {}\n", readFile(t, filepath.Join(w.Dir(), "pkg", "sub.go")))
+	assert.NoFileExists(t, filepath.Join(w.Dir(), "pkg", "Add123_test.go"))
+}
+
+func TestWorkspace_Keep(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"pkg/add.go": "package pkg\n"})
+
+	w, err := New(root)
+	require.NoError(t, err)
+
+	failed := filepath.Join(w.Dir(), "pkg", "Add123_test.go")
+	writeFiles(t, w.Dir(), map[string]string{"pkg/Add123_test.go": "package pkg\n\nfunc TestAdd() {}\n"})
+	kept, err := w.Keep(failed)
+	require.NoError(t, err)
+	assert.NotContains(t, kept, w.Dir())
+	assert.Equal(t, "Add123_test.go", filepath.Base(kept))
+
+	// Kept files outlive the workspace
+	require.NoError(t, w.Close())
+	assert.NoDirExists(t, w.Dir())
+	assert.Equal(t, "package pkg\n\nfunc TestAdd() {}\n", readFile(t, kept))
+	require.NoError(t, os.RemoveAll(w.base))
+}
+
+func TestWorkspace_Close(t *testing.T) {
+	w, err := New(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.NoDirExists(t, w.base)
+}
@@ -1,10 +1,12 @@
 package types
 
+import "context"
+
 type IAgent interface {
-	GenerateTest(params GenerateTestParams) (string, error)
-	FixTypeErrors(params IterateTestParams) (string, error)
-	FixTestFailures(params IterateTestParams) (string, error)
-	PickExample(sourceCode string, testExamples []TestExample) (TestExample, error)
+	GenerateTest(ctx context.Context, params GenerateTestParams) (string, error)
+	FixTypeErrors(ctx context.Context, params IterateTestParams) (string, error)
+	FixTestFailures(ctx context.Context, params IterateTestParams) (string, error)
+	PickExample(ctx context.Context, sourceCode string, testExamples []TestExample) (TestExample, error)
 }
 
 type IConfig interface {
@@ -12,14 +14,28 @@ type IConfig interface {
 	GetExcludedDirs() []string
 	GetExcludedFiles() []string
 	GetLanguage() string
+	GetGoSettings() GoSettings
+	GetMutation() Mutation
+	GetFlakiness() Flakiness
+	GetSandbox() Sandbox
+	GetExampleSelection() ExampleSelection
+	GetExamplesPerPrompt() int
 	LoadExamples() ([]TestExample, error)
 	LoadContextFiles() ([]ContextFile, error)
+	GetContextDiscovery() bool
+	LoadPromptTemplates() (PromptTemplates, error)
+	GetPricing() map[string]Price
+	GetBudget() Budget
+	GetAI() AI
 }
 
 // TestRunner interface for different test frameworks
 type ITestRunner interface {
 	GetName() string
-	RunTests(rootDir, testFilePath string) (bool, string, error)
+	RunTests(ctx context.Context, rootDir, testFilePath string) (RunResult, error)
+	// RerunTests runs the tests in testFilePath several times. Tests has an entry for
+	// every run of every test.
+	RerunTests(ctx context.Context, rootDir, testFilePath string, options RerunOptions) (RunResult, error)
 }
 
 // FileFinder interface for finding files that need tests
@@ -34,10 +50,37 @@ type ILanguage interface {
 	GetTestRunner() ITestRunner
 	GetFileExtension() string
 	GetTestFilePattern() string
-	CheckTypes(testFilePath string) (bool, string, error)
+	CheckTypes(ctx context.Context, testFilePath string) (RunResult, error)
+	CaptureBaseline(ctx context.Context, sourcePath string) error // Record pre-existing errors before generating tests for a file
 	GetFunctions(sourceCode string) ([]Function, error)
+	ResolveContext(ctx context.Context, sourcePath string, function Function) ([]ContextFile, error) // Declarations the function references from other files
+	Mutate(sourceCode string, function Function) ([]Mutant, error)                                   // Copies of the source with one change to the function each
+	MergeTests(tests []string) (string, error)                                                       // One test file from tests generated separately, with a single package clause and import list
 }
 
 type IPromptLogger interface {
-	Log(operation string, prompt string, response string) error
+	Log(operation string, prompt string, response string, usage Usage) error
+	LogSummary(summary UsageSummary) error
+}
+
+// IUsageTracker aggregates model usage by operation, file and function
+type IUsageTracker interface {
+	SetTarget(file, function string)                          // Attribute following calls to a file and function
+	Record(operation string, model string, usage Usage) Usage // Returns the usage with its estimated cost
+	RecordTest(passed bool)
+	CheckBudget() error                      // Non-nil once the run is out of tokens, money or time
+	CheckFunctionBudget() error              // Non-nil once the current function is out of agent calls
+	FunctionCalls(file, function string) int // Agent calls made so far for a function of a file
+	Summary() UsageSummary
+}
+
+// IOutputSink receives the final test files, e.g. writing them to the project or to a patch
+type IOutputSink interface {
+	WriteFile(file TestFile) error
+	Close() error // Flushes anything the sink held back, like a patch
+}
+
+// IReviewer shows generated tests to the user, who decides what happens to each
+type IReviewer interface {
+	Review(ctx context.Context, item ReviewItem) (ReviewDecision, error)
 }
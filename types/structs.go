@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type TestType string
 
 const (
@@ -9,11 +11,22 @@ const (
 	TestTypePrompt      TestType = "prompt"
 )
 
+// ExampleSelection is how the example test for a source file is chosen
+type ExampleSelection string
+
+const (
+	ExampleSelectionLLM    ExampleSelection = "llm"    // Ask the model to pick
+	ExampleSelectionLocal  ExampleSelection = "local"  // Rank examples locally, without a model call
+	ExampleSelectionHybrid ExampleSelection = "hybrid" // Rank locally, asking the model only between close candidates
+)
+
 type TestExample struct {
 	Name        string
 	Type        TestType
-	SourceCode  string
-	Description string
+	SourceCode  string   `prompt:"source_code,cdata"`
+	Description string   `prompt:"description,omitempty"`
+	AppliesTo   []string `prompt:"-"` // Globs of source files this example is always used for
+	Priority    int      `prompt:"-"` // Orders examples pinned to the same file, highest first
 }
 
 type ErrorAttempt struct {
@@ -26,50 +39,287 @@ type GenerateTestParams struct {
 	TestRunner     ITestRunner
 	TestPath       string
 	Function       Function // Function to generate a test for
-	SourceCode     string
+	SourceCode     string   `prompt:"source_code,cdata"` // Contents of the source file, which includes the function
 	SourceCodePath string
-	Example        TestExample
-	ContextFiles   []ContextFile // Additional context files for test generation
+	Example        TestExample   // Most relevant example
+	OtherExamples  []TestExample `prompt:"other_examples,omitempty"` // Further examples, most relevant first
+	ContextFiles   []ContextFile `prompt:"context_files,omitempty"`  // Additional context files for test generation
+	Instructions   string        `prompt:"instructions,omitempty"`   // Extra instructions from the user, e.g. when regenerating a test in review
 }
 
 type IterateTestParams struct {
-	Errors   []string
-	TestCode string
-	GenerateTestParams
+	Errors             []string `prompt:"errors,order=3"`
+	TestCode           string   `prompt:"test_code,cdata,order=2"`
+	GenerateTestParams `prompt:",order=1"`
 }
 
 // ContextFile represents a file that provides additional context for test generation
 type ContextFile struct {
 	Path        string // Path to the file relative to the config file
-	Content     string // Content of the file
+	Content     string `prompt:"content,cdata"` // Content of the file
 	Description string // Description of what this file contains/provides
 	Type        string // Type of context (e.g., "types", "utils", "constants")
+	Discovered  bool   `prompt:"-"` // Declarations the function references, found by the language rather than configured
+}
+
+// ContextSource is a context.files entry: a single file, a directory or a glob, expanded
+// into context files when the config is loaded
+type ContextSource struct {
+	Path        string   `json:"path"` // File, directory or glob like "./types/**/*.go", relative to the config file
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Include     []string `json:"include"`  // Globs files under a directory or glob must match, relative to its base directory
+	Exclude     []string `json:"exclude"`  // Globs of files to leave out, relative to the same base directory
+	MaxSize     int64    `json:"max_size"` // Files larger than this many bytes are skipped, zero for no limit
 }
 
 // Example represents a single test example configuration
 type Example struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	FilePath    string `json:"file_path"`
-	Description string `json:"description"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	FilePath    string   `json:"file_path"`
+	Description string   `json:"description"`
+	AppliesTo   []string `json:"applies_to"` // Globs of source files, relative to the project, that always use this example
+	Priority    int      `json:"priority"`   // Orders examples pinned to the same file, highest first
 }
 
 // Settings represents global configuration settings
 type Settings struct {
-	DefaultTestDirectory string   `json:"default_test_directory"`
-	Language             string   `json:"language"`
-	TestRunner           string   `json:"test_runner"`
-	ExcludedDirs         []string `json:"excluded_dirs"`
-	ExcludedFiles        []string `json:"excluded_files"`
+	DefaultTestDirectory string     `json:"default_test_directory"`
+	Language             string     `json:"language"`
+	TestRunner           string     `json:"test_runner"`
+	ExcludedDirs         []string   `json:"excluded_dirs"`
+	ExcludedFiles        []string   `json:"excluded_files"`
+	ExampleSelection     string     `json:"example_selection"`
+	ExamplesPerPrompt    int        `json:"examples_per_prompt"`
+	Go                   GoSettings `json:"go"`
+	Mutation             Mutation   `json:"mutation"`
+	Flakiness            Flakiness  `json:"flakiness"`
+	Sandbox              Sandbox    `json:"sandbox"`
+}
+
+// Sandbox configures isolating generated tests from the project and the network while
+// they're type checked and run
+type Sandbox struct {
+	Enabled      bool     `json:"enabled"`
+	Mode         string   `json:"mode"`          // "auto", "bwrap", "namespaces" or "copy"
+	Writable     []string `json:"writable"`      // Directories outside the project tests may write to
+	CPUTime      string   `json:"cpu_time"`      // CPU time limit per process, e.g. "5m"
+	MemoryMB     int      `json:"memory_mb"`     // Address space limit per process in MiB
+	MaxProcesses int      `json:"max_processes"` // Process limit
+	Timeout      string   `json:"timeout"`       // Wall-clock limit per command, e.g. "10m"
+}
+
+// Flakiness configures re-running passing tests to catch tests that depend on ordering,
+// timing or scheduling
+type Flakiness struct {
+	Enabled     *bool `json:"enabled"`      // Defaults to false
+	Runs        int   `json:"runs"`         // Times each passing test is re-run
+	Shuffle     bool  `json:"shuffle"`      // Randomize test order, with go test -shuffle or jest --randomize
+	Race        bool  `json:"race"`         // Re-run Go tests with the race detector
+	MaxAttempts int   `json:"max_attempts"` // Times the model is asked to fix a flaky test
+}
+
+// RerunOptions configures how tests are re-run to detect flakiness
+type RerunOptions struct {
+	Runs    int  // Times the tests are run
+	Shuffle bool // Randomize the order of tests on each run
+	Race    bool // Run Go tests with the race detector
+}
+
+// Mutation configures checking generated tests against mutants of the function under
+// test, to catch tests that pass without asserting anything meaningful
+type Mutation struct {
+	Enabled     bool    `json:"enabled"`
+	Threshold   float64 `json:"threshold"`    // Share of mutants the test must fail on, between 0 and 1
+	MaxMutants  int     `json:"max_mutants"`  // Mutants run per function, spread across the function
+	MaxAttempts int     `json:"max_attempts"` // Times the model is asked to strengthen a test below the threshold
+	Timeout     string  `json:"timeout"`      // Test run timeout per mutant, e.g. "1m"
+}
+
+// Mutant is a copy of a source file with one small change to a function
+type Mutant struct {
+	Description string // What was changed, e.g. "replaced == with !="
+	Line        int    // 1-based line of the change
+	SourceCode  string // The whole mutated source file
+}
+
+// FunctionTest is the passing test generated for one function
+type FunctionTest struct {
+	Function Function
+	TestCode string
+	Attempts int // Agent calls it took, including fixes
+}
+
+// TestFile is a final test file, made of the passing tests for a source file's functions
+type TestFile struct {
+	SourcePath string
+	Path       string
+	Content    []byte
+	Tests      []FunctionTest
+}
+
+// ReviewAction is what the user decided about a generated test
+type ReviewAction string
+
+const (
+	ReviewAccept     ReviewAction = "accept"
+	ReviewReject     ReviewAction = "reject"
+	ReviewRegenerate ReviewAction = "regenerate" // Generate the test again with an extra instruction
+	ReviewEdit       ReviewAction = "edit"       // Replace the test with the user's edited version
+	ReviewQuit       ReviewAction = "quit"       // Reject the test and stop the run
+)
+
+// ReviewItem is a generated test shown to the user for review
+type ReviewItem struct {
+	SourcePath    string // Relative to the project root
+	TestPath      string // Where the test is being checked
+	Function      Function
+	TestCode      string
+	Result        RunResult // Type check and test run of TestCode
+	MutationScore *float64  // Nil unless mutation testing scored the test
+}
+
+// ReviewDecision is the user's decision about a ReviewItem
+type ReviewDecision struct {
+	Action      ReviewAction
+	Instruction string // For ReviewRegenerate
+	TestCode    string // For ReviewEdit
+}
+
+// GoSettings configures how go vet and go test are invoked
+type GoSettings struct {
+	Tags    []string `json:"tags"`    // Build tags passed to -tags
+	Race    bool     `json:"race"`    // Run generated tests with the race detector
+	Timeout string   `json:"timeout"` // go test -timeout, e.g. "2m"
+}
+
+// Prompts overrides the built-in prompt templates with template files relative to the config file
+type Prompts struct {
+	Templates     map[string]string            `json:"templates"`       // Operation name to template file
+	ByExampleType map[string]map[string]string `json:"by_example_type"` // Example type to operation name to template file
+}
+
+// PromptTemplates holds the contents of the prompt template overrides
+type PromptTemplates struct {
+	Templates     map[string]string
+	ByExampleType map[TestType]map[string]string
 }
 
 // Context represents additional files to be used as context for test generation
 type Context struct {
-	Files []ContextFile `json:"files"`
+	Files []ContextSource `json:"files"`
+	Auto  *bool           `json:"auto,omitempty"` // Discover declarations the function references, defaults to true
 }
 
 type Function struct {
 	Name       string
-	SourceCode string
+	SourceCode string `prompt:"-"` // Already part of GenerateTestParams.SourceCode
 	IsExported bool
+	Receiver   string   `prompt:"receiver,omitempty"`   // Receiver type (Go) or enclosing class, object or namespace (TypeScript), empty for free functions
+	Params     []Param  `prompt:"params,omitempty"`     // Parameters in declaration order
+	Results    []Param  `prompt:"results,omitempty"`    // Results in declaration order, names are often empty
+	Doc        string   `prompt:"doc,omitempty"`        // Doc comment preceding the declaration, without comment markers
+	StartLine  int      `prompt:"start_line,omitempty"` // 1-based line where the function starts
+	EndLine    int      `prompt:"end_line,omitempty"`   // 1-based line where the function ends
+	References []string `prompt:"references,omitempty"` // Package-level identifiers and types the function uses, e.g. "Config" or "http.Client"
+}
+
+// Param is a single parameter or result of a function signature
+type Param struct {
+	Name string `prompt:"name,omitempty"`
+	Type string
+}
+
+type TestStatus string
+
+const (
+	TestStatusPass TestStatus = "pass"
+	TestStatusFail TestStatus = "fail"
+	TestStatusSkip TestStatus = "skip"
+)
+
+// Location is a position in a source file
+type Location struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// TestCase is the outcome of a single test
+type TestCase struct {
+	Name      string
+	Status    TestStatus
+	Failures  []string   // Failure messages reported by the test
+	Locations []Location // Source locations mentioned in the failure messages
+}
+
+// Diagnostic is a compiler or vet error
+type Diagnostic struct {
+	Location
+	Code    string // Compiler error code if available, e.g. "TS2345"
+	Message string
+}
+
+// RunResult is the structured outcome of a type check or test run
+type RunResult struct {
+	Passed      bool
+	Tests       []TestCase
+	Diagnostics []Diagnostic
+	Panic       string // Panic message and stack trace, if the run panicked
+	TimedOut    bool
+	Output      string // Raw output of the command
+}
+
+// Usage is the token consumption, latency and estimated cost of one or more model calls
+type Usage struct {
+	Calls               int           `json:"calls"`
+	InputTokens         int64         `json:"input_tokens"`
+	OutputTokens        int64         `json:"output_tokens"`
+	CacheCreationTokens int64         `json:"cache_creation_tokens"`
+	CacheReadTokens     int64         `json:"cache_read_tokens"`
+	Duration            time.Duration `json:"duration"`
+	Cost                float64       `json:"cost"` // Estimated cost in USD, zero if the model has no price
+}
+
+// UsageSummary aggregates usage for a run
+type UsageSummary struct {
+	Total              Usage            `json:"total"`
+	ByOperation        map[string]Usage `json:"by_operation"`
+	ByFile             map[string]Usage `json:"by_file"`
+	ByFunction         map[string]Usage `json:"by_function"` // Keyed by file and function, e.g. "pkg/math.go:Calculator.Add"
+	PassingTests       int              `json:"passing_tests"`
+	FailingTests       int              `json:"failing_tests"`
+	CostPerPassingTest float64          `json:"cost_per_passing_test"`
+}
+
+// Budget limits how much a run may spend. Zero values are unlimited.
+type Budget struct {
+	MaxTokens           int64   `json:"max_tokens"`             // Input, output and cache tokens across the run
+	MaxCost             float64 `json:"max_cost"`               // Estimated cost in USD across the run
+	MaxDuration         string  `json:"max_duration"`           // Wall-clock time, e.g. "2h"
+	MaxCallsPerFunction int     `json:"max_calls_per_function"` // Agent calls for a single function, including fixes
+}
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// ModelSettings configures the model behind an agent operation. Empty fields inherit the defaults.
+type ModelSettings struct {
+	Provider     string   `json:"provider,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	MaxTokens    int64    `json:"max_tokens,omitempty"`  // Maximum tokens in the response
+	Temperature  *float64 `json:"temperature,omitempty"` // Nil leaves the provider's default
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+}
+
+// AI configures the agent with defaults for every operation, overridden per operation
+type AI struct {
+	ModelSettings
+	Operations map[string]ModelSettings `json:"operations"` // Keyed by prompt operation, e.g. "pick_example"
 }